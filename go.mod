@@ -5,7 +5,11 @@ go 1.24.4
 require (
 	github.com/brittonhayes/notionmd v0.8.0
 	github.com/dstotijn/go-notion v0.11.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/spf13/pflag v1.0.7
 )
 
-require github.com/gomarkdown/markdown v0.0.0-20240723152757-afa4a469d4f9 // indirect
+require (
+	github.com/gomarkdown/markdown v0.0.0-20240723152757-afa4a469d4f9 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
@@ -1,53 +1,177 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/christhomas/notionmd-cli/pkg/notionsync"
 	"github.com/spf13/pflag"
-
-	"github.com/brittonhayes/notionmd"
-	"github.com/dstotijn/go-notion"
 )
 
+// Version is set at build time with -ldflags "-X main.Version=..."
+var Version = "dev"
+
 var (
-	debugEnabled bool
-	Version      = "dev"
+	// out receives the status messages, nothing with --output json
+	out io.Writer = os.Stdout
+	// report is the run report written with --report-file or printed by --output json, nil
+	// when neither is asked for
+	report *notionsync.Report
+	// debug prints the debug messages of main as well
+	debug bool
 )
 
-// PageMetadata is the metadata stored in the code block
-type PageMetadata struct {
-	ContentHash string `json:"content_hash"`
+// flagAliases maps alternative flag names to the flag they stand for
+var flagAliases = map[string]string{
+	"dir":      "md-dir",
+	"manifest": "page-map",
+}
+
+// normalizeFlagName resolves flag aliases to the flag's name
+func normalizeFlagName(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if alias, ok := flagAliases[name]; ok {
+		name = alias
+	}
+	return pflag.NormalizedName(name)
 }
 
 func main() {
 	var (
-		token        string
-		pageID       string
-		mdPath       string
-		appendF      bool
-		replaceF     bool
-		useHash      bool
-		hashProperty string
-		rewriteText  string
-		dryRun       bool
-		debugFlag    bool
-		version      bool
+		token     string
+		tokenFile string
+		pageID    string
+		mdPath    string
+		appendF   bool
+		debugFlag bool
+		version   bool
+
+		opts             notionsync.SyncOptions
+		uploadFieldName  string
+		uploadFormFields map[string]string
+		endpointVersions map[string]string
+		notionVersion    string
+		authHeader       string
+		multiDoc         bool
+		clearOnly        bool
+		yes              bool
+		captionFormat    string
+		reportFile       string
+		cacheDir         string
+		proxyURL         string
+		caBundle         string
+		uploadTimeout    time.Duration
+		timeout          time.Duration
+		watch            bool
+		watchDebounce    time.Duration
+		noUploadCache    bool
+		uploadRetries    int
+		maxRetries       int
+		rateLimit        float64
+		userMapPath      string
+		rewriteImages    string
+		mdDir            string
+		pageMapPath      string
 	)
-	pflag.StringVar(&token, "token", "", "Notion integration token")
-	pflag.StringVar(&pageID, "page", "", "Target Notion page ID")
+	pflag.StringVar(&token, "token", "", "Notion integration token (default: read from --token-file or the NOTION_TOKEN environment variable)")
+	pflag.StringVar(&tokenFile, "token-file", "", "File holding the Notion integration token, used when --token isn't given")
+	pflag.StringVar(&pageID, "page", "", "Target Notion page ID (overrides notion_page in the markdown frontmatter)")
 	pflag.StringVar(&mdPath, "md", "", "Path to markdown file")
+	pflag.StringVar(&mdDir, "md-dir", "", "Sync every .md file under this directory to the page mapped to it in --page-map or named in its frontmatter")
+	pflag.BoolVar(&multiDoc, "multi-doc", false, "Treat --md as several documents, each starting with frontmatter, and sync each to the notion_page it declares")
+	pflag.StringVar(&pageMapPath, "page-map", "", "Path to JSON file mapping markdown paths (relative to --md-dir) to Notion page IDs")
 	pflag.BoolVar(&appendF, "append", false, "Append content to the bottom of the existing page (default)")
-	pflag.BoolVar(&replaceF, "replace", false, "Replace all existing content with new content")
-	pflag.BoolVar(&useHash, "use-hash", false, "Store and check content hash in a dedicated metadata block and/or property.")
-	pflag.StringVar(&hashProperty, "hash-property", "", "Optionally specify property name for content hash, e.g. --hash-property=MyPropName")
-	pflag.StringVar(&rewriteText, "rewrite-text", "", "Path to JSON file mapping links to rewrite in the markdown file")
-	pflag.BoolVar(&dryRun, "dry-run", false, "Run all logic except Notion sync (no changes will be made to Notion)")
+	pflag.BoolVar(&clearOnly, "clear-only", false, "Remove all content of --page without adding anything (no --md needed)")
+	pflag.BoolVar(&yes, "yes", false, "Don't ask for confirmation before destructive operations such as --clear-only")
+	pflag.BoolVar(&opts.Replace, "replace", false, "Replace all existing content with new content")
+	pflag.IntVar(&opts.PreserveFirstN, "replace-preserve-first-n", 0, "With --replace, keep the first N existing blocks (e.g. a fixed header) and replace only what follows")
+	pflag.BoolVar(&opts.UseHash, "use-hash", false, "Store and check content hash in a dedicated metadata block and/or property.")
+	pflag.StringVar(&opts.UnderHeading, "under-heading", "", "When appending, insert the content at the end of the section under the page's heading with this text instead of at the bottom")
+	pflag.BoolVar(&opts.EntryHeadingDate, "entry-heading-date", false, "When appending, start the appended content with a heading holding today's date, for journal and log pages")
+	pflag.StringVar(&opts.EntryDateFormat, "entry-date-format", notionsync.DefaultEntryDateFormat, "Go time layout of the --entry-heading-date heading, e.g. 'Monday, 2 January 2006'")
+	pflag.BoolVar(&opts.SkipExistingEntry, "skip-existing-entry", false, "With --entry-heading-date, skip the sync if the page's last heading already is today's entry heading")
+	pflag.BoolVar(&opts.GitDiff, "git-diff", false, "With --replace, only replace the sections (heading and what follows up to the next heading) the latest git commit changed in the markdown file")
+	pflag.StringVar(&opts.DiffAgainstFile, "diff-against-file", "", "Skip the sync if the markdown is identical to the copy in this file, which is updated after every successful sync (no Notion reads)")
+	pflag.StringVar(&opts.StateFile, "state-file", "", "Record each page's last edit after syncing it in this JSON file, and refuse to --replace a page edited by someone else since")
+	pflag.BoolVar(&opts.Force, "force", false, "Sync even if --use-hash or --diff-against-file find the content unchanged (the hash is still stored), and replace the page even if --state-file shows it was edited by someone else since the last sync")
+	pflag.BoolVar(&opts.FrontmatterProps, "frontmatter-properties", false, "Set page properties (select, multi_select, checkbox, number, date, text) from the keys of the markdown frontmatter")
+	pflag.StringVar(&opts.HashProperty, "hash-property", "", "Optionally specify property name for content hash, e.g. --hash-property=MyPropName")
+	pflag.StringVar(&opts.PropertyPrefix, "property-prefix", "", "Prefix for the names of metadata properties this tool writes, e.g. notionmd_ gives 'notionmd_Content Hash'")
+	pflag.StringVar(&opts.TimestampProperty, "timestamp-property", "", "Text property to write the time of each sync that changes the page to, e.g. 'Last Synced'")
+	pflag.StringVar(&opts.HashStorage, "hash-storage", "property", "Where to store the content hash: property, code (JSON code block) or comment (trailing HTML comment paragraph)")
+	pflag.StringVar(&opts.RewriteText, "rewrite-text", "", "Path to JSON file mapping links to rewrite in the markdown file")
+	pflag.StringVar(&opts.RewriteMode, "rewrite-mode", "text", "Where --rewrite-text replaces its keys: text (anywhere in the markdown) or links (only in link and image destinations and HTML href/src attributes)")
+	pflag.StringVar(&rewriteImages, "rewrite-images", "", "Path to JSON file mapping image path fragments to rewrite, applied to image references only")
+	pflag.BoolVar(&opts.DateMentions, "date-mentions", false, "Convert dates written as @today or @2024-01-15 into Notion date mentions")
+	pflag.StringVar(&opts.DatePrefix, "date-mention-prefix", "@", "Prefix marking a date mention when --date-mentions is enabled")
+	pflag.StringVar(&opts.TaskMetadataMode, "task-metadata", "keep", "What to do with @due(...) and @assignee(...) in task items: keep, compact (append in short form) or drop")
+	pflag.StringVar(&opts.TasksDatabase, "tasks-database", "", "Add the task items to this Notion database as pages instead of to the page, with their @due(...) and @assignee(...) in date and people properties")
+	pflag.StringVar(&opts.TaskDueProperty, "task-due-property", "Due", "Date property of the --tasks-database holding a task's @due(...)")
+	pflag.StringVar(&opts.TaskAssigneeProperty, "task-assignee-property", "Assignee", "People property of the --tasks-database holding a task's @assignee(...), looked up in --user-map")
+	pflag.StringVar(&userMapPath, "user-map", "", "Path to JSON file mapping @handles to Notion user IDs, converting them into user mentions")
+	pflag.StringVar(&opts.HeadingEmoji, "heading-emoji", "inline", "What to do with an emoji starting a heading: inline (keep it), strip (drop it) or icon (drop it, using it as the icon of the child page the heading becomes with --split-by-heading)")
+	pflag.IntVar(&opts.SplitLevel, "split-by-heading", 0, "Split the document at headings of this level (1-3) into child pages linked from a table of contents on the target page")
+	pflag.StringVar(&opts.OnConflict, "on-conflict", "rename", "What to do when a child page with the same title already exists: skip, overwrite or rename")
+	pflag.StringVar(&opts.WrapIn, "wrap-in", "", "Wrap all converted content in a single toggle or callout block")
+	pflag.StringVar(&opts.WrapLabel, "wrap-label", "", "Label of the --wrap-in block (defaults to the markdown file name)")
+	pflag.StringVar(&opts.Footnotes, "footnotes", "list", "How footnotes ([^1] with [^1]: text) are shown: list (numbered list at the end), inline (text in parentheses at the reference) or comments (comment on the referencing block)")
+	pflag.BoolVar(&opts.BookmarkURLs, "bookmark-urls", false, "Turn paragraphs holding nothing but a URL into bookmark blocks")
+	pflag.BoolVar(&opts.LinkIndex, "link-index", false, "Append a numbered References section listing every unique external link")
+	pflag.BoolVar(&opts.CommentSummary, "comment-summary", false, "Post a page comment summarizing the sync (blocks added, images uploaded, time) after a successful sync")
+	pflag.BoolVar(&opts.VerifyPage, "verify-page", false, "Mark the page as verified after a successful sync (wiki pages only)")
+	pflag.StringVar(&opts.Icon, "icon", "", "Set the page icon to this emoji, image URL or local image file (overrides notion_icon in the frontmatter)")
+	pflag.StringVar(&opts.Cover, "cover", "", "Set the page cover to this image URL or local image file (overrides notion_cover in the frontmatter)")
+	pflag.StringVar(&opts.PageIDFile, "emit-page-id-file", "", "Write the synced page ID and URL to this file for later automation steps")
+	pflag.StringVar(&opts.BlockMapOut, "block-map-out", "", "Write a JSON file mapping each top level block's source line and heading to the Notion block ID it was given")
+	pflag.BoolVar(&opts.DryRun, "dry-run", false, "Run all logic without contacting Notion and print the JSON request bodies that would be sent")
+	pflag.BoolVar(&opts.ValidateOnly, "validate-only", false, "Convert and validate locally without contacting Notion, exiting non-zero on any warning or rejected block")
+	pflag.BoolVar(&opts.Roundtrip, "roundtrip", false, "Convert locally, render the blocks back to markdown and print the diff against the input (no Notion access)")
+	pflag.BoolVar(&opts.PreviewImages, "preview-images", false, "List the images that would be uploaded with their resolved path, size and content type, without contacting Notion (fails if a local image is missing)")
+	pflag.BoolVar(&opts.RowHeader, "row-header", false, "Mark the first column of tables as a row header")
+	pflag.BoolVar(&opts.EscapeReserved, "escape-reserved", false, "Remove control characters and drop links Notion would reject from the text, with a warning for each")
+	pflag.BoolVar(&opts.SkipImages, "skip-images", false, "Leave image references as plain text: no uploads, no external embeds, no missing file errors")
+	pflag.BoolVar(&opts.Images.ContinueOnError, "continue-on-image-error", false, "Replace images that fail to upload or can't be found with a link and sync the rest, reporting the failures at the end")
+	pflag.BoolVar(&opts.Images.NativeSize, "native-image-size", false, "Send image width/height as the block's display size instead of caption text, falling back to the caption if Notion rejects it")
+	pflag.StringVar(&opts.Images.CaptionSource, "image-caption", "title", "Image caption text: title (the image title if it has one, else the alt text), alt or both")
+	pflag.StringVar(&opts.Images.CaptionPosition, "caption-position", "caption", "Where image captions go: caption (the image block's own caption), above or below (a separate paragraph next to the image)")
+	pflag.BoolVar(&opts.Images.NoDimensionCaption, "no-dimension-caption", false, "Don't append the image width/height to captions (combine with --native-image-size to size the image instead)")
+	pflag.StringVar(&captionFormat, "dimension-caption-format", notionsync.DefaultDimensionCaptionFormat, "Go template for the image width/height appended to captions, with {{.Width}} and {{.Height}} (0 when not given)")
+	pflag.BoolVar(&opts.Images.UploadRemote, "upload-remote", false, "Download remote images and upload them to Notion instead of linking them, keeping the link when the download fails")
+	pflag.BoolVar(&opts.Images.VideoEmbeds, "video-embeds", false, "Embed images that point at YouTube/Vimeo videos or their thumbnails as videos")
+	pflag.StringVar(&cacheDir, "cache-dir", "", "Directory caching downloaded remote images between runs, revalidated via ETag/Last-Modified")
+	pflag.StringVar(&uploadFieldName, "upload-field-name", "file", "Multipart form field name used for the file content when uploading images")
+	pflag.StringToStringVar(&uploadFormFields, "upload-form-field", nil, "Extra multipart form field sent with image uploads, e.g. --upload-form-field=key=value (repeatable)")
+	pflag.IntVar(&opts.TitleLevel, "title-heading-level", 1, "Deepest heading level (1-3) a leading heading may have to be used as the page title")
+	pflag.StringVar(&proxyURL, "proxy", "", "HTTP(S) proxy for all requests, e.g. http://proxy.example.com:3128 (default: HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment)")
+	pflag.StringVar(&caBundle, "ca-bundle", "", "PEM file of CA certificates to trust on top of the system ones, for proxies that intercept TLS")
+	pflag.StringVar(&notionVersion, "notion-version", notionsync.DefaultNotionVersion, "Notion-Version header sent with API requests, e.g. 2022-06-28")
+	pflag.StringToStringVar(&endpointVersions, "endpoint-notion-version", nil, "Notion-Version for requests under an API path, e.g. --endpoint-notion-version=/v1/file_uploads=2022-06-28 (repeatable)")
+	pflag.StringVar(&authHeader, "notion-api-key-header", "", "Header carrying the token, for gateways in front of Notion, e.g. 'X-Api-Key: {token}' (default 'Authorization: Bearer {token}')")
+	pflag.DurationVar(&timeout, "timeout", 0, "Give up on the whole run after this long, e.g. 10m (0 means no timeout); with --watch on each sync")
+	pflag.BoolVar(&watch, "watch", false, "Keep running and sync --md again whenever it or a local image it references changes, until Ctrl-C")
+	pflag.DurationVar(&watchDebounce, "watch-debounce", 300*time.Millisecond, "With --watch, wait this long after a change for further changes before syncing")
+	pflag.DurationVar(&uploadTimeout, "upload-timeout", 0, "Timeout for each image upload request, e.g. 2m (0 means no timeout)")
+	pflag.IntVar(&maxRetries, "max-retries", 3, "How many times to retry a Notion API request answered with 429 (honouring Retry-After) or a 5xx status")
+	pflag.Float64Var(&rateLimit, "rate-limit", 0, "Most Notion API requests per second, shared by all requests of the run (0 means no limit; Notion allows 3 on average)")
+	pflag.BoolVar(&noUploadCache, "no-upload-cache", false, "Upload every image, instead of reusing the file upload of images unchanged since an earlier upload")
+	pflag.IntVar(&opts.Images.UploadConcurrency, "upload-concurrency", 4, "How many images to upload at the same time")
+	pflag.IntVar(&uploadRetries, "upload-retries", 0, "How many times to retry a failed image upload (network errors, 429 and 5xx responses)")
+	pflag.StringVar(&opts.TitleOverflow, "title-overflow", "truncate", "How to handle titles longer than Notion allows: truncate or error")
+	pflag.BoolVar(&opts.DryRunDiff, "dry-run-diff", false, "Fetch the live page and print the planned block changes without applying them")
+	pflag.StringVar(&opts.DiffOutput, "diff-output", "plan", "How --dry-run-diff shows the changes: plan (block level, honours --output) or unified (unified diff of the page as markdown)")
+	pflag.StringVar(&opts.Output, "output", "text", "Output format: text, or json to print the result of a sync as a single JSON object instead of status messages (and the --dry-run-diff plan and --preview-images listing as JSON)")
+	pflag.StringVar(&reportFile, "report-file", "", "Write a JSON report of the run (inputs, hash checks, blocks sent, uploads, warnings, API timings, status) to this file")
+	pflag.DurationVar(&opts.InputWait, "input-wait", 0, "Wait up to this long, e.g. 5s, for the markdown and mapping files to appear and stop changing before reading them")
 	pflag.BoolVar(&debugFlag, "debug", false, "Enable debug output")
 	pflag.BoolVarP(&version, "version", "v", false, "Print version and exit")
+	pflag.CommandLine.SetNormalizeFunc(normalizeFlagName)
 	pflag.Parse()
 
 	if version {
@@ -55,298 +179,382 @@ func main() {
 		os.Exit(0)
 	}
 
-	debugEnabled = debugFlag
+	opts.Debug, debug = debugFlag, debugFlag
+	if opts.Output != "text" && opts.Output != "json" {
+		failf("Invalid --output '%s': must be text or json", opts.Output)
+	}
 
-	debugLog("Given: \n--token '%s' \n--page '%s' \n--md '%s' \n--append '%t' \n--replace '%t' \n--use-hash '%t' \n--hash-property '%s' \n--rewrite-text '%s'\n", token, pageID, mdPath, appendF, replaceF, useHash, hashProperty, rewriteText)
+	// With --output json a sync prints its result as a JSON object instead of status messages,
+	// the --preview-images listing and the --dry-run-diff plan are JSON themselves
+	var result io.Writer
+	if opts.Output == "json" && !opts.PreviewImages && !opts.DryRunDiff {
+		if opts.DryRun || opts.Roundtrip {
+			failf("--dry-run and --roundtrip print their result as text and can't be combined with --output json.")
+		}
+		if clearOnly && !yes {
+			failf("--clear-only with --output json can't ask for confirmation, pass --yes.")
+		}
+		result = os.Stdout
+		out = io.Discard
+	}
+	opts.StatusOutput = out
+	if reportFile != "" || result != nil {
+		report = notionsync.NewReport(reportFile, os.Args[1:], result)
+		opts.Report = report
+	}
+	ctx := notionsync.NewContext(context.Background(), opts)
+
+	token, err := resolveToken(token, tokenFile)
+	if err != nil {
+		failf("%s", err)
+	}
 
-	if token == "" || pageID == "" || mdPath == "" || len(os.Args) == 1 {
+	debugf("Given: \n--token '%s' \n--page '%s' \n--md '%s' \n--append '%t' \n--replace '%t' \n--use-hash '%t' \n--hash-property '%s' \n--rewrite-text '%s'\n", token, pageID, mdPath, appendF, opts.Replace, opts.UseHash, opts.HashProperty, opts.RewriteText)
+
+	// A round trip check is a local conversion check, images stay as their markdown
+	if opts.Roundtrip {
+		opts.ValidateOnly = false
+		opts.SkipImages = true
+	}
+	offline := opts.Offline()
+
+	if clearOnly {
+		if token == "" || pageID == "" || mdPath != "" || mdDir != "" {
+			failf("--clear-only needs --token and --page and can't be combined with --md or --md-dir.")
+		}
+	} else if mdDir != "" {
+		if mdPath != "" || (!offline && token == "") {
+			failf("--md-dir needs --token unless validating and can't be combined with --md.")
+		}
+	} else if (!offline && token == "") || mdPath == "" || len(os.Args) == 1 {
 		pflag.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
-	if appendF && replaceF {
-		fmt.Println("Cannot use both --append and --replace flags at the same time.")
-		os.Exit(1)
+	if multiDoc && (mdDir != "" || pageID != "") {
+		failf("--multi-doc syncs each document to the page in its frontmatter and can't be combined with --page or --md-dir.")
 	}
 
-	printAppTitle(mdPath, replaceF, useHash, rewriteText)
+	if watch && (clearOnly || mdDir != "" || multiDoc || reportFile != "" || result != nil) {
+		failf("--watch syncs a single --md file over and over and can't be combined with --clear-only, --md-dir, --multi-doc, --report-file or --output json.")
+	}
 
-	// Read the file contents
-	mdContent, err := os.ReadFile(mdPath)
-	if err != nil {
-		fmt.Println("Error reading markdown file:", err)
-		os.Exit(1)
+	if opts.DiffAgainstFile != "" && (mdDir != "" || multiDoc) {
+		failf("--diff-against-file keeps the copy of a single document and can't be combined with --md-dir or --multi-doc.")
 	}
 
-	// Rewrite text if mapping is provided before conversion to notion blocks
-	if rewriteText != "" {
-		if mdContent, err = rewriteContent(mdContent, mdPath, rewriteText); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	if appendF && opts.Replace {
+		failf("Cannot use both --append and --replace flags at the same time.")
 	}
 
-	// Initialize Notion client
-	notionClient := NewNotionClient(token)
+	if opts.Images.UploadConcurrency < 1 {
+		failf("Invalid --upload-concurrency %d: must be at least 1", opts.Images.UploadConcurrency)
+	}
 
-	// First convert markdown to Notion blocks
-	blocks, err := notionmd.Convert(string(mdContent))
-	if err != nil {
-		fmt.Println("Error converting markdown to Notion blocks:", err)
-		os.Exit(1)
+	if opts.PreserveFirstN < 0 {
+		failf("Invalid --replace-preserve-first-n %d: must not be negative", opts.PreserveFirstN)
 	}
 
-	// Then process the blocks to handle images correctly
-	blocks, err = ProcessImageBlocks(blocks, mdPath, notionClient)
-	if err != nil {
-		fmt.Printf("Warning: failed to process images: %s\n", err)
-		os.Exit(1)
+	if opts.PreserveFirstN > 0 && !opts.Replace {
+		failf("--replace-preserve-first-n only works together with --replace.")
+	}
+
+	if opts.EntryHeadingDate && opts.Replace {
+		failf("--entry-heading-date starts each appended entry with a dated heading and can't be combined with --replace.")
 	}
 
-	// Debug all block types
-	debugBlocks(blocks)
+	if opts.UnderHeading != "" && opts.Replace {
+		failf("--under-heading picks where appended content goes and can't be combined with --replace.")
+	}
 
-	// --- content_hash optimization ---
-	// Compute hash of the input markdown file
-	hashBytes := sha256.Sum256(mdContent)
-	contentHash := fmt.Sprintf("%x", hashBytes[:])
+	if opts.SkipExistingEntry && !opts.EntryHeadingDate {
+		failf("--skip-existing-entry only works together with --entry-heading-date.")
+	}
 
-	// Validate blocks before sending to Notion
-	blocks = validateContentBlocks(blocks)
-	titleBlock, blocks := filterTitleBlock(blocks)
-	if titleBlock != nil {
-		err := notionClient.UpdatePageTitle(pageID, titleBlock)
-		if err != nil {
-			fmt.Printf("Error updating page title: %s\n", err)
-		}
+	if opts.GitDiff && (!opts.Replace || opts.SplitLevel > 0 || opts.WrapIn != "" || multiDoc) {
+		failf("--git-diff replaces sections of the page: it needs --replace and can't be combined with --split-by-heading, --wrap-in or --multi-doc.")
+	}
+
+	if _, err := time.Parse(time.DateOnly, notionVersion); err != nil {
+		fmt.Fprintf(out, "Warning: --notion-version '%s' doesn't look like a Notion API version, which are dates such as %s\n", notionVersion, notionsync.DefaultNotionVersion)
+	}
+
+	if opts.TitleOverflow != "truncate" && opts.TitleOverflow != "error" {
+		failf("Invalid --title-overflow '%s': must be truncate or error", opts.TitleOverflow)
+	}
+
+	if !slices.Contains(notionsync.FootnoteModes, opts.Footnotes) {
+		failf("Invalid --footnotes '%s': must be one of %s", opts.Footnotes, strings.Join(notionsync.FootnoteModes, ", "))
+	}
+
+	if !slices.Contains(notionsync.RewriteModes, opts.RewriteMode) {
+		failf("Invalid --rewrite-mode '%s': must be one of %s", opts.RewriteMode, strings.Join(notionsync.RewriteModes, ", "))
+	}
+
+	if !slices.Contains(notionsync.HeadingEmojiModes, opts.HeadingEmoji) {
+		failf("Invalid --heading-emoji '%s': must be one of %s", opts.HeadingEmoji, strings.Join(notionsync.HeadingEmojiModes, ", "))
+	}
+
+	if !slices.Contains(notionsync.DiffOutputs, opts.DiffOutput) {
+		failf("Invalid --diff-output '%s': must be one of %s", opts.DiffOutput, strings.Join(notionsync.DiffOutputs, ", "))
+	}
+
+	if opts.TitleLevel < 1 || opts.TitleLevel > 3 {
+		failf("Invalid --title-heading-level %d: must be between 1 and 3", opts.TitleLevel)
+	}
+
+	if opts.SplitLevel < 0 || opts.SplitLevel > 3 {
+		failf("Invalid --split-by-heading %d: must be between 1 and 3", opts.SplitLevel)
+	}
+
+	if !slices.Contains(notionsync.ConflictPolicies, opts.OnConflict) {
+		failf("Invalid --on-conflict '%s': must be one of %s", opts.OnConflict, strings.Join(notionsync.ConflictPolicies, ", "))
+	}
+
+	if !slices.Contains(notionsync.TaskMetadataModes, opts.TaskMetadataMode) {
+		failf("Invalid --task-metadata '%s': must be one of %s", opts.TaskMetadataMode, strings.Join(notionsync.TaskMetadataModes, ", "))
 	}
 
-	if dryRun {
-		fmt.Println("[DRY RUN] All parsing, conversion, and hash logic completed. No changes made to Notion.")
-		fmt.Printf("[MD CONTENT]\n%s\n\n", mdContent)
-		return
+	if opts.WrapIn != "" && !slices.Contains(notionsync.WrapModes, opts.WrapIn) {
+		failf("Invalid --wrap-in '%s': must be one of %s", opts.WrapIn, strings.Join(notionsync.WrapModes, ", "))
 	}
 
-	// Checks the content hash property to see whether the content is different than that already published in notion
-	if useHash {
-		contentHashPropertyName := "Content Hash"
-		if hashProperty != "" {
-			contentHashPropertyName = hashProperty
+	if opts.WrapIn != "" && opts.SplitLevel > 0 {
+		failf("Cannot use both --wrap-in and --split-by-heading flags at the same time.")
+	}
+
+	if !slices.Contains(notionsync.HashStorageModes, opts.HashStorage) {
+		failf("Invalid --hash-storage '%s': must be one of %s", opts.HashStorage, strings.Join(notionsync.HashStorageModes, ", "))
+	}
+
+	var authHeaderName, authHeaderFormat string
+	if authHeader != "" {
+		var err error
+		if authHeaderName, authHeaderFormat, err = notionsync.ParseAuthHeader(authHeader); err != nil {
+			failf("Invalid --notion-api-key-header '%s': %s", authHeader, err)
 		}
-		propertyHash, err := notionClient.GetProperty(pageID, contentHashPropertyName)
+	}
+
+	if !slices.Contains(notionsync.ImageCaptionSources, opts.Images.CaptionSource) {
+		failf("Invalid --image-caption '%s': must be one of %s", opts.Images.CaptionSource, strings.Join(notionsync.ImageCaptionSources, ", "))
+	}
+
+	if !slices.Contains(notionsync.CaptionPositions, opts.Images.CaptionPosition) {
+		failf("Invalid --caption-position '%s': must be one of %s", opts.Images.CaptionPosition, strings.Join(notionsync.CaptionPositions, ", "))
+	}
+
+	if captionFormat != notionsync.DefaultDimensionCaptionFormat {
+		format, err := notionsync.ParseDimensionCaptionFormat(captionFormat)
 		if err != nil {
-			fmt.Printf("Error getting '%s' property: %s\n", contentHashPropertyName, err)
-			os.Exit(1)
+			failf("Invalid --dimension-caption-format: %s", err)
 		}
-		fmt.Printf("Page hash (Property Name: '%s'): %s\n", contentHashPropertyName, propertyHash)
-		fmt.Printf("Content hash: %s\n", contentHash)
-		if propertyHash == contentHash {
-			fmt.Println("⚠️ No content change detected. Skipping update.")
-			os.Exit(0)
+		opts.Images.CaptionFormat = format
+	}
+
+	if userMapPath != "" {
+		users, err := notionsync.LoadUserMap(ctx, userMapPath)
+		if err != nil {
+			failf("%s", err)
 		}
-		if err := notionClient.SetProperty(pageID, contentHashPropertyName, contentHash); err != nil {
-			fmt.Printf("Warning: failed to set '%s' property: %s\n", contentHashPropertyName, err)
+		opts.Users = users
+	}
+
+	if rewriteImages != "" {
+		rewrites, err := notionsync.LoadImageRewrites(ctx, rewriteImages)
+		if err != nil {
+			failf("%s", err)
 		}
+		opts.Images.PathRewrites = rewrites
 	}
 
-	// If we are replacing all the content with new content, we need to clear all the existing content first
-	if replaceF {
-		if err := notionClient.ClearPageContent(pageID); err != nil {
-			fmt.Printf("Error clearing Notion page: %s\n", err)
-			os.Exit(1)
+	// A custom transport only when asked for, the default one already honors the proxy variables
+	var transport *http.Transport
+	if proxyURL != "" || caBundle != "" {
+		var err error
+		if transport, err = notionsync.NewHTTPTransport(proxyURL, caBundle); err != nil {
+			failf("Error configuring HTTP transport: %s", err)
 		}
 	}
 
-	if err := notionClient.AddPageContent(pageID, blocks); err != nil {
-		fmt.Printf("Error updating Notion page: %s\n", err)
-		os.Exit(1)
+	opts.Images.HTTPClient = &http.Client{}
+	if transport != nil {
+		opts.Images.HTTPClient.Transport = transport
 	}
+	// Checks without Notion don't download images either, remote images stay links
+	opts.Images.UploadRemote = opts.Images.UploadRemote && !offline
 
-	fmt.Println("✅ Page updated successfully.")
-}
+	if cacheDir != "" && !opts.SkipImages {
+		cache, err := notionsync.NewImageCache(ctx, cacheDir)
+		if err != nil {
+			failf("Error opening image cache: %s", err)
+		}
+		cache.Client.Transport = opts.Images.HTTPClient.Transport
+		opts.Images.Cache = cache
+	}
 
-// rewriteContent applies rewrite-text mapping from a file to the markdown content.
-func rewriteContent(mdContent []byte, mdPath, rewriteLink string) ([]byte, error) {
-	data, err := os.ReadFile(rewriteLink)
-	if err != nil {
-		return nil, fmt.Errorf("Error reading rewrite-text mapping file: %w", err)
-	}
-
-	var singlePage map[string]string
-	if err := json.Unmarshal(data, &singlePage); err == nil {
-		debugLog("[DEBUG] Detected single-page rewrite mapping with %d links\n", len(singlePage))
-		return []byte(rewriteTextMap(string(mdContent), singlePage)), nil
-	}
-
-	var multiPage map[string]map[string]string
-	if err := json.Unmarshal(data, &multiPage); err == nil {
-		debugLog("[DEBUG] Detected multi-page rewrite mapping. Searching for a matching page key in: %s\n", mdPath)
-		var (
-			matchedKey string
-			pageMap    map[string]string
-		)
-		for key, candidate := range multiPage {
-			if strings.Contains(mdPath, key) {
-				matchedKey = key
-				pageMap = candidate
-				break
+	// Initialize Notion client, validation and dry runs never talk to Notion
+	var notionClient notionsync.NotionClientInterface = notionsync.OfflineNotionClient{}
+	if !offline {
+		client := notionsync.NewNotionClient(token, notionVersion)
+		client.UploadFieldName = uploadFieldName
+		client.UploadFormFields = uploadFormFields
+		client.TitleOverflow = opts.TitleOverflow
+		client.UploadTimeout = uploadTimeout
+		client.UploadRetries = uploadRetries
+		if !noUploadCache {
+			if path, err := notionsync.DefaultUploadCachePath(); err == nil {
+				client.UploadCache = notionsync.LoadUploadCache(ctx, path)
+			} else {
+				debugf("[DEBUG] Upload cache disabled: %s\n", err)
 			}
 		}
-		if matchedKey != "" {
-			debugLog("[DEBUG] Found %d links for page key '%s' (matched in: %s)\n", len(pageMap), matchedKey, mdPath)
-			return []byte(rewriteTextMap(string(mdContent), pageMap)), nil
+		client.NotionHTTP.EndpointVersions = endpointVersions
+		client.NotionHTTP.MaxRetries = maxRetries
+		client.NotionHTTP.RateLimiter = notionsync.NewRateLimiter(rateLimit)
+		if transport != nil {
+			client.NotionHTTP.Client.Transport = transport
 		}
-		debugLog("[DEBUG] No mapping found for any key in '%s'. No rewrite applied.\n", mdPath)
-		return mdContent, nil // no mapping for this page, return original content
+		if authHeaderName != "" {
+			client.SetAuthHeader(authHeaderName, authHeaderFormat)
+		}
+		notionClient = client
+	}
+	notionClient = notionsync.WithReport(notionClient, report)
+
+	// Ctrl-C cancels the requests in flight, a second one kills the run right away
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+	ctx = signalCtx
+	if timeout > 0 && !watch {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
-	debugLog("[DEBUG] Could not decode rewrite-text mapping file as single or multi-page mapping")
-	return nil, fmt.Errorf("Error decoding rewrite-text mapping file as single or multi-page mapping")
-}
 
-// debugLog prints debug messages if debugEnabled is true.
-func debugLog(format string, args ...interface{}) {
-	if debugEnabled {
-		fmt.Printf(format, args...)
+	if clearOnly {
+		if err := notionsync.ClearPage(ctx, notionClient, pageID, yes, opts.DryRun); err != nil {
+			exitIfCancelled(ctx, timeout)
+			failf("%s", err)
+		}
+		exit(0)
 	}
-}
 
-func debugBlocks(blocks []notion.Block) {
-	// early dropout
-	if !debugEnabled {
-		return
+	if mdDir != "" {
+		code := notionsync.SyncDirectory(ctx, opts, notionClient, mdDir, pageMapPath)
+		exitIfCancelled(ctx, timeout)
+		exit(code)
 	}
 
-	debugLog("Found %d blocks after markdown conversion\n", len(blocks))
-	for i, block := range blocks {
-		debugLog("Block %d is of type: %T\n", i, block)
+	if multiDoc {
+		code := notionsync.SyncMultiDocument(ctx, opts, notionClient, mdPath)
+		exitIfCancelled(ctx, timeout)
+		exit(code)
+	}
 
-		// Check specifically for code blocks
-		if codeBlock, ok := block.(*notion.CodeBlock); ok {
-			debugLog("Found code block at index %d\n", i)
-			if codeBlock.Language == nil {
-				debugLog("  Language is nil\n")
-			} else {
-				debugLog("  Language is '%s'\n", *codeBlock.Language)
+	if watch {
+		syncWatched := func() {
+			syncCtx, cancel := ctx, context.CancelFunc(func() {})
+			if timeout > 0 {
+				syncCtx, cancel = context.WithTimeout(ctx, timeout)
 			}
+			defer cancel()
+			printWatchResult(syncCtx, mdPath, timeout, notionsync.SyncFile(syncCtx, opts, notionClient, mdPath, pageID))
+		}
+		syncWatched()
+		fmt.Fprintf(out, "Watching %s for changes, press Ctrl-C to stop.\n", mdPath)
+		if err := notionsync.WatchFile(ctx, mdPath, opts.Images, watchDebounce, syncWatched); err != nil {
+			failf("%s", err)
 		}
+		fmt.Fprintln(out, "Stopped watching.")
+		exit(0)
 	}
-}
 
-// mapLanguageToNotionCompatible maps common language identifiers to Notion-compatible values
-func mapLanguageToNotionCompatible(lang string) string {
-	langMap := map[string]string{
-		"sh":   "shell",
-		"bash": "bash",
-		"zsh":  "shell",
-		"js":   "javascript",
-		"ts":   "typescript",
-		"py":   "python",
-		"rb":   "ruby",
-		"cs":   "c#",
-		"cpp":  "c++",
-		"yml":  "yaml",
-		"text": "plain text",
-		"txt":  "plain text",
-	}
-
-	if mapped, ok := langMap[lang]; ok {
-		return mapped
-	}
-	return lang
+	if err := notionsync.SyncFile(ctx, opts, notionClient, mdPath, pageID); err != nil {
+		if errors.Is(err, notionsync.ErrContentUnchanged) {
+			exit(0)
+		}
+		exitIfCancelled(ctx, timeout)
+		report.Fail(err)
+		if !errors.Is(err, notionsync.ErrValidationFailed) {
+			fmt.Fprintln(out, err)
+		}
+		exit(1)
+	}
+	exit(0)
 }
 
-// rewriteTextMap replaces markdown links according to the mapping
-func rewriteTextMap(content string, linkMap map[string]string) string {
-	fmt.Printf("Rewriting %d links:\n", len(linkMap))
-	for old, new := range linkMap {
-		fmt.Printf("Replacing:  '%s' -> '%s'\n", old, new)
-		// Replace text if present anywhere
-		content = strings.ReplaceAll(content, old, new)
+// resolveToken returns the token to use: --token if given, else the content of --token-file
+// without surrounding whitespace, else the NOTION_TOKEN environment variable
+func resolveToken(token, tokenFile string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("Error reading token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("Token file '%s' is empty", tokenFile)
+		}
+		return token, nil
 	}
-	return content
+	return os.Getenv("NOTION_TOKEN"), nil
 }
 
-// filterTitleBlock checks if the first block is a title node, removes and returns it. Otherwise returns nil, blocks.
-func filterTitleBlock(blocks []notion.Block) (notion.Block, []notion.Block) {
-	if len(blocks) == 0 {
-		return nil, blocks
-	}
-	if _, ok := blocks[0].(notion.Heading1Block); ok {
-		return blocks[0], blocks[1:]
+// debugf prints a debug message with --debug
+func debugf(format string, args ...interface{}) {
+	if debug {
+		fmt.Fprintf(out, format, args...)
 	}
-	return nil, blocks
 }
 
-// processCodeBlock handles both pointer and non-pointer code blocks and returns a non-pointer type
-func processCodeBlock(i int, codeBlock notion.CodeBlock) notion.CodeBlock {
-	defaultLang := "plain text"
-	if codeBlock.Language == nil {
-		codeBlock.Language = &defaultLang
-		fmt.Printf("⚠️  Fixed code block at index %d: set nil language to '%s'\n", i, defaultLang)
-	} else {
-		// Map language to Notion-compatible value
-		originalLang := *codeBlock.Language
-		mappedLang := mapLanguageToNotionCompatible(originalLang)
-		if mappedLang != originalLang {
-			*codeBlock.Language = mappedLang
-			fmt.Printf("⚠️  Fixed code block at index %d: mapped language from '%s' to '%s'\n", i, originalLang, mappedLang)
-		}
+// exit writes the run report, if one was requested, and exits with code
+func exit(code int) {
+	if err := report.Finish(code); err != nil {
+		fmt.Fprintf(out, "Warning: %s\n", err)
 	}
-	debugLog("⚠️  Code block at index %d has language: %s\n", i, *codeBlock.Language)
-	return codeBlock
+	os.Exit(code)
 }
 
-// validateContentBlocks scans and patches Notion blocks for known API problems (e.g., empty bulleted list items)
-func validateContentBlocks(blocks []notion.Block) []notion.Block {
-	var patched []notion.Block
-	for i, block := range blocks {
-		// Convert pointer to non-pointer type
-		if _, ok := block.(*notion.CodeBlock); ok {
-			block = notion.CodeBlock(*block.(*notion.CodeBlock))
-		}
+// failf prints an error, records it in the run report and exits with 1
+func failf(format string, args ...interface{}) {
+	err := fmt.Errorf(format, args...)
+	report.Fail(err)
+	fmt.Fprintln(out, err)
+	exit(1)
+}
 
-		switch b := block.(type) {
-		case notion.BulletedListItemBlock:
-			if len(b.RichText) == 0 || (len(b.RichText) == 1 && b.RichText[0].PlainText == "") {
-				fmt.Printf("⚠️  Skipping empty bulleted list item at index %d\n", i)
-				continue
-			}
-			if len(b.Children) > 0 {
-				b.Children = validateContentBlocks(b.Children)
-			}
-			patched = append(patched, b)
-		case notion.NumberedListItemBlock:
-			if len(b.RichText) == 0 || (len(b.RichText) == 1 && b.RichText[0].PlainText == "") {
-				fmt.Printf("⚠️  Skipping empty numbered list item at index %d\n", i)
-				continue
-			}
-			if len(b.Children) > 0 {
-				b.Children = validateContentBlocks(b.Children)
-			}
-			patched = append(patched, b)
-		case notion.CodeBlock:
-			b = processCodeBlock(i, b)
-			patched = append(patched, b)
-		default:
-			// TODO: add more block type checks as needed
-			patched = append(patched, block)
-		}
+// exitIfCancelled fails the run with a message saying why if ctx was interrupted or timed out,
+// as the error of the request in flight only says its context was canceled
+func exitIfCancelled(ctx context.Context, timeout time.Duration) {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		failf("Timed out after %s, the page may hold part of the new content.", timeout)
+	case context.Canceled:
+		failf("Interrupted, the page may hold part of the new content.")
 	}
-	return patched
 }
 
-// printTitle prints a detailed operation title based on flags and arguments
-func printAppTitle(mdPath string, replaceF, useHash bool, rewriteText string) {
-	mode := "append"
-	if replaceF {
-		mode = "replace"
-	}
-	details := []string{"NotionMD Cli: Processing file '" + mdPath + "' using " + mode}
-	if useHash {
-		details = append(details, "content hash check enabled")
-	}
-	if rewriteText != "" {
-		details = append(details, "rewrite mapping: '"+rewriteText+"'")
+// printWatchResult prints a timestamped line saying how a --watch sync of mdPath went
+func printWatchResult(ctx context.Context, mdPath string, timeout time.Duration, err error) {
+	stamp := time.Now().Format(time.TimeOnly)
+	switch {
+	case err == nil:
+		fmt.Fprintf(out, "[%s] Synced %s\n", stamp, mdPath)
+	case errors.Is(err, notionsync.ErrContentUnchanged):
+		fmt.Fprintf(out, "[%s] %s is unchanged, skipped\n", stamp, mdPath)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(out, "[%s] Sync of %s timed out after %s, the page may hold part of the new content\n", stamp, mdPath, timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		fmt.Fprintf(out, "[%s] Sync of %s interrupted, the page may hold part of the new content\n", stamp, mdPath)
+	case errors.Is(err, notionsync.ErrValidationFailed):
+		fmt.Fprintf(out, "[%s] %s failed validation, fix it and save again\n", stamp, mdPath)
+	default:
+		fmt.Fprintf(out, "[%s] Sync of %s failed: %s\n", stamp, mdPath, err)
 	}
-	fmt.Println("\n===== " + strings.Join(details, ", ") + " =====\n")
 }
@@ -0,0 +1,123 @@
+package notionsync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find an MkDocs admonition header: !!! type "Optional title"
+var admonitionRegex = regexp.MustCompile(`^!!!\s+([A-Za-z][\w-]*)(?:\s+[\w-]+)*(?:\s+"([^"]*)")?\s*$`)
+
+// Regular expression to find a GitHub alert marker on the first line of a blockquote: [!NOTE]
+var alertMarkerRegex = regexp.MustCompile(`^\s*\[!([A-Za-z]+)\]\s*$`)
+
+// admonitionStyle is the icon and color of the callout an admonition type becomes
+type admonitionStyle struct {
+	Emoji string
+	Color notion.Color
+}
+
+// admonitionStyles maps MkDocs admonition and GitHub alert types to their callout style
+var admonitionStyles = map[string]admonitionStyle{
+	"note":      {"📝", notion.ColorBlueBg},
+	"abstract":  {"📋", notion.ColorBlueBg},
+	"summary":   {"📋", notion.ColorBlueBg},
+	"tldr":      {"📋", notion.ColorBlueBg},
+	"info":      {"ℹ️", notion.ColorBlueBg},
+	"todo":      {"ℹ️", notion.ColorBlueBg},
+	"tip":       {"💡", notion.ColorGreenBg},
+	"hint":      {"💡", notion.ColorGreenBg},
+	"important": {"❗", notion.ColorPurpleBg},
+	"success":   {"✅", notion.ColorGreenBg},
+	"check":     {"✅", notion.ColorGreenBg},
+	"done":      {"✅", notion.ColorGreenBg},
+	"question":  {"❓", notion.ColorYellowBg},
+	"help":      {"❓", notion.ColorYellowBg},
+	"faq":       {"❓", notion.ColorYellowBg},
+	"warning":   {"⚠️", notion.ColorYellowBg},
+	"attention": {"⚠️", notion.ColorYellowBg},
+	"caution":   {"🛑", notion.ColorRedBg},
+	"failure":   {"❌", notion.ColorRedBg},
+	"fail":      {"❌", notion.ColorRedBg},
+	"missing":   {"❌", notion.ColorRedBg},
+	"danger":    {"⛔", notion.ColorRedBg},
+	"error":     {"⛔", notion.ColorRedBg},
+	"bug":       {"🐛", notion.ColorRedBg},
+	"example":   {"🧪", notion.ColorPurpleBg},
+	"quote":     {"💬", notion.ColorGrayBg},
+	"cite":      {"💬", notion.ColorGrayBg},
+}
+
+// convertAdmonition converts the MkDocs admonition whose header is lines[start] and whose body
+// is indented below it into a callout. Returns the callout and the next line index.
+func (c *markdownConverter) convertAdmonition(ctx context.Context, lines []string, start int) (notion.Block, int, error) {
+	line := lines[start]
+	match := admonitionRegex.FindStringSubmatchIndex(line)
+	var title *string
+	if match[4] >= 0 {
+		quoted := line[match[4]:match[5]]
+		title = &quoted
+	}
+	body, next := indentedBody(lines, start+1)
+	callout, err := c.newAdmonitionCallout(ctx, line[match[2]:match[3]], title, body)
+	return callout, next, err
+}
+
+// convertAlert converts the content of a blockquote opening with a GitHub alert marker
+// ("> [!WARNING]") into a callout. ok is false if the quote isn't an alert.
+func (c *markdownConverter) convertAlert(ctx context.Context, inner []string) (callout notion.Block, ok bool, err error) {
+	match := alertMarkerRegex.FindStringSubmatch(inner[0])
+	if match == nil {
+		return nil, false, nil
+	}
+	callout, err = c.newAdmonitionCallout(ctx, match[1], nil, strings.Join(inner[1:], "\n"))
+	return callout, true, err
+}
+
+// newAdmonitionCallout builds the callout for an admonition of the given type. The body is
+// converted like any markdown, so code blocks, lists and images become the callout's children.
+// The callout's text is the title, the capitalized type if there is none, or the body's first
+// paragraph if the title is explicitly empty.
+func (c *markdownConverter) newAdmonitionCallout(ctx context.Context, kind string, title *string, body string) (notion.Block, error) {
+	kind = strings.ToLower(kind)
+	style, ok := admonitionStyles[kind]
+	if !ok {
+		style = admonitionStyles["note"]
+	}
+	emoji := style.Emoji
+	callout := &notion.CalloutBlock{
+		RichText: []notion.RichText{},
+		Icon:     &notion.Icon{Type: notion.IconTypeEmoji, Emoji: &emoji},
+		Color:    style.Color,
+	}
+
+	children, err := c.convert(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case title == nil:
+		callout.RichText = boldRichText(strings.ToUpper(kind[:1]) + kind[1:])
+	case *title != "":
+		callout.RichText = boldRichText(*title)
+	case len(children) > 0:
+		if paragraph, ok := children[0].(*notion.ParagraphBlock); ok && len(paragraph.Children) == 0 {
+			callout.RichText = paragraph.RichText
+			children = children[1:]
+		}
+	}
+	if len(children) > 0 {
+		callout.Children = children
+	}
+	return callout, nil
+}
+
+// boldRichText builds a bold rich text slice for content
+func boldRichText(content string) []notion.RichText {
+	richText := plainRichText(content)
+	richText[0].Annotations = &notion.Annotations{Bold: true}
+	return richText
+}
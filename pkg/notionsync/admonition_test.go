@@ -0,0 +1,111 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestConvertAdmonitionWithCodeAndList(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{
+			name:     "mkdocs admonition",
+			markdown: "!!! warning \"Before you start\"\n    Check the version:\n\n    ```sh\n    tool --version\n    ```\n\n    - first\n    - second\n\nAfter.\n",
+		},
+		{
+			name:     "github alert",
+			markdown: "> [!WARNING]\n> Check the version:\n>\n> ```sh\n> tool --version\n> ```\n>\n> - first\n> - second\n\nAfter.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.CalloutBlock", "notion.ParagraphBlock"}) {
+				t.Fatalf("blocks = %v, want the callout and the paragraph after it", got)
+			}
+			callout := blocks[0].(*notion.CalloutBlock)
+			if callout.Icon == nil || callout.Icon.Emoji == nil || *callout.Icon.Emoji != "⚠️" || callout.Color != notion.ColorYellowBg {
+				t.Errorf("callout style = %+v %q, want the warning style", callout.Icon, callout.Color)
+			}
+			// With a title, or labelled by its type, the body's first paragraph stays a child
+			want := []string{"notion.ParagraphBlock", "notion.CodeBlock", "notion.BulletedListItemBlock", "notion.BulletedListItemBlock"}
+			children := callout.Children
+			if got := blockTypes(children); !slices.Equal(got, want) {
+				t.Fatalf("callout holds %v, want %v", got, want)
+			}
+			code := children[1].(*notion.CodeBlock)
+			if ownText(code) != "tool --version" || code.Language == nil || *code.Language != "shell" {
+				t.Errorf("code block = %q, want the indented code as shell", ownText(code))
+			}
+			if ownText(children[2]) != "first" || ownText(children[3]) != "second" {
+				t.Errorf("list = %q, %q", ownText(children[2]), ownText(children[3]))
+			}
+		})
+	}
+}
+
+func TestConvertAdmonitionTitles(t *testing.T) {
+	tests := []struct {
+		name         string
+		markdown     string
+		wantText     string
+		wantChildren int
+		wantEmoji    string
+	}{
+		{name: "titled", markdown: "!!! tip \"Shortcut\"\n    Press the key.\n", wantText: "Shortcut", wantChildren: 1, wantEmoji: "💡"},
+		{name: "untitled uses the type", markdown: "!!! note\n    Read this.\n", wantText: "Note", wantChildren: 1, wantEmoji: "📝"},
+		{name: "empty title uses the first paragraph", markdown: "!!! danger \"\"\n    Don't.\n\n    - really\n", wantText: "Don't.", wantChildren: 1, wantEmoji: "⛔"},
+		{name: "unknown type looks like a note", markdown: "!!! custom\n    Text.\n", wantText: "Custom", wantChildren: 1, wantEmoji: "📝"},
+		{name: "alert type is case insensitive", markdown: "> [!tip]\n> Text.\n", wantText: "Tip", wantChildren: 1, wantEmoji: "💡"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one callout", blockTypes(blocks))
+			}
+			callout, ok := blocks[0].(*notion.CalloutBlock)
+			if !ok {
+				t.Fatalf("block = %T, want a callout", blocks[0])
+			}
+			if got := ownText(callout); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if got := len(callout.Children); got != tt.wantChildren {
+				t.Errorf("callout holds %v, want %d blocks", blockTypes(callout.Children), tt.wantChildren)
+			}
+			if *callout.Icon.Emoji != tt.wantEmoji {
+				t.Errorf("emoji = %q, want %q", *callout.Icon.Emoji, tt.wantEmoji)
+			}
+		})
+	}
+}
+
+func TestSyncFileAdmonitionNesting(t *testing.T) {
+	// The nested list puts its items three levels deep, past what one request may nest
+	markdown := "# Title\n\n!!! example\n    ```go\n    fmt.Println(1)\n    ```\n\n    - outer\n        - inner\n            - innermost\n"
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if len(content) != 1 {
+		t.Fatalf("content = %v, want the callout", blockTypes(content))
+	}
+	children := blockChildren(content[0])
+	if got := blockTypes(children); !slices.Equal(got, []string{"notion.CodeBlock", "notion.BulletedListItemBlock"}) {
+		t.Fatalf("callout holds %v, want the code and the list", got)
+	}
+	inner := blockChildren(children[1])
+	if len(inner) != 1 || ownText(inner[0]) != "inner" {
+		t.Fatalf("outer item holds %v, want the inner item", blockTypes(inner))
+	}
+	if innermost := blockChildren(inner[0]); len(innermost) != 1 || ownText(innermost[0]) != "innermost" {
+		t.Errorf("inner item holds %v, want the innermost item", blockTypes(innermost))
+	}
+}
@@ -0,0 +1,47 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// sectionEnd finds the first heading among the page's blocks whose text is heading, ignoring
+// case and surrounding whitespace. Returns the ID of the last block of its section, before the
+// next heading of the same or a higher level, or of the heading itself when the section is empty.
+func sectionEnd(live []notion.Block, heading string) (string, bool) {
+	heading = strings.TrimSpace(heading)
+	for i, block := range live {
+		level := headingLevel(block)
+		if level == 0 || !strings.EqualFold(strings.TrimSpace(richTextPlainText(blockRichText(block))), heading) {
+			continue
+		}
+		end := i
+		for end+1 < len(live) {
+			if next := headingLevel(live[end+1]); next > 0 && next <= level {
+				break
+			}
+			end++
+		}
+		return live[end].ID(), true
+	}
+	return "", false
+}
+
+// appendUnderHeading adds blocks at the end of the section under the page's heading, falling
+// back to the bottom of the page with a warning when the page has no such heading
+func appendUnderHeading(ctx context.Context, notionClient NotionClientInterface, pageID, heading string, blocks []notion.Block) ([]string, error) {
+	live, err := notionClient.GetPageContent(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the page: %w", err)
+	}
+	afterID, ok := sectionEnd(live, heading)
+	if !ok {
+		warnf(ctx, "The page has no heading '%s', appending to the bottom of the page\n", heading)
+		return notionClient.AddPageContent(ctx, pageID, blocks)
+	}
+	fmt.Fprintf(output(ctx), "Appending under heading '%s'\n", heading)
+	return notionClient.ReplaceSection(ctx, pageID, afterID, nil, blocks)
+}
@@ -0,0 +1,228 @@
+package notionsync
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find a trailing pandoc attribute block: {#id .class key=value}
+var attributeBlockRegex = regexp.MustCompile(`(?:^|[ \t]+)\{([^{}]*)\}[ \t]*$`)
+
+// Regular expression to find one pandoc attribute: #id, .class, key=value or key="quoted value"
+var attributeRegex = regexp.MustCompile(`^(?:#([\w:.-]+)|\.([\w-]+)|([\w-]+)=(?:"([^"]*)"|'([^']*)'|([^\s"']+)))$`)
+
+// Regular expression to find a markdown image directly followed by an attribute block
+var imageAttributesRegex = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)\{([^{}]*)\}`)
+
+// pandocAttributes are the attributes of a pandoc attribute block
+type pandocAttributes struct {
+	ID      string
+	Classes []string
+	Values  map[string]string
+}
+
+// parseAttributes parses the inside of an attribute block. ok is false if anything in it isn't
+// an attribute, so braces in ordinary text are left alone.
+func parseAttributes(text string) (attrs pandocAttributes, ok bool) {
+	attrs.Values = map[string]string{}
+	fields := attributeFields(text)
+	if len(fields) == 0 {
+		return attrs, false
+	}
+	for _, field := range fields {
+		match := attributeRegex.FindStringSubmatch(field)
+		switch {
+		case match == nil:
+			return attrs, false
+		case match[1] != "":
+			attrs.ID = match[1]
+		case match[2] != "":
+			attrs.Classes = append(attrs.Classes, match[2])
+		default:
+			attrs.Values[match[3]] = match[4] + match[5] + match[6]
+		}
+	}
+	return attrs, true
+}
+
+// attributeFields splits the inside of an attribute block at whitespace outside quotes
+func attributeFields(text string) []string {
+	var (
+		fields  []string
+		current strings.Builder
+		quote   rune
+	)
+	for _, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// splitAttributes splits a trailing attribute block off text. ok is false if there is none.
+func splitAttributes(text string) (rest string, attrs pandocAttributes, ok bool) {
+	loc := attributeBlockRegex.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return text, attrs, false
+	}
+	if attrs, ok = parseAttributes(text[loc[2]:loc[3]]); !ok {
+		return text, attrs, false
+	}
+	return text[:loc[0]], attrs, true
+}
+
+// warnUnusedAttributes warns about the classes and keys of an attribute block that nothing
+// maps to a Notion feature. IDs are accepted silently: Notion blocks can't carry custom IDs,
+// links to a block use the ID Notion gives it.
+func warnUnusedAttributes(ctx context.Context, what string, classes []string, values map[string]string, used ...string) {
+	var unused []string
+	for _, class := range classes {
+		if !slices.Contains(used, "."+class) {
+			unused = append(unused, "."+class)
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !slices.Contains(used, key) {
+			unused = append(unused, key+"=")
+		}
+	}
+	if len(unused) > 0 {
+		warnf(ctx, "Dropping unsupported attributes on %s: %s\n", what, strings.Join(unused, " "))
+	}
+}
+
+// headingColor returns the Notion color a heading class or color= value names, such as red,
+// blue_background or blue-background
+func headingColor(name string) (notion.Color, bool) {
+	color := notion.Color(strings.ReplaceAll(strings.ToLower(name), "-", "_"))
+	return color, slices.Contains(notionColors, color)
+}
+
+// convertHeadingAttributes converts a heading line ending in an attribute block. A class or
+// color= value naming a Notion color colors the heading. ok is false if there is no attribute
+// block.
+func (c *markdownConverter) convertHeadingAttributes(ctx context.Context, line string) (blocks []notion.Block, ok bool, err error) {
+	text, attrs, ok := splitAttributes(line)
+	if !ok {
+		return nil, false, nil
+	}
+	if blocks, err = c.convert(ctx, text); err != nil {
+		return nil, true, err
+	}
+	var used []string
+	for _, class := range attrs.Classes {
+		if color, isColor := headingColor(class); isColor && len(blocks) == 1 {
+			blocks[0] = withHeadingColor(blocks[0], color)
+			used = append(used, "."+class)
+		}
+	}
+	if color, isColor := headingColor(attrs.Values["color"]); isColor && len(blocks) == 1 {
+		blocks[0] = withHeadingColor(blocks[0], color)
+		used = append(used, "color")
+	}
+	warnUnusedAttributes(ctx, "heading '"+strings.TrimLeft(strings.TrimSpace(text), "# ")+"'", attrs.Classes, attrs.Values, used...)
+	return blocks, true, nil
+}
+
+// withHeadingColor returns a heading block with its color set. Pointer blocks are updated in place.
+func withHeadingColor(block notion.Block, color notion.Color) notion.Block {
+	switch b := block.(type) {
+	case *notion.Heading1Block:
+		b.Color = color
+	case notion.Heading1Block:
+		b.Color = color
+		return b
+	case *notion.Heading2Block:
+		b.Color = color
+	case notion.Heading2Block:
+		b.Color = color
+		return b
+	case *notion.Heading3Block:
+		b.Color = color
+	case notion.Heading3Block:
+		b.Color = color
+		return b
+	case deepHeadingBlock:
+		b.Color = color
+		return b
+	}
+	return block
+}
+
+// rewriteImageAttributes turns attribute blocks after images into the markdown the image
+// handling understands: caption= replaces the alt text the caption is made from, width= and
+// height= become the ?width= and ?height= size parameters.
+func rewriteImageAttributes(ctx context.Context, line string) string {
+	return imageAttributesRegex.ReplaceAllStringFunc(line, func(image string) string {
+		match := imageAttributesRegex.FindStringSubmatch(image)
+		attrs, ok := parseAttributes(match[3])
+		if !ok {
+			return image
+		}
+		alt, src := match[1], match[2]
+		if caption, ok := attrs.Values["caption"]; ok {
+			alt = caption
+		}
+		var size []string
+		for _, key := range []string{"width", "height"} {
+			if value, ok := attrs.Values[key]; ok {
+				size = append(size, key+"="+url.QueryEscape(strings.TrimSuffix(value, "px")))
+			}
+		}
+		if len(size) > 0 {
+			separator := "?"
+			if strings.Contains(src, "?") {
+				separator = "&"
+			}
+			src += separator + strings.Join(size, "&")
+		}
+		warnUnusedAttributes(ctx, "image '"+match[2]+"'", attrs.Classes, attrs.Values, "caption", "width", "height")
+		return "![" + alt + "](" + src + ")"
+	})
+}
+
+// fenceAttributes splits the attribute block off a fence's info string, which is either all
+// attributes ({.python caption="Example"}) or follows the language (python {.numberLines}).
+// A class naming a language sets the language when the info string doesn't.
+func fenceAttributes(info string) (string, pandocAttributes) {
+	rest, attrs, ok := splitAttributes(info)
+	if !ok {
+		return info, pandocAttributes{}
+	}
+	if strings.TrimSpace(rest) == "" {
+		for _, class := range attrs.Classes {
+			if codeLanguage(class) != nil {
+				rest = class
+				break
+			}
+		}
+	}
+	return strings.TrimSpace(rest), attrs
+}
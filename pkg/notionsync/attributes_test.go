@@ -0,0 +1,155 @@
+package notionsync
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestParseAttributes(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   pandocAttributes
+		wantOK bool
+	}{
+		{
+			text:   `#intro .python .numberLines caption="An example" width=50%`,
+			want:   pandocAttributes{ID: "intro", Classes: []string{"python", "numberLines"}, Values: map[string]string{"caption": "An example", "width": "50%"}},
+			wantOK: true,
+		},
+		{
+			text:   `key='single quoted'`,
+			want:   pandocAttributes{Values: map[string]string{"key": "single quoted"}},
+			wantOK: true,
+		},
+		{text: "not attributes", wantOK: false},
+		{text: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		attrs, ok := parseAttributes(tt.text)
+		if ok != tt.wantOK {
+			t.Errorf("parseAttributes(%q) ok = %v, want %v", tt.text, ok, tt.wantOK)
+			continue
+		}
+		if ok && (attrs.ID != tt.want.ID || !slices.Equal(attrs.Classes, tt.want.Classes) || !maps.Equal(attrs.Values, tt.want.Values)) {
+			t.Errorf("parseAttributes(%q) = %+v, want %+v", tt.text, attrs, tt.want)
+		}
+	}
+}
+
+// convertWarnings converts markdown and returns the blocks and how many warnings it gave
+func convertWarnings(t *testing.T, markdown string) ([]notion.Block, int) {
+	t.Helper()
+	ctx := NewContext(context.Background(), testOptions())
+	blocks, err := convertMarkdown(ctx, markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks, warningCount(ctx)
+}
+
+func TestConvertCodeBlockAttributes(t *testing.T) {
+	tests := []struct {
+		name         string
+		markdown     string
+		wantLanguage string
+		wantCaption  string
+		wantWarnings int
+	}{
+		{
+			name:         "language class and caption",
+			markdown:     "```{.python #example caption=\"Hello *world*\"}\nprint(1)\n```\n",
+			wantLanguage: "python",
+			wantCaption:  "Hello world",
+		},
+		{
+			name:         "attributes after the language",
+			markdown:     "```go {caption=Example}\nfmt.Println(1)\n```\n",
+			wantLanguage: "go",
+			wantCaption:  "Example",
+		},
+		{
+			name:         "unrecognized attributes",
+			markdown:     "```{.go .numberLines startFrom=10}\nfmt.Println(1)\n```\n",
+			wantLanguage: "go",
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, warnings := convertWarnings(t, tt.markdown)
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one code block", blockTypes(blocks))
+			}
+			code, ok := blocks[0].(*notion.CodeBlock)
+			if !ok {
+				t.Fatalf("block = %T, want a code block", blocks[0])
+			}
+			if code.Language == nil || *code.Language != tt.wantLanguage {
+				t.Errorf("language = %v, want %q", code.Language, tt.wantLanguage)
+			}
+			if got := richTextPlainText(code.Caption); got != tt.wantCaption {
+				t.Errorf("caption = %q, want %q", got, tt.wantCaption)
+			}
+			if strings.ContainsAny(ownText(code), "{}") {
+				t.Errorf("code = %q, want the attributes left out", ownText(code))
+			}
+			if warnings != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestConvertHeadingAttributes(t *testing.T) {
+	tests := []struct {
+		name         string
+		markdown     string
+		wantText     string
+		wantColor    notion.Color
+		wantWarnings int
+	}{
+		{name: "color class", markdown: "## Setup {.red}\n", wantText: "Setup", wantColor: notion.ColorRed},
+		{name: "color value", markdown: "## Setup {color=blue-background}\n", wantText: "Setup", wantColor: notion.ColorBlueBg},
+		{name: "id only", markdown: "## Setup {#setup}\n", wantText: "Setup"},
+		{name: "unrecognized class", markdown: "## Setup {#setup .unnumbered}\n", wantText: "Setup", wantWarnings: 1},
+		{name: "braces that aren't attributes", markdown: "## Use {curly braces}\n", wantText: "Use {curly braces}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, warnings := convertWarnings(t, tt.markdown)
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one heading", blockTypes(blocks))
+			}
+			heading, ok := blocks[0].(notion.Heading2Block)
+			if !ok {
+				t.Fatalf("block = %T, want a level 2 heading", blocks[0])
+			}
+			if got := ownText(heading); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if heading.Color != tt.wantColor {
+				t.Errorf("color = %q, want %q", heading.Color, tt.wantColor)
+			}
+			if warnings != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestRewriteImageAttributes(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	got := rewriteImageAttributes(ctx, `See ![alt](a.png){caption="A chart" width=300px .wide} and ![b](b.png?x=1){height=20}.`)
+	want := "See ![A chart](a.png?width=300) and ![b](b.png?x=1&height=20)."
+	if got != want {
+		t.Errorf("rewriteImageAttributes = %q, want %q", got, want)
+	}
+	if n := warningCount(ctx); n != 1 {
+		t.Errorf("got %d warnings, want 1 for the .wide class", n)
+	}
+}
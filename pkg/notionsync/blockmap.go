@@ -0,0 +1,91 @@
+package notionsync
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// blockMapEntry records which Notion block a top level converted block became
+type blockMapEntry struct {
+	Index   int    `json:"index"`
+	Line    int    `json:"line,omitempty"`
+	Heading string `json:"heading,omitempty"`
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`
+	BlockID string `json:"block_id"`
+}
+
+// maxBlockMapText is how much of a block's text the block map keeps
+const maxBlockMapText = 80
+
+// buildBlockMap pairs the blocks sent to Notion with the IDs it returned. Each entry also
+// records the heading it falls under and, where the block's text can be found in the
+// markdown source, the 1-based line it starts on.
+func buildBlockMap(source string, blocks []notion.Block, blockIDs []string) []blockMapEntry {
+	lines := strings.Split(source, "\n")
+	cursor := 0
+	heading := ""
+	entries := make([]blockMapEntry, 0, len(blocks))
+	for i, block := range blocks {
+		if i >= len(blockIDs) {
+			break
+		}
+		text := richTextPlainText(blockRichText(block))
+		if headingLevel(block) > 0 {
+			heading = text
+		}
+		entry := blockMapEntry{
+			Index:   i,
+			Heading: heading,
+			Type:    blockTypeName(block),
+			Text:    truncateText(strings.ReplaceAll(text, "\n", " "), maxBlockMapText),
+			BlockID: blockIDs[i],
+		}
+		if line := findSourceLine(lines, cursor, text); line >= 0 {
+			entry.Line = line + 1
+			cursor = line + 1
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// findSourceLine returns the index of the first line from start containing the beginning of
+// text, or -1 when it can't be found. It looks for the first three words, then only the first
+// one, since inline markup in the source can sit between words.
+func findSourceLine(lines []string, start int, text string) int {
+	words := strings.Fields(strings.SplitN(text, "\n", 2)[0])
+	if len(words) == 0 {
+		return -1
+	}
+	probes := []string{strings.Join(words[:min(3, len(words))], " "), words[0]}
+	for _, probe := range probes {
+		for i := start; i < len(lines); i++ {
+			if strings.Contains(lines[i], probe) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// truncateText shortens text to at most limit characters, marking the cut with an ellipsis
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// writeBlockMap writes the block map entries to path as JSON
+func writeBlockMap(path string, entries []blockMapEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
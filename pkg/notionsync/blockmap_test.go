@@ -0,0 +1,69 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// appendChildrenAPI answers block children appends the way Notion does, returning the new
+// blocks with the IDs block-1, block-2 and so on. Other requests get an empty object.
+func appendChildrenAPI() roundTripFunc {
+	next := 0
+	return func(req *http.Request) *http.Response {
+		body := "{}"
+		if req.Method == http.MethodPatch && strings.HasSuffix(req.URL.Path, "/children") {
+			var sent struct {
+				Children []map[string]any `json:"children"`
+			}
+			data, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(data, &sent)
+			results := make([]map[string]any, len(sent.Children))
+			for i, child := range sent.Children {
+				next++
+				child["object"], child["id"] = "block", fmt.Sprintf("block-%d", next)
+				results[i] = child
+			}
+			data, _ = json.Marshal(map[string]any{"object": "list", "results": results})
+			body = string(data)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	}
+}
+
+func TestSyncFileWritesBlockMap(t *testing.T) {
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: appendChildrenAPI()}
+	opts := testOptions()
+	opts.TitleLevel = 0
+	opts.BlockMapOut = filepath.Join(t.TempDir(), "blocks.json")
+	markdown := "# Install\n\nRun the **installer** first.\n\n## Configure\n\n- set the token\n"
+	if err := SyncFile(context.Background(), opts, c, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(opts.BlockMapOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []blockMapEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []blockMapEntry{
+		{Index: 0, Line: 1, Heading: "Install", Type: "heading_1", Text: "Install", BlockID: "block-1"},
+		{Index: 1, Line: 3, Heading: "Install", Type: "paragraph", Text: "Run the installer first.", BlockID: "block-2"},
+		{Index: 2, Line: 5, Heading: "Configure", Type: "heading_2", Text: "Configure", BlockID: "block-3"},
+		{Index: 3, Line: 7, Heading: "Configure", Type: "bulleted_list_item", Text: "set the token", BlockID: "block-4"},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("block map =\n%+v\nwant\n%+v", entries, want)
+	}
+}
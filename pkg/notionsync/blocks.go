@@ -0,0 +1,269 @@
+package notionsync
+
+import (
+	"slices"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// maxRichTextLength is the longest content Notion accepts in a single rich text element
+const maxRichTextLength = 2000
+
+// maxBlocksPerRequest is the most children Notion accepts in a single append request
+const maxBlocksPerRequest = 100
+
+// maxNestingDepth is how many levels of children Notion accepts in a single append request
+const maxNestingDepth = 2
+
+// blockRichText returns the rich text content of a block, if its type has any.
+// Converted blocks come as values or pointers, blocks read back from Notion as pointers.
+func blockRichText(block notion.Block) []notion.RichText {
+	switch b := block.(type) {
+	case *notion.ParagraphBlock:
+		return b.RichText
+	case notion.ParagraphBlock:
+		return b.RichText
+	case *notion.Heading1Block:
+		return b.RichText
+	case notion.Heading1Block:
+		return b.RichText
+	case *notion.Heading2Block:
+		return b.RichText
+	case notion.Heading2Block:
+		return b.RichText
+	case *notion.Heading3Block:
+		return b.RichText
+	case notion.Heading3Block:
+		return b.RichText
+	case *notion.BulletedListItemBlock:
+		return b.RichText
+	case notion.BulletedListItemBlock:
+		return b.RichText
+	case *notion.NumberedListItemBlock:
+		return b.RichText
+	case notion.NumberedListItemBlock:
+		return b.RichText
+	case *notion.ToDoBlock:
+		return b.RichText
+	case notion.ToDoBlock:
+		return b.RichText
+	case *notion.ToggleBlock:
+		return b.RichText
+	case notion.ToggleBlock:
+		return b.RichText
+	case *notion.QuoteBlock:
+		return b.RichText
+	case notion.QuoteBlock:
+		return b.RichText
+	case *notion.CalloutBlock:
+		return b.RichText
+	case notion.CalloutBlock:
+		return b.RichText
+	case *notion.CodeBlock:
+		return b.RichText
+	case notion.CodeBlock:
+		return b.RichText
+	}
+	return nil
+}
+
+// blockChildren returns the nested children of a block, if its type supports any
+func blockChildren(block notion.Block) []notion.Block {
+	switch b := block.(type) {
+	case *notion.ParagraphBlock:
+		return b.Children
+	case notion.ParagraphBlock:
+		return b.Children
+	case *notion.BulletedListItemBlock:
+		return b.Children
+	case notion.BulletedListItemBlock:
+		return b.Children
+	case *notion.NumberedListItemBlock:
+		return b.Children
+	case notion.NumberedListItemBlock:
+		return b.Children
+	case *notion.ToDoBlock:
+		return b.Children
+	case notion.ToDoBlock:
+		return b.Children
+	case *notion.ToggleBlock:
+		return b.Children
+	case notion.ToggleBlock:
+		return b.Children
+	case *notion.QuoteBlock:
+		return b.Children
+	case notion.QuoteBlock:
+		return b.Children
+	case *notion.CalloutBlock:
+		return b.Children
+	case notion.CalloutBlock:
+		return b.Children
+	}
+	return nil
+}
+
+// headingLevel returns 1, 2 or 3 for heading blocks and 0 for anything else
+func headingLevel(block notion.Block) int {
+	switch block.(type) {
+	case notion.Heading1Block, *notion.Heading1Block:
+		return 1
+	case notion.Heading2Block, *notion.Heading2Block:
+		return 2
+	case notion.Heading3Block, *notion.Heading3Block:
+		return 3
+	}
+	return 0
+}
+
+// withRichText returns block with its rich text replaced. Pointer blocks are updated in place.
+func withRichText(block notion.Block, richText []notion.RichText) notion.Block {
+	switch b := block.(type) {
+	case *notion.ParagraphBlock:
+		b.RichText = richText
+	case notion.ParagraphBlock:
+		b.RichText = richText
+		return b
+	case *notion.Heading1Block:
+		b.RichText = richText
+	case notion.Heading1Block:
+		b.RichText = richText
+		return b
+	case *notion.Heading2Block:
+		b.RichText = richText
+	case notion.Heading2Block:
+		b.RichText = richText
+		return b
+	case *notion.Heading3Block:
+		b.RichText = richText
+	case notion.Heading3Block:
+		b.RichText = richText
+		return b
+	case *notion.BulletedListItemBlock:
+		b.RichText = richText
+	case notion.BulletedListItemBlock:
+		b.RichText = richText
+		return b
+	case *notion.NumberedListItemBlock:
+		b.RichText = richText
+	case notion.NumberedListItemBlock:
+		b.RichText = richText
+		return b
+	case *notion.ToDoBlock:
+		b.RichText = richText
+	case notion.ToDoBlock:
+		b.RichText = richText
+		return b
+	case *notion.ToggleBlock:
+		b.RichText = richText
+	case notion.ToggleBlock:
+		b.RichText = richText
+		return b
+	case *notion.QuoteBlock:
+		b.RichText = richText
+	case notion.QuoteBlock:
+		b.RichText = richText
+		return b
+	case *notion.CalloutBlock:
+		b.RichText = richText
+	case notion.CalloutBlock:
+		b.RichText = richText
+		return b
+	case *notion.CodeBlock:
+		b.RichText = richText
+	case notion.CodeBlock:
+		b.RichText = richText
+		return b
+	}
+	return block
+}
+
+// withChildren returns block with its children replaced. Pointer blocks are updated in place.
+func withChildren(block notion.Block, children []notion.Block) notion.Block {
+	switch b := block.(type) {
+	case *notion.ParagraphBlock:
+		b.Children = children
+	case notion.ParagraphBlock:
+		b.Children = children
+		return b
+	case *notion.BulletedListItemBlock:
+		b.Children = children
+	case notion.BulletedListItemBlock:
+		b.Children = children
+		return b
+	case *notion.NumberedListItemBlock:
+		b.Children = children
+	case notion.NumberedListItemBlock:
+		b.Children = children
+		return b
+	case *notion.ToDoBlock:
+		b.Children = children
+	case notion.ToDoBlock:
+		b.Children = children
+		return b
+	case *notion.ToggleBlock:
+		b.Children = children
+	case notion.ToggleBlock:
+		b.Children = children
+		return b
+	case *notion.QuoteBlock:
+		b.Children = children
+	case notion.QuoteBlock:
+		b.Children = children
+		return b
+	case *notion.CalloutBlock:
+		b.Children = children
+	case notion.CalloutBlock:
+		b.Children = children
+		return b
+	}
+	return block
+}
+
+// isCodeBlock reports whether block is a code block, whose content must never be transformed
+func isCodeBlock(block notion.Block) bool {
+	switch block.(type) {
+	case notion.CodeBlock, *notion.CodeBlock:
+		return true
+	}
+	return false
+}
+
+// deepChildren are the children of a block nested deeper than Notion accepts in a single
+// append request. path holds the block's index at every level, starting at the top.
+type deepChildren struct {
+	path     []int
+	children []notion.Block
+}
+
+// detachDeepChildren removes the children nested deeper than maxNestingDepth from blocks,
+// returning them with where they were. Blocks are changed in place, reattachDeepChildren
+// puts the children back.
+func detachDeepChildren(blocks []notion.Block, path []int) []deepChildren {
+	var detached []deepChildren
+	for i, block := range blocks {
+		children := blockChildren(block)
+		if len(children) == 0 {
+			continue
+		}
+		blockPath := append(slices.Clone(path), i)
+		if len(blockPath) > maxNestingDepth {
+			detached = append(detached, deepChildren{path: blockPath, children: children})
+			blocks[i] = withChildren(block, nil)
+			continue
+		}
+		detached = append(detached, detachDeepChildren(children, blockPath)...)
+	}
+	return detached
+}
+
+// reattachDeepChildren puts the children removed by detachDeepChildren back into blocks
+func reattachDeepChildren(blocks []notion.Block, detached []deepChildren) {
+	for _, deep := range detached {
+		level := blocks
+		for _, index := range deep.path[:len(deep.path)-1] {
+			level = blockChildren(level[index])
+		}
+		index := deep.path[len(deep.path)-1]
+		level[index] = withChildren(level[index], deep.children)
+	}
+}
@@ -0,0 +1,47 @@
+package notionsync
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find a paragraph consisting of a single URL: https://example.com/article
+var bareURLRegex = regexp.MustCompile(`^https?://\S+$`)
+
+// ProcessBookmarkBlocks replaces paragraphs holding nothing but a URL with bookmark blocks
+// pointing at it, recursing into children. Paragraphs with any other text, or linking
+// somewhere else than the URL they show, are left alone.
+func ProcessBookmarkBlocks(blocks []notion.Block) []notion.Block {
+	for i, block := range blocks {
+		if paragraph, ok := block.(*notion.ParagraphBlock); ok && len(paragraph.Children) == 0 {
+			if url, ok := bareURL(paragraph.RichText); ok {
+				blocks[i] = &notion.BookmarkBlock{URL: url}
+				continue
+			}
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			blocks[i] = withChildren(block, ProcessBookmarkBlocks(children))
+		}
+	}
+	return blocks
+}
+
+// bareURL returns the URL a paragraph's rich text consists of. Runs may only link to that
+// same URL, a mention or equation disqualifies the paragraph.
+func bareURL(richText []notion.RichText) (string, bool) {
+	url := strings.TrimSpace(richTextPlainText(richText))
+	if !bareURLRegex.MatchString(url) {
+		return "", false
+	}
+	for _, rt := range richText {
+		if rt.Text == nil {
+			return "", false
+		}
+		if rt.Text.Link != nil && rt.Text.Link.URL != url {
+			return "", false
+		}
+	}
+	return url, true
+}
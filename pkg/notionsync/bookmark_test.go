@@ -0,0 +1,81 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestProcessBookmarkBlocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		wantTypes []string
+		wantURL   string
+	}{
+		{
+			name:      "bare URL",
+			markdown:  "https://example.com/article\n",
+			wantTypes: []string{"notion.BookmarkBlock"},
+			wantURL:   "https://example.com/article",
+		},
+		{
+			name:      "autolink",
+			markdown:  "<https://example.com/article?id=1>\n",
+			wantTypes: []string{"notion.BookmarkBlock"},
+			wantURL:   "https://example.com/article?id=1",
+		},
+		{
+			name:      "URL with text",
+			markdown:  "Read https://example.com/article first.\n",
+			wantTypes: []string{"notion.ParagraphBlock"},
+		},
+		{
+			name:      "multiple URLs",
+			markdown:  "https://example.com/one https://example.com/two\n",
+			wantTypes: []string{"notion.ParagraphBlock"},
+		},
+		{
+			name:      "link elsewhere",
+			markdown:  "[https://example.com/article](https://example.com/other)\n",
+			wantTypes: []string{"notion.ParagraphBlock"},
+		},
+		{
+			name:      "not a web URL",
+			markdown:  "ftp://example.com/file\n",
+			wantTypes: []string{"notion.ParagraphBlock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := ProcessBookmarkBlocks(convert(t, tt.markdown))
+			if got := blockTypes(blocks); !slices.Equal(got, tt.wantTypes) {
+				t.Fatalf("blocks = %v, want %v", got, tt.wantTypes)
+			}
+			if bookmark, ok := blocks[0].(*notion.BookmarkBlock); ok && bookmark.URL != tt.wantURL {
+				t.Errorf("bookmark URL = %q, want %q", bookmark.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestSyncFileBookmarkURLs(t *testing.T) {
+	const markdown = "# Title\n\nhttps://example.com/article\n\n> https://example.com/quoted\n\nSee https://example.com/article.\n"
+	for _, enabled := range []bool{false, true} {
+		client := newFakeNotionClient()
+		opts := testOptions()
+		opts.BookmarkURLs = enabled
+		if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"notion.ParagraphBlock", "notion.QuoteBlock", "notion.ParagraphBlock"}
+		if enabled {
+			want[0] = "notion.BookmarkBlock"
+		}
+		if got := blockTypes(client.content["page"]); !slices.Equal(got, want) {
+			t.Errorf("with bookmarks %v blocks = %v, want %v", enabled, got, want)
+		}
+	}
+}
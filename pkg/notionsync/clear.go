@@ -0,0 +1,44 @@
+package notionsync
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ClearPage removes all content of the page pageID without adding anything. Unless yes is
+// set the user has to confirm on the terminal; without one, --yes is required.
+func ClearPage(ctx context.Context, notionClient NotionClientInterface, pageID string, yes, dryRun bool) error {
+	if dryRun {
+		fmt.Fprintf(output(ctx), "Dry run: would clear all content of page %s\n", pageID)
+		return nil
+	}
+	if !yes {
+		confirmed, err := confirm(ctx, fmt.Sprintf("Clear all content of page %s?", pageID))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("Clearing page %s was not confirmed", pageID)
+		}
+	}
+	if err := notionClient.ClearPageContent(ctx, pageID); err != nil {
+		return fmt.Errorf("Error clearing page content: %w", err)
+	}
+	fmt.Fprintln(output(ctx), "✅ Page content cleared.")
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, failing if stdin isn't one
+func confirm(ctx context.Context, question string) (bool, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false, fmt.Errorf("%s Pass --yes to confirm when not running in a terminal", question)
+	}
+	fmt.Fprintf(output(ctx), "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,78 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestClearPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		yes       bool
+		dryRun    bool
+		wantErr   bool
+		wantCalls []string
+		wantOut   string
+	}{
+		{
+			name:      "confirmed clears and adds nothing",
+			yes:       true,
+			wantCalls: []string{"ClearPageContent page"},
+			wantOut:   "Page content cleared",
+		},
+		{
+			name:    "dry run only reports",
+			yes:     true,
+			dryRun:  true,
+			wantOut: "Dry run: would clear all content of page page",
+		},
+		{
+			name:    "unconfirmed leaves the page alone",
+			wantErr: true,
+		},
+	}
+	// With stdin not a terminal an unconfirmed clear fails instead of prompting
+	stdin := os.Stdin
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	os.Stdin = reader
+	defer func() { os.Stdin = stdin }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			opts := testOptions()
+			opts.StatusOutput = &out
+			ctx := NewContext(context.Background(), opts)
+			client := newFakeNotionClient()
+			client.content["page"] = []notion.Block{&notion.ParagraphBlock{RichText: []notion.RichText{{Text: &notion.Text{Content: "old"}}}}}
+
+			err := ClearPage(ctx, client, "page", tt.yes, tt.dryRun)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(client.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", client.calls, tt.wantCalls)
+			}
+			wantBlocks := 1
+			if tt.wantCalls != nil {
+				wantBlocks = 0
+			}
+			if got := len(client.content["page"]); got != wantBlocks {
+				t.Errorf("page has %d blocks, want %d", got, wantBlocks)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
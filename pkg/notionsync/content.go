@@ -0,0 +1,119 @@
+package notionsync
+
+import (
+	"context"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// FilterTitleBlock checks if the first block is a heading of at most maxLevel, removes and returns it. Otherwise returns nil, blocks.
+func FilterTitleBlock(blocks []notion.Block, maxLevel int) (notion.Block, []notion.Block) {
+	if len(blocks) == 0 {
+		return nil, blocks
+	}
+	if level := headingLevel(blocks[0]); level > 0 && level <= maxLevel {
+		return blocks[0], blocks[1:]
+	}
+	return nil, blocks
+}
+
+// processCodeBlock handles both pointer and non-pointer code blocks and returns a non-pointer type
+func processCodeBlock(ctx context.Context, i int, codeBlock notion.CodeBlock) notion.CodeBlock {
+	defaultLang := "plain text"
+	if codeBlock.Language == nil {
+		codeBlock.Language = &defaultLang
+		warnf(ctx, "Fixed code block at index %d: set nil language to '%s'\n", i, defaultLang)
+	} else {
+		// Map language to Notion-compatible value
+		originalLang := *codeBlock.Language
+		mappedLang := mapLanguageToNotionCompatible(originalLang)
+		if mappedLang != originalLang {
+			*codeBlock.Language = mappedLang
+			warnf(ctx, "Fixed code block at index %d: mapped language from '%s' to '%s'\n", i, originalLang, mappedLang)
+		}
+	}
+	debugf(ctx, "⚠️  Code block at index %d has language: %s\n", i, *codeBlock.Language)
+	codeBlock.RichText = splitCodeRichText(codeBlock.RichText)
+	return codeBlock
+}
+
+// mapLanguageToNotionCompatible maps common language identifiers to Notion-compatible values
+func mapLanguageToNotionCompatible(lang string) string {
+	langMap := map[string]string{
+		"sh":   "shell",
+		"bash": "bash",
+		"zsh":  "shell",
+		"js":   "javascript",
+		"ts":   "typescript",
+		"py":   "python",
+		"rb":   "ruby",
+		"cs":   "c#",
+		"cpp":  "c++",
+		"yml":  "yaml",
+		"text": "plain text",
+		"txt":  "plain text",
+	}
+
+	if mapped, ok := langMap[lang]; ok {
+		return mapped
+	}
+	return lang
+}
+
+// ValidateContentBlocks scans and patches Notion blocks for known API problems (e.g., empty bulleted list items)
+func ValidateContentBlocks(ctx context.Context, blocks []notion.Block) []notion.Block {
+	var patched []notion.Block
+	for i, block := range blocks {
+		// Convert pointer to non-pointer type
+		if _, ok := block.(*notion.CodeBlock); ok {
+			block = notion.CodeBlock(*block.(*notion.CodeBlock))
+		}
+
+		switch b := block.(type) {
+		case notion.BulletedListItemBlock:
+			if len(b.RichText) == 0 || (len(b.RichText) == 1 && b.RichText[0].PlainText == "") {
+				warnf(ctx, "Skipping empty bulleted list item at index %d\n", i)
+				continue
+			}
+			if len(b.Children) > 0 {
+				b.Children = ValidateContentBlocks(ctx, b.Children)
+			}
+			// Task list items ("- [ ] task") become to-dos
+			if todo, ok := toDoFromListItem(b); ok {
+				patched = append(patched, todo)
+				continue
+			}
+			patched = append(patched, b)
+		case notion.NumberedListItemBlock:
+			if len(b.RichText) == 0 || (len(b.RichText) == 1 && b.RichText[0].PlainText == "") {
+				warnf(ctx, "Skipping empty numbered list item at index %d\n", i)
+				continue
+			}
+			if len(b.Children) > 0 {
+				b.Children = ValidateContentBlocks(ctx, b.Children)
+			}
+			patched = append(patched, b)
+		case notion.CodeBlock:
+			b = processCodeBlock(ctx, i, b)
+			patched = append(patched, b)
+		case notion.ToggleBlock:
+			if len(b.Children) > 0 {
+				b.Children = ValidateContentBlocks(ctx, b.Children)
+			}
+			patched = append(patched, b)
+		case deepHeadingBlock:
+			// Notion has no level 4 to 6 headings, bold level 3 ones still stand out from real ones
+			heading := b.Heading3Block
+			heading.RichText = emboldenRichText(heading.RichText)
+			debugf(ctx, "[DEBUG] Collapsing level %d heading at index %d into a bold level 3 heading\n", b.Level, i)
+			patched = append(patched, heading)
+		default:
+			// TODO: add more block type checks as needed
+			if children := blockChildren(block); len(children) > 0 {
+				block = withChildren(block, ValidateContentBlocks(ctx, children))
+			}
+			patched = append(patched, block)
+		}
+	}
+	return patched
+}
@@ -0,0 +1,501 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brittonhayes/notionmd"
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find content tab headers: === "Tab name"
+var tabHeaderRegex = regexp.MustCompile(`^===[!+]?\s+"([^"]*)"\s*$`)
+
+// Regular expression to find setext heading underlines: === or --- indented by at most three spaces
+var setextUnderlineRegex = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+// Regular expression to find thematic breaks: three or more -, * or _ optionally separated by spaces
+var thematicBreakRegex = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+// Regular expression to find lines that open a block other than a paragraph
+var nonParagraphLineRegex = regexp.MustCompile(`^ {0,3}(?:#|>|[-*+][ \t]|\d+[.)][ \t]|\||<)`)
+
+// Regular expression to find an ATX heading line: ## Heading
+var headingLineRegex = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]|$)`)
+
+// Regular expression to find an ATX heading deeper than Notion's three levels: #### Heading
+var deepHeadingRegex = regexp.MustCompile(`^ {0,3}(#{4,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+// deepHeadingBlock is a level 4 to 6 heading found during conversion. Notion only has three
+// heading levels, ValidateContentBlocks collapses it into a bold level 3 heading.
+type deepHeadingBlock struct {
+	notion.Heading3Block
+	Level int
+}
+
+// newDeepHeadingBlock converts a heading line matching deepHeadingRegex
+func newDeepHeadingBlock(line string) deepHeadingBlock {
+	match := deepHeadingRegex.FindStringSubmatch(line)
+	return deepHeadingBlock{
+		Heading3Block: notion.Heading3Block{RichText: parseInline(match[2])},
+		Level:         len(match[1]),
+	}
+}
+
+// Regular expression to find a line holding nothing but a markdown image: ![alt](path)
+var standaloneImageRegex = regexp.MustCompile(`^ {0,3}!\[[^\]]*\]\([^)]+\)[ \t]*$`)
+
+// Regular expression to find the opening of a block level inline SVG
+var svgOpenRegex = regexp.MustCompile(`(?i)^ {0,3}<svg[\s>]`)
+
+// inlineSVGBlock is a block level <svg> found during conversion. It marshals as a code block
+// showing the SVG source, which ProcessImageBlocks swaps for an uploaded image when it can.
+type inlineSVGBlock struct {
+	notion.CodeBlock
+	Source string
+}
+
+// newInlineSVGBlock builds the block for an inline SVG, defaulting to its code block fallback
+func newInlineSVGBlock(source string) inlineSVGBlock {
+	language := "xml"
+	return inlineSVGBlock{
+		CodeBlock: notion.CodeBlock{
+			RichText: codeRichText(source),
+			Language: &language,
+		},
+		Source: source,
+	}
+}
+
+// Regular expression to find the title attribute of a code fence info string: title="Example"
+var fenceTitleRegex = regexp.MustCompile(`\btitle=(?:"([^"]*)"|'([^']*)'|(\S+))`)
+
+// fencedBlock builds the block for the fence spanning lines[start:end]. Fences marked
+// "collapse" in their info string (```go collapse title="Full example") put the code
+// block inside a toggle labelled with the title, or with the language if there is none.
+func fencedBlock(ctx context.Context, lines []string, start, end int, marker string) notion.Block {
+	info, attrs := fenceAttributes(fenceInfo(lines[start], marker))
+	code := fencedCodeBlock(lines, start, end, marker)
+	used := []string{"caption", "title", ".collapse", "." + info}
+	warnUnusedAttributes(ctx, "code block", attrs.Classes, attrs.Values, used...)
+	if !slices.Contains(strings.Fields(info), "collapse") && !slices.Contains(attrs.Classes, "collapse") {
+		return code
+	}
+	label := "Code example"
+	if match := fenceTitleRegex.FindStringSubmatch(info); match != nil {
+		label = match[1] + match[2] + match[3]
+	} else if title, ok := attrs.Values["title"]; ok {
+		label = title
+	} else if code.Language != nil && *code.Language != "plain text" {
+		label = strings.ToUpper((*code.Language)[:1]) + (*code.Language)[1:] + " example"
+	}
+	return notion.ToggleBlock{
+		RichText: plainRichText(label),
+		Children: []notion.Block{code},
+	}
+}
+
+// fenceInfo returns the info string following the opening fence marker on line
+func fenceInfo(line, marker string) string {
+	return strings.TrimSpace(strings.TrimLeft(line, " ")[len(marker):])
+}
+
+// fencedCodeBlock builds the code block for the fence spanning lines[start:end] opened by marker.
+// The content is exactly the lines between the fences, whitespace included.
+func fencedCodeBlock(lines []string, start, end int, marker string) *notion.CodeBlock {
+	body := lines[start+1 : end]
+	if n := len(body); n > 0 && isClosingFence(body[n-1], marker) {
+		body = body[:n-1]
+	} else if n > 0 && body[n-1] == "" {
+		// Unclosed fences run to the end of the document, minus its final newline
+		body = body[:n-1]
+	}
+	info, attrs := fenceAttributes(fenceInfo(lines[start], marker))
+	code := &notion.CodeBlock{
+		RichText: codeRichText(strings.Join(body, "\n")),
+		Language: codeLanguage(info),
+	}
+	if info == "" {
+		// A fence without a language is plain text, not a language to warn about
+		plain := "plain text"
+		code.Language = &plain
+	}
+	if caption, ok := attrs.Values["caption"]; ok {
+		code.Caption = inlineRichText(caption)
+	}
+	return code
+}
+
+// normalizeLineEndings turns Windows (CRLF) and old Mac (CR) line endings into LF, so regular
+// expressions and code blocks never see a stray carriage return
+func normalizeLineEndings(content []byte) []byte {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(content, []byte("\r"), []byte("\n"))
+}
+
+// markdownConverter wraps notionmd.Convert for constructs it doesn't understand.
+// Those constructs are converted here, swapped for a placeholder paragraph in the
+// markdown handed to notionmd, and spliced back in once conversion is done.
+// Lines whose emphasis notionmd would flatten are handed over with their inline content swapped
+// for a placeholder word, which is replaced by the rich text parsed here afterwards.
+type markdownConverter struct {
+	placeholders map[string][]notion.Block
+	inline       map[string][]notion.RichText
+}
+
+// convertMarkdown converts a markdown document into Notion blocks
+func convertMarkdown(ctx context.Context, content string) ([]notion.Block, error) {
+	c := &markdownConverter{
+		placeholders: make(map[string][]notion.Block),
+		inline:       make(map[string][]notion.RichText),
+	}
+	blocks, err := c.convert(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	return transformRichText(blocks, c.expandInlinePlaceholders), nil
+}
+
+func (c *markdownConverter) convert(ctx context.Context, content string) ([]notion.Block, error) {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+	listChecked := 0
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		// Fenced code is never treated as markup. Unindented fences are converted here so their
+		// content is kept byte for byte, indented ones may belong to a list and are copied verbatim.
+		if marker := fenceOpening(line); marker != "" {
+			end := fenceEnd(lines, i, marker)
+			if line[0] == ' ' {
+				out = append(out, lines[i:end]...)
+			} else {
+				out = append(out, "", c.placeholder([]notion.Block{fencedBlock(ctx, lines, i, end, marker)}), "")
+			}
+			i = end
+			continue
+		}
+
+		// Pandoc attributes on images become their caption and size
+		if strings.Contains(line, "){") {
+			line = rewriteImageAttributes(ctx, line)
+			lines[i] = line
+		}
+
+		// Pandoc attributes on headings set their color, notionmd would drop them
+		if headingLineRegex.MatchString(line) {
+			blocks, ok, err := c.convertHeadingAttributes(ctx, line)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, "", c.placeholder(blocks), "")
+				i++
+				continue
+			}
+		}
+
+		// notionmd turns headings deeper than level 3 into level 3, losing their level
+		if deepHeadingRegex.MatchString(line) {
+			out = append(out, "", c.placeholder([]notion.Block{newDeepHeadingBlock(line)}), "")
+			i++
+			continue
+		}
+
+		// notionmd keeps <details> as raw HTML text, collapsible sections become toggles
+		if isDetailsStart(line) {
+			if end := detailsEnd(lines, i); end > 0 {
+				toggle, err := c.convertDetails(ctx, lines, i, end)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, "", c.placeholder([]notion.Block{toggle}), "")
+				i = end
+				continue
+			}
+		}
+
+		// notionmd can't nest blocks other than lists under list items, so lists holding a
+		// collapsible section are converted item by item
+		if i >= listChecked && listItemRegex.MatchString(line) {
+			end := listEnd(lines, i)
+			if listHasDetails(lines, i, end) {
+				items, err := c.convertList(ctx, lines, i, end)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, "", c.placeholder(items), "")
+				i = end
+				continue
+			}
+			listChecked = end
+		}
+
+		// notionmd flattens blockquotes into a single run of text
+		if quoteLineRegex.MatchString(line) {
+			end := quoteEnd(lines, i)
+			quote, err := c.convertQuote(ctx, lines, i, end)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, "", c.placeholder([]notion.Block{quote}), "")
+			i = end
+			continue
+		}
+
+		// notionmd drops tables
+		if isTableStart(lines, i) {
+			table, next := convertTable(lines, i)
+			out = append(out, "", c.placeholder([]notion.Block{table}), "")
+			i = next
+			continue
+		}
+
+		// A setext underline after paragraph text makes a heading, not a thematic break.
+		// notionmd's parser misses underlines that are indented, so normalize them.
+		if i > 0 && isSetextUnderline(lines[i-1], line) {
+			out = append(out, strings.TrimSpace(line))
+			i++
+			continue
+		}
+
+		// notionmd drops thematic breaks. Frontmatter is parsed off before conversion, so any
+		// "---" left is a rule.
+		if thematicBreakRegex.MatchString(line) {
+			out = append(out, "", c.placeholder([]notion.Block{&notion.DividerBlock{}}), "")
+			i++
+			continue
+		}
+
+		// notionmd drops images, keep them as paragraph text for ProcessImageBlocks to pick up
+		if standaloneImageRegex.MatchString(line) {
+			paragraph := &notion.ParagraphBlock{RichText: plainRichText(strings.TrimSpace(line))}
+			out = append(out, "", c.placeholder([]notion.Block{paragraph}), "")
+			i++
+			continue
+		}
+
+		if svgOpenRegex.MatchString(line) {
+			if end := svgEnd(lines, i); end > 0 {
+				source := strings.TrimSpace(strings.Join(lines[i:end], "\n"))
+				out = append(out, "", c.placeholder([]notion.Block{newInlineSVGBlock(source)}), "")
+				i = end
+				continue
+			}
+		}
+
+		if admonitionRegex.MatchString(line) {
+			callout, next, err := c.convertAdmonition(ctx, lines, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, "", c.placeholder([]notion.Block{callout}), "")
+			i = next
+			continue
+		}
+
+		if tabHeaderRegex.MatchString(line) {
+			blocks, next, err := c.convertTabGroup(ctx, lines, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, "", c.placeholder(blocks), "")
+			i = next
+			continue
+		}
+
+		// notionmd flattens combined emphasis such as ***both*** or **bold _and italic_**
+		if token, ok := c.inlinePlaceholder(line, i > 0 && strings.TrimSpace(lines[i-1]) == ""); ok {
+			out = append(out, token)
+			i++
+			continue
+		}
+
+		out = append(out, line)
+		i++
+	}
+
+	blocks, err := notionmd.Convert(strings.Join(out, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	return c.expandPlaceholders(blocks), nil
+}
+
+// placeholder stores blocks and returns the token standing in for them in the markdown
+func (c *markdownConverter) placeholder(blocks []notion.Block) string {
+	token := fmt.Sprintf("NOTIONMDPLACEHOLDER%d", len(c.placeholders))
+	c.placeholders[token] = blocks
+	return token
+}
+
+// expandPlaceholders replaces placeholder paragraphs with the blocks they stand for
+func (c *markdownConverter) expandPlaceholders(blocks []notion.Block) []notion.Block {
+	result := make([]notion.Block, 0, len(blocks))
+	for _, block := range blocks {
+		if paragraph, ok := block.(*notion.ParagraphBlock); ok && paragraph != nil {
+			if stored, ok := c.placeholders[richTextPlainText(paragraph.RichText)]; ok {
+				result = append(result, stored...)
+				continue
+			}
+		}
+		result = append(result, block)
+	}
+	return result
+}
+
+// convertTabGroup converts consecutive content tabs starting at lines[start] into a
+// parent toggle holding one toggle per tab. Returns the blocks and the next line index.
+func (c *markdownConverter) convertTabGroup(ctx context.Context, lines []string, start int) ([]notion.Block, int, error) {
+	var (
+		tabs  []notion.Block
+		names []string
+	)
+	i := start
+	for i < len(lines) {
+		match := tabHeaderRegex.FindStringSubmatch(lines[i])
+		if match == nil {
+			break
+		}
+		body, next := indentedBody(lines, i+1)
+		children, err := c.convert(ctx, body)
+		if err != nil {
+			return nil, 0, err
+		}
+		names = append(names, match[1])
+		tabs = append(tabs, notion.ToggleBlock{
+			RichText: plainRichText(match[1]),
+			Children: children,
+		})
+		i = next
+
+		// Blank lines between tabs don't end the group
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) && tabHeaderRegex.MatchString(lines[j]) {
+			i = j
+		}
+	}
+	group := notion.ToggleBlock{
+		RichText: plainRichText(strings.Join(names, " | ")),
+		Children: tabs,
+	}
+	return []notion.Block{group}, i, nil
+}
+
+// indentedBody collects the lines indented by four spaces (or a tab) from lines[start],
+// returning them dedented along with the index of the first line not part of the body
+func indentedBody(lines []string, start int) (string, int) {
+	var body []string
+	end := start
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			body = append(body, "")
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "    "):
+			body = append(body, line[4:])
+		case strings.HasPrefix(line, "\t"):
+			body = append(body, line[1:])
+		default:
+			return strings.Join(body[:end-start], "\n"), end
+		}
+		end = i + 1
+	}
+	return strings.Join(body[:end-start], "\n"), end
+}
+
+// isSetextUnderline reports whether line underlines previous as a setext heading per CommonMark
+func isSetextUnderline(previous, line string) bool {
+	if !setextUnderlineRegex.MatchString(line) {
+		return false
+	}
+	return isParagraphLine(previous)
+}
+
+// isParagraphLine reports whether line is paragraph text rather than blank or the start of another block
+func isParagraphLine(line string) bool {
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	return !nonParagraphLineRegex.MatchString(line) && !thematicBreakRegex.MatchString(line) && fenceOpening(line) == ""
+}
+
+// svgEnd returns the index just past the line closing the <svg> opened at lines[start], or 0 if it is never closed
+func svgEnd(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if strings.Contains(strings.ToLower(lines[i]), "</svg>") {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// fenceOpening returns the fence marker (``` or ~~~, possibly longer) if line opens a fenced code block
+func fenceOpening(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return ""
+	}
+	for _, ch := range []string{"`", "~"} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == ch[0] {
+			n++
+		}
+		if n >= 3 {
+			// Backtick fences can't have backticks in their info string
+			if ch == "`" && strings.Contains(trimmed[n:], "`") {
+				return ""
+			}
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+// fenceEnd returns the index just past the line closing the fence opened at lines[start]
+func fenceEnd(lines []string, start int, marker string) int {
+	for i := start + 1; i < len(lines); i++ {
+		if isClosingFence(lines[i], marker) {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
+
+// isClosingFence reports whether line closes a fence opened by marker
+func isClosingFence(line, marker string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, marker[:1]) == ""
+}
+
+// plainRichText builds an unannotated rich text slice for content
+func plainRichText(content string) []notion.RichText {
+	return []notion.RichText{{
+		Type:      notion.RichTextTypeText,
+		Text:      &notion.Text{Content: content},
+		PlainText: content,
+	}}
+}
+
+// richTextPlainText concatenates the plain text of all rich text runs
+func richTextPlainText(richText []notion.RichText) string {
+	var sb strings.Builder
+	for _, rt := range richText {
+		if rt.Text != nil {
+			sb.WriteString(rt.Text.Content)
+		} else {
+			sb.WriteString(rt.PlainText)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,269 @@
+package notionsync
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// convert converts markdown, failing the test on an error
+func convert(t *testing.T, markdown string) []notion.Block {
+	t.Helper()
+	blocks, err := convertMarkdown(NewContext(context.Background(), testOptions()), markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks
+}
+
+// ownText returns the plain text of a block's own rich text, without its children
+func ownText(block notion.Block) string {
+	return richTextPlainText(blockRichText(block))
+}
+
+func TestConvertTabGroup(t *testing.T) {
+	blocks := convert(t, "Intro.\n\n=== \"Linux\"\n\n    apt install tool\n\n=== \"macOS\"\n\n    - brew install tool\n    - done\n\nAfter.\n")
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.ToggleBlock", "notion.ParagraphBlock"}) {
+		t.Fatalf("blocks = %v, want the tab group between the paragraphs", got)
+	}
+	group := blocks[1]
+	if got := ownText(group); got != "Linux | macOS" {
+		t.Errorf("group label = %q, want the tab names", got)
+	}
+	tabs := blockChildren(group)
+	if len(tabs) != 2 {
+		t.Fatalf("group holds %d tabs, want 2", len(tabs))
+	}
+	if ownText(tabs[0]) != "Linux" || ownText(tabs[1]) != "macOS" {
+		t.Errorf("tabs = %q, %q, want Linux and macOS", ownText(tabs[0]), ownText(tabs[1]))
+	}
+	if got := blockChildren(tabs[0]); len(got) != 1 || ownText(got[0]) != "apt install tool" {
+		t.Errorf("Linux tab holds %v, want its paragraph", blockTypes(got))
+	}
+	if got := blockTypes(blockChildren(tabs[1])); !slices.Equal(got, []string{"notion.BulletedListItemBlock", "notion.BulletedListItemBlock"}) {
+		t.Errorf("macOS tab holds %v, want its list", got)
+	}
+}
+
+func TestConvertSingleTab(t *testing.T) {
+	blocks := convert(t, "=== \"Only\"\n\n    text\n")
+	if len(blocks) != 1 || ownText(blocks[0]) != "Only" || len(blockChildren(blocks[0])) != 1 {
+		t.Errorf("blocks = %v, want one group with one tab", blockTypes(blocks))
+	}
+}
+
+func TestConvertSetextHeadings(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		wantText string
+	}{
+		{"setext h1", "Title\n=====\n\ntext\n", []string{"notion.Heading1Block", "notion.ParagraphBlock"}, "Title"},
+		{"setext h2", "Section\n---\n\ntext\n", []string{"notion.Heading2Block", "notion.ParagraphBlock"}, "Section"},
+		{"rule after a blank line", "text\n\n---\n\nmore\n", []string{"notion.ParagraphBlock", "notion.DividerBlock", "notion.ParagraphBlock"}, "text"},
+		{"spaced rule after text", "text\n- - -\n", []string{"notion.ParagraphBlock", "notion.DividerBlock"}, "text"},
+		{"rule at the start", "---\n\ntext\n", []string{"notion.DividerBlock", "notion.ParagraphBlock"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			if got := blockTypes(blocks); !slices.Equal(got, tt.want) {
+				t.Fatalf("blocks = %v, want %v", got, tt.want)
+			}
+			if got := ownText(blocks[0]); got != tt.wantText {
+				t.Errorf("first block text = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestConvertCollapsibleCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		wantLabel string
+	}{
+		{"normal code", "```go\nfmt.Println(1)\n```\n", ""},
+		{"collapsed with the language as label", "```go collapse\nfmt.Println(1)\n```\n", "Go example"},
+		{"collapsed with a title", "```go collapse title=\"Full example\"\nfmt.Println(1)\n```\n", "Full example"},
+		{"collapsed by class", "```{.go .collapse title=Setup}\nfmt.Println(1)\n```\n", "Setup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			if len(blocks) != 1 {
+				t.Fatalf("got %v, want one block", blockTypes(blocks))
+			}
+			code := blocks[0]
+			if tt.wantLabel != "" {
+				toggle, ok := blocks[0].(notion.ToggleBlock)
+				if !ok {
+					t.Fatalf("block = %v, want a toggle", blockTypes(blocks))
+				}
+				if got := ownText(toggle); got != tt.wantLabel {
+					t.Errorf("label = %q, want %q", got, tt.wantLabel)
+				}
+				if len(toggle.Children) != 1 {
+					t.Fatalf("toggle holds %v, want the code block", blockTypes(toggle.Children))
+				}
+				code = toggle.Children[0]
+			}
+			codeBlock, ok := code.(*notion.CodeBlock)
+			if !ok {
+				t.Fatalf("block = %T, want a code block", code)
+			}
+			if ownText(codeBlock) != "fmt.Println(1)" || *codeBlock.Language != "go" {
+				t.Errorf("code = %q in %q, want the Go snippet", ownText(codeBlock), *codeBlock.Language)
+			}
+		})
+	}
+}
+
+func TestConvertTildeFences(t *testing.T) {
+	tests := []struct {
+		name         string
+		info         string
+		code         string
+		wantLanguage string
+	}{
+		{name: "with a language", info: "go", code: "fmt.Println(\"`quoted`\")", wantLanguage: "go"},
+		{name: "with an alias", info: "yml", code: "key: value", wantLanguage: "yaml"},
+		{name: "with attributes", info: " python title=\"a.py\"", code: "print(1)", wantLanguage: "python"},
+		{name: "without a language", code: "```\nnot a fence\n```", wantLanguage: "plain text"},
+		{name: "with an unknown language", info: "nosuchlang", code: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tilde := convert(t, "~~~"+tt.info+"\n"+tt.code+"\n~~~\n")
+			if len(tilde) != 1 {
+				t.Fatalf("blocks = %v, want one code block", blockTypes(tilde))
+			}
+			code, ok := tilde[0].(*notion.CodeBlock)
+			if !ok {
+				t.Fatalf("block = %T, want a code block", tilde[0])
+			}
+			if got := ownText(code); got != tt.code {
+				t.Errorf("code = %q, want %q", got, tt.code)
+			}
+			language := ""
+			if code.Language != nil {
+				language = *code.Language
+			}
+			if language != tt.wantLanguage {
+				t.Errorf("language = %q, want %q", language, tt.wantLanguage)
+			}
+
+			// The same fence with backticks gives the same language, unless the code holds backticks
+			if !strings.Contains(tt.code, "```") {
+				backtick := convert(t, "````"+tt.info+"\n"+tt.code+"\n````\n")
+				if len(backtick) != 1 || !reflect.DeepEqual(backtick[0].(*notion.CodeBlock).Language, code.Language) {
+					t.Errorf("backtick fence = %#v, want the tilde fence's language", backtick)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertLongerTildeFence(t *testing.T) {
+	blocks := convert(t, "~~~~markdown\n~~~\ninner\n~~~\n~~~~\n\nAfter.\n")
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.CodeBlock", "notion.ParagraphBlock"}) {
+		t.Fatalf("blocks = %v, want the code block and the paragraph", got)
+	}
+	if got := ownText(blocks[0]); got != "~~~\ninner\n~~~" {
+		t.Errorf("code = %q, want the shorter fences kept inside", got)
+	}
+}
+
+func TestSyncFileTildeFenceLanguage(t *testing.T) {
+	client := newFakeNotionClient()
+	markdown := "# Title\n\n~~~\nno language\n~~~\n\n~~~sh\necho hi\n~~~\n"
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	var languages []string
+	for _, block := range client.content["page"] {
+		if code, ok := block.(*notion.CodeBlock); ok && code.Language != nil {
+			languages = append(languages, *code.Language)
+		}
+	}
+	if want := []string{"plain text", "shell"}; !slices.Equal(languages, want) {
+		t.Errorf("languages = %v, want %v", languages, want)
+	}
+}
+
+func TestSyncFileDeepHeadings(t *testing.T) {
+	client := newFakeNotionClient()
+	markdown := "# Title\n\n### Three\n\n#### Four\n\n##### Five *and more*\n\n###### Six ######\n\nText.\n"
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	want := []string{"notion.Heading3Block", "notion.Heading3Block", "notion.Heading3Block", "notion.Heading3Block", "notion.ParagraphBlock"}
+	if got := blockTypes(content); !slices.Equal(got, want) {
+		t.Fatalf("blocks = %v, want %v", got, want)
+	}
+	wantRuns := [][]string{{"Three"}, {"Four[b]"}, {"Five [b]", "and more[bi]"}, {"Six[b]"}}
+	for i, want := range wantRuns {
+		if got := annotatedRuns(blockRichText(content[i])); !slices.Equal(got, want) {
+			t.Errorf("heading %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestSyncFileDividers(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "between paragraphs",
+			markdown: "# Title\n\nFirst.\n\n---\n\nSecond.\n\n***\n\nThird.\n\n___\n\nFourth.\n",
+			want:     []string{"First.", "<divider>", "Second.", "<divider>", "Third.", "<divider>", "Fourth."},
+		},
+		{
+			name:     "at the end of the file",
+			markdown: "# Title\n\nLast paragraph.\n\n---\n",
+			want:     []string{"Last paragraph.", "<divider>"},
+		},
+		{
+			name:     "at the end without a final newline",
+			markdown: "# Title\n\nLast paragraph.\n\n* * *",
+			want:     []string{"Last paragraph.", "<divider>"},
+		},
+		{
+			name:     "after frontmatter",
+			markdown: "---\ntags: docs\n---\n# Title\n\nFirst.\n\n---\n\nSecond.\n",
+			want:     []string{"First.", "<divider>", "Second."},
+		},
+		{
+			name:     "inside code",
+			markdown: "# Title\n\n```\n---\n```\n",
+			want:     []string{"---"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, tt.markdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, block := range client.content["page"] {
+				if _, ok := block.(*notion.DividerBlock); ok {
+					got = append(got, "<divider>")
+				} else {
+					got = append(got, ownText(block))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("page = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package notionsync
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dataURIImageTypes maps the image MIME types Notion accepts as uploads to the file
+// extension the decoded data is written with, so the upload gets the right content type
+var dataURIImageTypes = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/bmp":     ".bmp",
+	"image/tiff":    ".tiff",
+	"image/heic":    ".heic",
+	"image/x-icon":  ".ico",
+}
+
+// errUnsupportedDataURI is returned by writeDataURIImage for data URIs of a type Notion can't show
+var errUnsupportedDataURI = errors.New("unsupported data URI type")
+
+// isDataURI reports whether an image path is an inline data URI
+func isDataURI(path string) bool {
+	return strings.HasPrefix(path, "data:")
+}
+
+// writeDataURIImage decodes an inline "data:<mime>[;base64],<data>" image into a temporary
+// file and returns its path. The caller removes the file once it has been uploaded.
+func writeDataURIImage(uri string) (string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return "", fmt.Errorf("malformed data URI: missing ','")
+	}
+	params := strings.Split(header, ";")
+	mimeType := strings.ToLower(strings.TrimSpace(params[0]))
+	ext, ok := dataURIImageTypes[mimeType]
+	if !ok {
+		return "", fmt.Errorf("%w '%s'", errUnsupportedDataURI, mimeType)
+	}
+
+	var content []byte
+	var err error
+	if params[len(params)-1] == "base64" {
+		// Generated markdown sometimes wraps long data URIs or leaves out the padding
+		data = strings.Join(strings.Fields(data), "")
+		content, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			content, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		}
+	} else {
+		var text string
+		text, err = url.PathUnescape(data)
+		content = []byte(text)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s data URI: %w", mimeType, err)
+	}
+
+	file, err := os.CreateTemp("", "notionmd-data-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,72 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// contentUploadClient is a fakeNotionClient keeping the content of every uploaded file
+type contentUploadClient struct {
+	*fakeNotionClient
+	contents map[string][]byte
+}
+
+func (c *contentUploadClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	c.contents[filePath] = content
+	return c.fakeNotionClient.UploadFile(ctx, filePath)
+}
+
+// onePixelPNG is a 1x1 transparent PNG
+var onePixelPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae,
+	0x42, 0x60, 0x82,
+}
+
+func TestProcessImageBlocksDataURI(t *testing.T) {
+	client := &contentUploadClient{newFakeNotionClient(), make(map[string][]byte)}
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(onePixelPNG)
+	blocks := processImages(t, client, writeMarkdown(t, ""), "![Pixel]("+uri+")\n", ImageOptions{})
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notionsync.ImageBlock"}) {
+		t.Fatalf("blocks = %v, want an uploaded image", got)
+	}
+	if len(client.uploads) != 1 {
+		t.Fatalf("uploads = %v, want one", client.uploads)
+	}
+	uploaded := client.uploads[0]
+	if filepath.Ext(uploaded) != ".png" {
+		t.Errorf("uploaded %s, want a .png file", uploaded)
+	}
+	if !bytes.Equal(client.contents[uploaded], onePixelPNG) {
+		t.Errorf("uploaded %d bytes, want the decoded PNG", len(client.contents[uploaded]))
+	}
+	if _, err := os.Stat(uploaded); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s is left behind", uploaded)
+	}
+}
+
+func TestProcessImageBlocksUnsupportedDataURI(t *testing.T) {
+	client := newFakeNotionClient()
+	ctx := NewContext(context.Background(), testOptions())
+	blocks, err := ProcessImageBlocks(ctx, convert(t, "![Report](data:application/pdf;base64,JVBERi0=)\n"), writeMarkdown(t, ""), client, ImageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || ownText(blocks[0]) != "Report" {
+		t.Errorf("blocks = %v, want the alt text kept", blockTypes(blocks))
+	}
+	if len(client.uploads) != 0 || warningCount(ctx) != 1 {
+		t.Errorf("%d uploads and %d warnings, want a warning instead of an upload", len(client.uploads), warningCount(ctx))
+	}
+}
@@ -0,0 +1,48 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// warnf prints a warning and counts it, so validation can fail on warnings
+func warnf(ctx context.Context, format string, args ...interface{}) {
+	s := sessionFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.warnings++
+	s.report.warning(strings.TrimSpace(fmt.Sprintf(format, args...)))
+	fmt.Fprintf(s.out, "⚠️  "+format, args...)
+}
+
+// debugf prints debug messages if SyncOptions.Debug is set
+func debugf(ctx context.Context, format string, args ...interface{}) {
+	if s := sessionFrom(ctx); s.debug {
+		fmt.Fprintf(s.out, format, args...)
+	}
+}
+
+func debugBlocks(ctx context.Context, blocks []notion.Block) {
+	// early dropout
+	if !sessionFrom(ctx).debug {
+		return
+	}
+
+	debugf(ctx, "Found %d blocks after markdown conversion\n", len(blocks))
+	for i, block := range blocks {
+		debugf(ctx, "Block %d is of type: %T\n", i, block)
+
+		// Check specifically for code blocks
+		if codeBlock, ok := block.(*notion.CodeBlock); ok {
+			debugf(ctx, "Found code block at index %d\n", i)
+			if codeBlock.Language == nil {
+				debugf(ctx, "  Language is nil\n")
+			} else {
+				debugf(ctx, "  Language is '%s'\n", *codeBlock.Language)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package notionsync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find the opening of a collapsible section: <details> or <details open>
+var detailsOpenRegex = regexp.MustCompile(`(?i)<details(?:\s[^>]*)?>`)
+
+// Regular expression to find the closing of a collapsible section
+var detailsCloseRegex = regexp.MustCompile(`(?i)</details\s*>`)
+
+// Regular expression to find the label of a collapsible section: <summary>Label</summary>
+var summaryRegex = regexp.MustCompile(`(?is)<summary(?:\s[^>]*)?>(.*?)</summary\s*>`)
+
+// Regular expression to find a list item marker: "- ", "* ", "+ ", "1. " or "1) "
+var listItemRegex = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+
+// isDetailsStart reports whether line opens a block level collapsible section
+func isDetailsStart(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	loc := detailsOpenRegex.FindStringIndex(trimmed)
+	return len(line)-len(trimmed) <= 3 && loc != nil && loc[0] == 0
+}
+
+// detailsEnd returns the index of the line after the </details> closing the section opened at
+// lines[start], counting nested sections, or 0 if it is never closed
+func detailsEnd(lines []string, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		depth += len(detailsOpenRegex.FindAllStringIndex(lines[i], -1))
+		depth -= len(detailsCloseRegex.FindAllStringIndex(lines[i], -1))
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// convertDetails converts the collapsible section spanning lines[start:end] into a toggle
+// labelled with its summary, holding the section's content
+func (c *markdownConverter) convertDetails(ctx context.Context, lines []string, start, end int) (notion.Block, error) {
+	source := strings.Join(lines[start:end], "\n")
+	open := detailsOpenRegex.FindStringIndex(source)
+	closes := detailsCloseRegex.FindAllStringIndex(source, -1)
+	inner := source[open[1]:closes[len(closes)-1][0]]
+
+	label := "Details"
+	if match := summaryRegex.FindStringSubmatchIndex(inner); match != nil {
+		if summary := strings.TrimSpace(inner[match[2]:match[3]]); summary != "" {
+			label = summary
+		}
+		inner = inner[:match[0]] + inner[match[1]:]
+	}
+
+	children, err := c.convert(ctx, dedent(strings.Trim(inner, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	return notion.ToggleBlock{
+		RichText: inlineRichText(label),
+		Children: children,
+	}, nil
+}
+
+// listEnd returns the index of the line after the list starting at lines[start]: its items and
+// everything indented under them, including blank lines between items
+func listEnd(lines []string, start int) int {
+	end := start + 1
+	blank := false
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			blank = true
+			continue
+		case listItemRegex.MatchString(line) || strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t"):
+		case !blank && !nonParagraphLineRegex.MatchString(line) && fenceOpening(line) == "" && !thematicBreakRegex.MatchString(line):
+			// Lazy continuation of the item's paragraph
+		default:
+			return end
+		}
+		blank = false
+		end = i + 1
+	}
+	return end
+}
+
+// listHasDetails reports whether any item of the list spanning lines[start:end] holds a
+// collapsible section, which notionmd can't nest
+func listHasDetails(lines []string, start, end int) bool {
+	for _, line := range lines[start+1 : end] {
+		if isDetailsStart(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertList converts the list spanning lines[start:end] item by item, so content indented
+// under an item, such as a collapsible section, becomes the item's children
+func (c *markdownConverter) convertList(ctx context.Context, lines []string, start, end int) ([]notion.Block, error) {
+	var blocks []notion.Block
+	for i := start; i < end; {
+		match := listItemRegex.FindStringSubmatch(lines[i])
+		contentIndent := len(match[0])
+		if len(match[3]) > 4 {
+			// Indented code right after the marker, the content starts one space in
+			contentIndent = len(match[1]) + len(match[2]) + 1
+		}
+
+		body := []string{lines[i][len(match[0]):]}
+		next := i + 1
+		for ; next < end; next++ {
+			line := lines[next]
+			if listItemRegex.MatchString(line) && len(line)-len(strings.TrimLeft(line, " ")) < contentIndent {
+				break
+			}
+			line = trimIndent(line, contentIndent)
+			if listItemRegex.MatchString(line) && strings.TrimSpace(body[len(body)-1]) != "" {
+				// A nested list right under the item's text would be read as more of that text
+				body = append(body, "")
+			}
+			body = append(body, line)
+		}
+
+		item, err := c.convertListItem(ctx, match[2], strings.Join(body, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, item)
+		i = next
+	}
+	return blocks, nil
+}
+
+// convertListItem builds a list item from its marker and dedented content. The first paragraph
+// is the item's text, everything after it the item's children.
+func (c *markdownConverter) convertListItem(ctx context.Context, marker, body string) (notion.Block, error) {
+	content, err := c.convert(ctx, strings.TrimRight(body, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	richText := []notion.RichText{}
+	if len(content) > 0 {
+		if paragraph, ok := content[0].(*notion.ParagraphBlock); ok && len(paragraph.Children) == 0 {
+			richText = paragraph.RichText
+			content = content[1:]
+		}
+	}
+	if len(content) == 0 {
+		content = nil
+	}
+	if marker[0] >= '0' && marker[0] <= '9' {
+		return notion.NumberedListItemBlock{RichText: richText, Children: content}, nil
+	}
+	return notion.BulletedListItemBlock{RichText: richText, Children: content}, nil
+}
+
+// trimIndent removes up to n leading spaces from line
+func trimIndent(line string, n int) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if removed := len(line) - len(trimmed); removed < n {
+		return trimmed
+	}
+	return line[n:]
+}
+
+// dedent removes the indentation shared by all non-blank lines of text
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indent := len(line) - len(strings.TrimLeft(line, " ")); common < 0 || indent < common {
+			common = indent
+		}
+	}
+	if common <= 0 {
+		return text
+	}
+	for i, line := range lines {
+		lines[i] = trimIndent(line, common)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,81 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestConvertDetailsInListItem(t *testing.T) {
+	const markdown = "- First item\n" +
+		"  <details>\n" +
+		"  <summary>More about it</summary>\n" +
+		"\n" +
+		"  Hidden paragraph.\n" +
+		"\n" +
+		"  - hidden point\n" +
+		"  </details>\n" +
+		"- Second item\n" +
+		"\n" +
+		"After the list.\n"
+
+	blocks := convert(t, markdown)
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.BulletedListItemBlock", "notion.BulletedListItemBlock", "notion.ParagraphBlock"}) {
+		t.Fatalf("blocks = %v, want both items and the paragraph after the list", got)
+	}
+	if ownText(blocks[0]) != "First item" || ownText(blocks[1]) != "Second item" {
+		t.Errorf("items = %q, %q", ownText(blocks[0]), ownText(blocks[1]))
+	}
+	if children := blockChildren(blocks[1]); len(children) != 0 {
+		t.Errorf("second item holds %v, want nothing", blockTypes(children))
+	}
+
+	children := blockChildren(blocks[0])
+	if got := blockTypes(children); !slices.Equal(got, []string{"notion.ToggleBlock"}) {
+		t.Fatalf("first item holds %v, want the toggle", got)
+	}
+	toggle := children[0]
+	if got := ownText(toggle); got != "More about it" {
+		t.Errorf("toggle label = %q, want the summary", got)
+	}
+	hidden := blockChildren(toggle)
+	if got := blockTypes(hidden); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.BulletedListItemBlock"}) {
+		t.Fatalf("toggle holds %v, want the paragraph and the list", got)
+	}
+	if ownText(hidden[0]) != "Hidden paragraph." || ownText(hidden[1]) != "hidden point" {
+		t.Errorf("toggle content = %q, %q", ownText(hidden[0]), ownText(hidden[1]))
+	}
+}
+
+func TestConvertDetailsInNumberedList(t *testing.T) {
+	blocks := convert(t, "1. Step one\n   <details><summary>Why</summary>\n   Because.\n   </details>\n2. Step two\n")
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.NumberedListItemBlock", "notion.NumberedListItemBlock"}) {
+		t.Fatalf("blocks = %v, want the two steps", got)
+	}
+	children := blockChildren(blocks[0])
+	if len(children) != 1 || ownText(children[0]) != "Why" {
+		t.Fatalf("first step holds %v, want the toggle", blockTypes(children))
+	}
+	if hidden := blockChildren(children[0]); len(hidden) != 1 || ownText(hidden[0]) != "Because." {
+		t.Errorf("toggle holds %v, want its paragraph", blockTypes(hidden))
+	}
+}
+
+func TestSyncFileDetailsInList(t *testing.T) {
+	client := newFakeNotionClient()
+	markdown := "# Title\n\n- Item\n  <details>\n  <summary>Open me</summary>\n\n  Inside.\n  </details>\n"
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if len(content) != 1 {
+		t.Fatalf("content = %v, want the list item", blockTypes(content))
+	}
+	toggles := blockChildren(content[0])
+	if len(toggles) != 1 || ownText(toggles[0]) != "Open me" {
+		t.Fatalf("item holds %v, want the toggle", blockTypes(toggles))
+	}
+	if hidden := blockChildren(toggles[0]); len(hidden) != 1 || ownText(hidden[0]) != "Inside." {
+		t.Errorf("toggle holds %v, want its paragraph", blockTypes(hidden))
+	}
+}
@@ -0,0 +1,116 @@
+package notionsync
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is one line of a diff: Kind is ' ' for unchanged, '-' for removed and '+' for added
+type diffOp struct {
+	Kind byte
+	Text string
+}
+
+// diffStrings computes a minimal line diff turning old into new using the longest common subsequence
+func diffStrings(old, new []string) []diffOp {
+	// lcs[i][j] is the LCS length of old[i:] and new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(old) && j < len(new) {
+		switch {
+		case old[i] == new[j]:
+			ops = append(ops, diffOp{Kind: ' ', Text: old[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{Kind: '-', Text: old[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{Kind: '+', Text: new[j]})
+			j++
+		}
+	}
+	for ; i < len(old); i++ {
+		ops = append(ops, diffOp{Kind: '-', Text: old[i]})
+	}
+	for ; j < len(new); j++ {
+		ops = append(ops, diffOp{Kind: '+', Text: new[j]})
+	}
+	return ops
+}
+
+// unifiedDiff renders the diff of old and new lines in unified format with context lines
+// around each change, labelling the sides oldName and newName. It returns "" if nothing changed.
+func unifiedDiff(oldName, newName string, old, new []string, context int) string {
+	ops := diffStrings(old, new)
+
+	// Group the changes into hunks, merging those whose context overlaps
+	var hunks [][2]int
+	for k, op := range ops {
+		if op.Kind == ' ' {
+			continue
+		}
+		start, end := max(k-context, 0), min(k+context+1, len(ops))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+			continue
+		}
+		hunks = append(hunks, [2]int{start, end})
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	oldLine, newLine, k := 0, 0, 0
+	for _, hunk := range hunks {
+		for ; k < hunk[0]; k++ {
+			oldLine, newLine = oldLine+diffLines(ops[k], '+'), newLine+diffLines(ops[k], '-')
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunk[0]:hunk[1]] {
+			oldCount, newCount = oldCount+diffLines(op, '+'), newCount+diffLines(op, '-')
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for ; k < hunk[1]; k++ {
+			sb.WriteString(string(ops[k].Kind) + ops[k].Text + "\n")
+			oldLine, newLine = oldLine+diffLines(ops[k], '+'), newLine+diffLines(ops[k], '-')
+		}
+	}
+	return sb.String()
+}
+
+// diffLines returns 1 if op is a line of the side that other doesn't belong to, else 0
+func diffLines(op diffOp, other byte) int {
+	if op.Kind == other {
+		return 0
+	}
+	return 1
+}
+
+// hunkRange formats the line range of one side of a hunk starting after line before. An
+// empty range names the line before it, as diff does.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -0,0 +1,72 @@
+package notionsync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		context int
+		want    string
+	}{
+		{
+			name: "unchanged",
+			old:  "a\nb",
+			new:  "a\nb",
+			want: "",
+		},
+		{
+			name:    "added line",
+			old:     "a\nb\nc",
+			new:     "a\nb\nnew\nc",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -2,2 +2,3 @@\n b\n+new\n c\n",
+		},
+		{
+			name:    "removed line",
+			old:     "a\nb\nc\nd",
+			new:     "a\nc\nd",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name:    "changed line",
+			old:     "a\nb\nc",
+			new:     "a\nB\nc",
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -2 +2 @@\n-b\n+B\n",
+		},
+		{
+			name:    "added to an empty page",
+			old:     "",
+			new:     "a\nb",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:    "separate hunks",
+			old:     "1\n2\n3\n4\n5\n6\n7\n8",
+			new:     "1\nx\n3\n4\n5\n6\ny\n8",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,3 +1,3 @@\n 1\n-2\n+x\n 3\n@@ -6,3 +6,3 @@\n 6\n-7\n+y\n 8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old, new []string
+			if tt.old != "" {
+				old = strings.Split(tt.old, "\n")
+			}
+			if tt.new != "" {
+				new = strings.Split(tt.new, "\n")
+			}
+			if got := unifiedDiff("old", "new", old, new, tt.context); got != tt.want {
+				t.Errorf("unifiedDiff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package notionsync
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the file in the --md-dir root listing paths to leave out
+const ignoreFileName = ".notionmdignore"
+
+// ignoreRules are the patterns read from a .notionmdignore file. They follow a subset of
+// .gitignore: "#" comments, "*" and "?" wildcards, a trailing "/" to match only directories
+// and a "/" inside the pattern to match the path from the root instead of any file name.
+type ignoreRules []string
+
+// loadIgnoreRules reads the ignore file in dir, a missing file means nothing is ignored
+func loadIgnoreRules(dir string) (ignoreRules, error) {
+	file, err := os.Open(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var rules ignoreRules
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	return rules, scanner.Err()
+}
+
+// Match reports whether the slash separated path rel, relative to the root, is ignored
+func (r ignoreRules) Match(rel string, isDir bool) bool {
+	for _, pattern := range r {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			pattern, target = strings.TrimPrefix(pattern, "/"), rel
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPageMap reads a JSON object mapping markdown paths relative to the directory to page IDs
+func loadPageMap(ctx context.Context, mapPath string) (map[string]string, error) {
+	data, err := readInputFile(ctx, mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading page map file: %w", err)
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Error decoding page map file: %w", err)
+	}
+	pages := make(map[string]string, len(raw))
+	for file, pageID := range raw {
+		pages[path.Clean(filepath.ToSlash(file))] = pageID
+	}
+	return pages, nil
+}
+
+// findMarkdownFiles walks dir for .md files not excluded by its ignore file, returning
+// their slash separated paths relative to dir in walk order
+func findMarkdownFiles(ctx context.Context, dir string) ([]string, error) {
+	rules, err := loadIgnoreRules(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %w", ignoreFileName, err)
+	}
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rules.Match(rel, entry.IsDir()) {
+			debugf(ctx, "[DEBUG] Ignoring %s\n", rel)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(p), ".md") {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// fileResult is the outcome of syncing one file of a directory
+type fileResult struct {
+	File   string
+	PageID string
+	Status string
+	Err    error
+}
+
+// syncStatus describes the outcome of syncing a file: synced, unchanged or failed
+func syncStatus(err error) string {
+	switch {
+	case err == nil:
+		return "synced"
+	case errors.Is(err, ErrContentUnchanged):
+		return "unchanged"
+	}
+	return "failed"
+}
+
+// SyncDirectory syncs every markdown file under dir to the page the page map assigns it,
+// falling back to the page named in the file's frontmatter, prints a per-file summary and returns the exit code: 1 if any file failed
+func SyncDirectory(ctx context.Context, opts SyncOptions, notionClient NotionClientInterface, dir, pageMapPath string) int {
+	ctx = NewContext(ctx, opts)
+	pages := map[string]string{}
+	if pageMapPath != "" {
+		var err error
+		if pages, err = loadPageMap(ctx, pageMapPath); err != nil {
+			fmt.Fprintln(output(ctx), err)
+			return 1
+		}
+	}
+	files, err := findMarkdownFiles(ctx, dir)
+	if err != nil {
+		fmt.Fprintln(output(ctx), "Error reading markdown directory:", err)
+		return 1
+	}
+
+	var results []fileResult
+	for _, file := range files {
+		// A cancelled run stops before the next file, the summary lists the files tried
+		if ctx.Err() != nil {
+			break
+		}
+		mdPath := filepath.Join(dir, filepath.FromSlash(file))
+		result := fileResult{File: file, PageID: pages[file]}
+		if result.PageID == "" {
+			// Files not in the page map can name their page in the frontmatter
+			if result.PageID, err = frontmatterPageID(ctx, mdPath); err != nil {
+				result.Status, result.Err = "failed", err
+				results = append(results, result)
+				continue
+			}
+		}
+		if result.PageID == "" && !opts.Offline() {
+			result.Status = "skipped, no page mapped"
+			results = append(results, result)
+			continue
+		}
+		err := SyncFile(ctx, opts, notionClient, mdPath, result.PageID)
+		if result.Status = syncStatus(err); result.Status == "failed" {
+			result.Err = err
+		}
+		results = append(results, result)
+	}
+	return printDirectorySummary(ctx, results, "files")
+}
+
+// printDirectorySummary prints one line per file, or document, and returns 1 if any failed
+func printDirectorySummary(ctx context.Context, results []fileResult, what string) int {
+	var succeeded, failed, skipped int
+	fmt.Fprintf(output(ctx), "\n===== Synced %d markdown %s =====\n", len(results), what)
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(output(ctx), "❌ %s: %s\n", result.File, result.Err)
+		case result.PageID != "":
+			fmt.Fprintf(output(ctx), "✅ %s → %s: %s\n", result.File, result.PageID, result.Status)
+		default:
+			fmt.Fprintf(output(ctx), "⏭️  %s: %s\n", result.File, result.Status)
+		}
+		if result.Err == nil && strings.HasPrefix(result.Status, "skipped") {
+			skipped++
+		} else if result.Err == nil {
+			succeeded++
+		}
+	}
+	fmt.Fprintf(output(ctx), "%d succeeded, %d failed, %d skipped\n", succeeded, failed, skipped)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files, keyed by slash separated paths, under a new temporary directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindMarkdownFilesIgnoreRules(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".notionmdignore":      "# drafts aren't published\ndrafts/\n*.tmp.md\n/guide/internal.md\n",
+		"a.md":                 "# A\n",
+		"notes.txt":            "not markdown",
+		"drafts/wip.md":        "# WIP\n",
+		"guide/b.md":           "# B\n",
+		"guide/internal.md":    "# Internal\n",
+		"guide/scratch.tmp.md": "# Scratch\n",
+		"other/internal.md":    "# Other internal\n",
+	})
+	files, err := findMarkdownFiles(NewContext(context.Background(), testOptions()), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "guide/b.md", "other/internal.md"}; !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
+
+func TestSyncDirectory(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".notionmdignore": "drafts/\n",
+		"a.md":            "# A\n\nalpha\n",
+		"guide/b.md":      "# B\n\nbeta\n",
+		"guide/c.md":      "---\nnotion_page: page-c\n---\n# C\n\ngamma\n",
+		"unmapped.md":     "# Unmapped\n",
+		"drafts/wip.md":   "# WIP\n",
+	})
+	pageMap := filepath.Join(t.TempDir(), "pages.json")
+	if err := os.WriteFile(pageMap, []byte(`{"a.md": "page-a", "./guide/b.md": "page-b"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.StatusOutput = &out
+	client := newFakeNotionClient()
+	if code := SyncDirectory(context.Background(), opts, client, dir, pageMap); code != 0 {
+		t.Errorf("exit code = %d, want 0\n%s", code, out.String())
+	}
+	for pageID, title := range map[string]string{"page-a": "A", "page-b": "B", "page-c": "C"} {
+		if got := client.titles[pageID]; got != title {
+			t.Errorf("%s title = %q, want %q", pageID, got, title)
+		}
+	}
+	if len(client.titles) != 3 {
+		t.Errorf("synced %d pages, want 3: %v", len(client.titles), client.titles)
+	}
+	summary := out.String()
+	for _, want := range []string{"✅ a.md → page-a: synced", "✅ guide/c.md → page-c: synced", "⏭️  unmapped.md: skipped, no page mapped", "3 succeeded, 0 failed, 1 skipped"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+
+	out.Reset()
+	failing := &failingAddClient{newFakeNotionClient()}
+	if code := SyncDirectory(context.Background(), opts, failing, dir, pageMap); code != 1 {
+		t.Errorf("exit code = %d with failing files, want 1", code)
+	}
+	if !strings.Contains(out.String(), "0 succeeded, 3 failed, 1 skipped") {
+		t.Errorf("summary doesn't count the failures:\n%s", out.String())
+	}
+}
@@ -0,0 +1,33 @@
+// Package notionsync converts markdown documents into Notion blocks and syncs
+// them to Notion pages. It is the pipeline behind the notionmd-cli command and
+// can be used from any Go program.
+//
+// SyncFile runs the whole pipeline for one document against any
+// NotionClientInterface, so a program can pass NewNotionClient for a real
+// workspace or its own implementation to capture the blocks that would be sent.
+// Every call takes a context, cancelling it aborts the requests in flight:
+//
+//	type captureClient struct {
+//		notionsync.OfflineNotionClient
+//		blocks []notion.Block
+//	}
+//
+//	func (c *captureClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+//		c.blocks = append(c.blocks, blocks...)
+//		return make([]string, len(blocks)), nil
+//	}
+//
+//	client := &captureClient{}
+//	if err := notionsync.SyncFile(ctx, notionsync.SyncOptions{}, client, "README.md", pageID); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The status messages, debug output, warning count and run report belong to
+// each sync, set through SyncOptions.StatusOutput, Debug and Report, so syncs
+// can run concurrently in one program.
+//
+// The individual steps are exported as well: FindImageReferences,
+// ProcessImageBlocks, FilterTitleBlock, ValidateContentBlocks,
+// RewriteTextMap and RewriteLinkMap. A context from NewContext makes them
+// print and report like a sync with the same options.
+package notionsync
@@ -0,0 +1,97 @@
+package notionsync
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// HeadingEmojiModes are the accepted --heading-emoji values: keep a heading's leading emoji in
+// its text (inline), drop it (strip) or drop it and use it as the icon of the child page the
+// heading becomes with --split-by-heading (icon)
+var HeadingEmojiModes = []string{"inline", "strip", "icon"}
+
+// isEmojiRune reports whether r is a pictographic emoji or dingbat
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, flags, skin tones
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // technical symbols such as ⌛ and ⏰
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats such as ☀ and ✅
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and shapes such as ⭐
+		return true
+	}
+	return false
+}
+
+// isEmojiModifier reports whether r continues an emoji: variation selectors, the zero width
+// joiner combining emoji and the keycap mark
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x200D || r == 0x20E3
+}
+
+// leadingEmoji splits an emoji followed by whitespace off the start of text, returning the
+// emoji and the text after it. Text that doesn't start with one, or is only an emoji, returns
+// an empty emoji and text unchanged.
+func leadingEmoji(text string) (emoji, rest string) {
+	first, size := utf8.DecodeRuneInString(text)
+	if !isEmojiRune(first) {
+		return "", text
+	}
+	end := size
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isEmojiModifier(r) && !isEmojiRune(r) {
+			break
+		}
+		end += size
+	}
+	rest = strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+	if rest == text[end:] || rest == "" {
+		return "", text
+	}
+	return text[:end], rest
+}
+
+// stripHeadingEmoji removes the leading emoji from every heading in blocks and their children
+func stripHeadingEmoji(blocks []notion.Block) []notion.Block {
+	for i, block := range blocks {
+		if richText := blockRichText(block); headingLevel(block) > 0 && len(richText) > 0 && isPlainTextRun(richText[0]) {
+			if _, rest := leadingEmoji(richText[0].Text.Content); rest != richText[0].Text.Content {
+				richText = append([]notion.RichText{textRun(rest, richText[0].Annotations)}, richText[1:]...)
+				block = withRichText(block, richText)
+			}
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, stripHeadingEmoji(children))
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// splitSections splits the sections off blocks for --split-by-heading, then applies the
+// --heading-emoji mode to the headings left and to the section titles
+func splitSections(blocks []notion.Block, opts SyncOptions) ([]notion.Block, []pageSection) {
+	var sections []pageSection
+	if opts.SplitLevel > 0 {
+		blocks, sections = splitByHeading(blocks, opts.SplitLevel)
+	}
+	if opts.HeadingEmoji == "" || opts.HeadingEmoji == "inline" {
+		return blocks, sections
+	}
+	blocks = stripHeadingEmoji(blocks)
+	for i := range sections {
+		emoji, title := leadingEmoji(sections[i].Title)
+		sections[i].Title = title
+		if opts.HeadingEmoji == "icon" {
+			sections[i].Icon = emoji
+		}
+		sections[i].Blocks = stripHeadingEmoji(sections[i].Blocks)
+	}
+	return blocks, sections
+}
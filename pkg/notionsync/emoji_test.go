@@ -0,0 +1,127 @@
+package notionsync
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestLeadingEmoji(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantEmoji string
+		wantRest  string
+	}{
+		{"🚀 Launch", "🚀", "Launch"},
+		{"⚠️  Careful", "⚠️", "Careful"},
+		{"👩‍💻 Team", "👩‍💻", "Team"},
+		{"✅ Done", "✅", "Done"},
+		{"Launch 🚀", "", "Launch 🚀"},
+		{"🚀Launch", "", "🚀Launch"},
+		{"🚀", "", "🚀"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if emoji, rest := leadingEmoji(tt.text); emoji != tt.wantEmoji || rest != tt.wantRest {
+			t.Errorf("leadingEmoji(%q) = %q, %q, want %q, %q", tt.text, emoji, rest, tt.wantEmoji, tt.wantRest)
+		}
+	}
+}
+
+func TestSyncFileHeadingEmoji(t *testing.T) {
+	const markdown = "# Title\n\nIntro.\n\n## 🚀 Launch\n\nGo.\n\n### 🧪 Tests\n\nCheck.\n\n## Plain\n\nText.\n"
+	tests := []struct {
+		mode       string
+		split      bool
+		wantTexts  []string
+		wantPages  []string
+		launchPage string
+		wantIcons  map[string]string
+		wantInPage []string
+	}{
+		{
+			mode:      "",
+			wantTexts: []string{"Intro.", "🚀 Launch", "Go.", "🧪 Tests", "Check.", "Plain", "Text."},
+		},
+		{
+			mode:      "inline",
+			wantTexts: []string{"Intro.", "🚀 Launch", "Go.", "🧪 Tests", "Check.", "Plain", "Text."},
+		},
+		{
+			mode:      "strip",
+			wantTexts: []string{"Intro.", "Launch", "Go.", "Tests", "Check.", "Plain", "Text."},
+		},
+		{
+			mode:       "inline",
+			split:      true,
+			wantPages:  []string{"Plain", "🚀 Launch"},
+			launchPage: "🚀 Launch",
+			wantIcons:  map[string]string{},
+			wantInPage: []string{"Go.", "🧪 Tests", "Check."},
+		},
+		{
+			mode:       "strip",
+			split:      true,
+			wantPages:  []string{"Launch", "Plain"},
+			launchPage: "Launch",
+			wantIcons:  map[string]string{},
+			wantInPage: []string{"Go.", "Tests", "Check."},
+		},
+		{
+			mode:       "icon",
+			split:      true,
+			wantPages:  []string{"Launch", "Plain"},
+			launchPage: "Launch",
+			wantIcons:  map[string]string{"Launch": "🚀"},
+			wantInPage: []string{"Go.", "Tests", "Check."},
+		},
+	}
+	for _, tt := range tests {
+		name := tt.mode
+		if name == "" {
+			name = "default"
+		}
+		if tt.split {
+			name += " split"
+		}
+		t.Run(name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			opts := testOptions()
+			opts.HeadingEmoji = tt.mode
+			if tt.split {
+				opts.SplitLevel = 2
+			}
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.split {
+				if got := pageTexts(client.content["page"]); !slices.Equal(got, tt.wantTexts) {
+					t.Errorf("page = %q, want %q", got, tt.wantTexts)
+				}
+				return
+			}
+
+			var titles []string
+			for title := range client.childPages["page"] {
+				titles = append(titles, title)
+			}
+			slices.Sort(titles)
+			if !slices.Equal(titles, tt.wantPages) {
+				t.Fatalf("child pages = %q, want %q", titles, tt.wantPages)
+			}
+			icons := map[string]string{}
+			for title, id := range client.childPages["page"] {
+				if icon, ok := client.icons[id]; ok {
+					icons[title] = icon
+				}
+			}
+			if !maps.Equal(icons, tt.wantIcons) {
+				t.Errorf("icons = %q, want %q", icons, tt.wantIcons)
+			}
+			if got := pageTexts(client.content[client.childPages["page"][tt.launchPage]]); !slices.Equal(got, tt.wantInPage) {
+				t.Errorf("%s page = %q, want %q", tt.launchPage, got, tt.wantInPage)
+			}
+		})
+	}
+}
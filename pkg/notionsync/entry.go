@@ -0,0 +1,27 @@
+package notionsync
+
+import (
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// DefaultEntryDateFormat is the Go time layout of entry headings unless --entry-date-format says otherwise
+const DefaultEntryDateFormat = "2006-01-02"
+
+// entryHeading builds the heading starting a dated entry for the day of now
+func entryHeading(now time.Time, format string) notion.Block {
+	return notion.Heading2Block{RichText: plainRichText(now.Format(format))}
+}
+
+// hasEntry reports whether the last heading on the page has the text of the entry heading,
+// meaning the entry was already appended
+func hasEntry(live []notion.Block, heading notion.Block) bool {
+	text := richTextPlainText(blockRichText(heading))
+	for i := len(live) - 1; i >= 0; i-- {
+		if headingLevel(live[i]) > 0 {
+			return richTextPlainText(blockRichText(live[i])) == text
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestHasEntry(t *testing.T) {
+	today := entryHeading(time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC), DefaultEntryDateFormat)
+	tests := []struct {
+		name string
+		live []notion.Block
+		want bool
+	}{
+		{name: "empty page", want: false},
+		{name: "today's entry last", live: convert(t, "## 2024-04-30\n\nOld.\n\n## 2024-05-01\n\nNew.\n"), want: true},
+		{name: "older entry last", live: convert(t, "## 2024-05-01\n\nNew.\n\n## 2024-04-30\n\nOld.\n"), want: false},
+		{name: "today in text only", live: convert(t, "## 2024-04-30\n\n2024-05-01\n"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasEntry(tt.live, today); got != tt.want {
+				t.Errorf("hasEntry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncFileEntryHeadingDate(t *testing.T) {
+	const format = "Monday, January 2"
+	today := time.Now().Format(format)
+	tests := []struct {
+		name     string
+		live     string
+		skip     bool
+		wantErr  error
+		wantPage []string
+	}{
+		{
+			name:     "new entry",
+			live:     "## Sunday, January 1\n\nOld.\n",
+			skip:     true,
+			wantPage: []string{"Sunday, January 1", "Old.", today, "Logged."},
+		},
+		{
+			name:     "same day entry skipped",
+			live:     "## " + today + "\n\nEarlier.\n",
+			skip:     true,
+			wantErr:  ErrContentUnchanged,
+			wantPage: []string{today, "Earlier."},
+		},
+		{
+			name:     "same day entry without skipping",
+			live:     "## " + today + "\n\nEarlier.\n",
+			wantPage: []string{today, "Earlier.", today, "Logged."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := syncedClient(t, "# Log\n\n"+tt.live)
+			opts := testOptions()
+			opts.EntryHeadingDate, opts.EntryDateFormat, opts.SkipExistingEntry = true, format, tt.skip
+
+			err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Log\n\nLogged.\n"), "page")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			content := client.content["page"]
+			if got := pageTexts(content); !slices.Equal(got, tt.wantPage) {
+				t.Errorf("page = %q, want %q", got, tt.wantPage)
+			}
+			if tt.wantErr == nil && headingLevel(content[len(content)-2]) != 2 {
+				t.Errorf("entry block = %T, want a level 2 heading", content[len(content)-2])
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package notionsync_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/christhomas/notionmd-cli/pkg/notionsync"
+	"github.com/dstotijn/go-notion"
+)
+
+// captureClient records the blocks a sync sends instead of sending them to Notion
+type captureClient struct {
+	notionsync.OfflineNotionClient
+	title  string
+	blocks []notion.Block
+}
+
+func (c *captureClient) UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error {
+	c.title = titleBlock.(notion.Heading1Block).RichText[0].PlainText
+	return nil
+}
+
+func (c *captureClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	c.blocks = append(c.blocks, blocks...)
+	return make([]string, len(blocks)), nil
+}
+
+func ExampleSyncFile() {
+	dir, err := os.MkdirTemp("", "notionsync-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mdPath := filepath.Join(dir, "notes.md")
+	markdown := "# Release notes\n\nFixed the upload retry.\n\n- faster\n- smaller\n"
+	if err := os.WriteFile(mdPath, []byte(markdown), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	client := &captureClient{}
+	opts := notionsync.SyncOptions{TitleLevel: 1, StatusOutput: io.Discard}
+	if err := notionsync.SyncFile(context.Background(), opts, client, mdPath, "page-id"); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("title:", client.title)
+	for _, block := range client.blocks {
+		fmt.Println(strings.TrimPrefix(fmt.Sprintf("%T", block), "*"))
+	}
+	// Output:
+	// title: Release notes
+	// notion.ParagraphBlock
+	// notion.BulletedListItemBlock
+	// notion.BulletedListItemBlock
+}
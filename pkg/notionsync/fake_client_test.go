@@ -0,0 +1,324 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// fakeNotionClient is an in-memory NotionClientInterface recording every call made to it.
+// Blocks added to a page get IDs like the ones Notion hands out, so later calls can delete
+// them by ID.
+type fakeNotionClient struct {
+	calls []string
+
+	content    map[string][]notion.Block
+	titles     map[string]string
+	properties map[string]map[string]string
+	hashes     map[string]string
+	childPages map[string]map[string]string
+	icons      map[string]string
+	databases  map[string][]string
+	comments   map[string][]string
+	uploads    []string
+	nextID     int
+}
+
+func newFakeNotionClient() *fakeNotionClient {
+	return &fakeNotionClient{
+		content:    make(map[string][]notion.Block),
+		titles:     make(map[string]string),
+		properties: make(map[string]map[string]string),
+		hashes:     make(map[string]string),
+		childPages: make(map[string]map[string]string),
+		icons:      make(map[string]string),
+		databases:  make(map[string][]string),
+		comments:   make(map[string][]string),
+	}
+}
+
+var _ NotionClientInterface = (*fakeNotionClient)(nil)
+
+func (c *fakeNotionClient) record(format string, args ...any) {
+	c.calls = append(c.calls, fmt.Sprintf(format, args...))
+}
+
+// callNames returns the names of the recorded calls, without their arguments
+func (c *fakeNotionClient) callNames() []string {
+	names := make([]string, len(c.calls))
+	for i, call := range c.calls {
+		names[i], _, _ = strings.Cut(call, " ")
+	}
+	return names
+}
+
+// newID returns a fresh block or page ID
+func (c *fakeNotionClient) newID() string {
+	c.nextID++
+	return fmt.Sprintf("fake-%d", c.nextID)
+}
+
+// withID returns block as Notion would return it from the API, carrying id. API responses
+// leave children out, so they are decoded separately and attached to the result.
+func withID(block notion.Block, id string) notion.Block {
+	children := blockChildren(block)
+	switch table := block.(type) {
+	case *notion.TableBlock:
+		children = table.Children
+	case notion.TableBlock:
+		children = table.Children
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		panic(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		panic(err)
+	}
+	var blockType string
+	for key := range fields {
+		blockType = key
+	}
+	var content map[string]json.RawMessage
+	if err := json.Unmarshal(fields[blockType], &content); err == nil {
+		delete(content, "children")
+		fields[blockType], _ = json.Marshal(content)
+	}
+	fields["type"], _ = json.Marshal(blockType)
+	fields["object"], _ = json.Marshal("block")
+	fields["id"], _ = json.Marshal(id)
+	fields["has_children"], _ = json.Marshal(len(children) > 0)
+	data, _ = json.Marshal(map[string]any{"results": []any{fields}})
+	var resp notion.BlockChildrenResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		panic(err)
+	}
+	if len(children) == 0 {
+		return resp.Results[0]
+	}
+	withIDs := make([]notion.Block, len(children))
+	for i, child := range children {
+		withIDs[i] = withID(child, fmt.Sprintf("%s-%d", id, i+1))
+	}
+	if table, ok := resp.Results[0].(*notion.TableBlock); ok {
+		table.Children = withIDs
+		return table
+	}
+	return withChildren(resp.Results[0], withIDs)
+}
+
+// blockTypes returns the types of blocks, e.g. "notion.ParagraphBlock", for comparing in tests
+func blockTypes(blocks []notion.Block) []string {
+	types := make([]string, len(blocks))
+	for i, block := range blocks {
+		types[i] = strings.TrimPrefix(fmt.Sprintf("%T", block), "*")
+	}
+	return types
+}
+
+func (c *fakeNotionClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	c.record("UploadFile %s", filePath)
+	c.uploads = append(c.uploads, filePath)
+	return "upload-" + c.newID(), nil
+}
+
+func (c *fakeNotionClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	c.record("AddPageContent %s %d", pageID, len(blocks))
+	ids := make([]string, len(blocks))
+	for i, block := range blocks {
+		ids[i] = c.newID()
+		c.content[pageID] = append(c.content[pageID], withID(block, ids[i]))
+	}
+	return ids, nil
+}
+
+func (c *fakeNotionClient) ReplaceSection(ctx context.Context, pageID, afterID string, oldIDs []string, blocks []notion.Block) ([]string, error) {
+	c.record("ReplaceSection %s %s %d %d", pageID, afterID, len(oldIDs), len(blocks))
+	content := c.content[pageID]
+	at := len(content)
+	if afterID != "" {
+		at = slices.IndexFunc(content, func(b notion.Block) bool { return b.ID() == afterID }) + 1
+	}
+	ids := make([]string, len(blocks))
+	inserted := make([]notion.Block, len(blocks))
+	for i, block := range blocks {
+		ids[i] = c.newID()
+		inserted[i] = withID(block, ids[i])
+	}
+	content = slices.Insert(content, at, inserted...)
+	c.content[pageID] = content
+	c.deleteBlocks(pageID, func(b notion.Block) bool { return slices.Contains(oldIDs, b.ID()) })
+	return ids, nil
+}
+
+func (c *fakeNotionClient) ClearPageContent(ctx context.Context, pageID string) error {
+	c.record("ClearPageContent %s", pageID)
+	c.deleteBlocks(pageID, func(notion.Block) bool { return true })
+	return nil
+}
+
+func (c *fakeNotionClient) ClearPageContentAfter(ctx context.Context, pageID string, keep int) error {
+	c.record("ClearPageContentAfter %s %d", pageID, keep)
+	i := 0
+	c.deleteBlocks(pageID, func(notion.Block) bool {
+		i++
+		return i > keep
+	})
+	return nil
+}
+
+// deleteBlocks removes the blocks of pageID matching del. Deleted child_page blocks archive
+// their page the way Notion does, so they are no longer listed by GetChildPages.
+func (c *fakeNotionClient) deleteBlocks(pageID string, del func(notion.Block) bool) {
+	c.content[pageID] = slices.DeleteFunc(c.content[pageID], func(b notion.Block) bool {
+		if !del(b) {
+			return false
+		}
+		if page, ok := b.(*notion.ChildPageBlock); ok {
+			delete(c.childPages[pageID], page.Title)
+		}
+		return true
+	})
+}
+
+func (c *fakeNotionClient) UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error {
+	c.record("UpdatePageTitle %s", pageID)
+	c.titles[pageID] = richTextPlainText(blockRichText(titleBlock))
+	return nil
+}
+
+func (c *fakeNotionClient) GetProperty(ctx context.Context, pageID, propName string) (string, error) {
+	c.record("GetProperty %s %s", pageID, propName)
+	return c.properties[pageID][propName], nil
+}
+
+func (c *fakeNotionClient) SetProperty(ctx context.Context, pageID, propName, value string) error {
+	c.record("SetProperty %s %s", pageID, propName)
+	if c.properties[pageID] == nil {
+		c.properties[pageID] = make(map[string]string)
+	}
+	c.properties[pageID][propName] = value
+	return nil
+}
+
+func (c *fakeNotionClient) SetProperties(ctx context.Context, pageID string, values map[string]string) error {
+	c.record("SetProperties %s %s", pageID, strings.Join(slices.Sorted(maps.Keys(values)), ","))
+	if c.properties[pageID] == nil {
+		c.properties[pageID] = make(map[string]string)
+	}
+	maps.Copy(c.properties[pageID], values)
+	return nil
+}
+
+func (c *fakeNotionClient) GetLastEdit(ctx context.Context, pageID string) (PageEdit, error) {
+	c.record("GetLastEdit %s", pageID)
+	return PageEdit{}, nil
+}
+
+func (c *fakeNotionClient) GetPageContent(ctx context.Context, pageID string) ([]notion.Block, error) {
+	c.record("GetPageContent %s", pageID)
+	return slices.Clone(c.content[pageID]), nil
+}
+
+func (c *fakeNotionClient) VerifyPage(ctx context.Context, pageID string) error {
+	c.record("VerifyPage %s", pageID)
+	return nil
+}
+
+func (c *fakeNotionClient) GetStoredHash(ctx context.Context, pageID, storage string) (string, error) {
+	c.record("GetStoredHash %s %s", pageID, storage)
+	return c.hashes[pageID], nil
+}
+
+func (c *fakeNotionClient) SetStoredHash(ctx context.Context, pageID, storage, hash string) error {
+	c.record("SetStoredHash %s %s", pageID, storage)
+	c.hashes[pageID] = hash
+	return nil
+}
+
+func (c *fakeNotionClient) CreateChildPage(ctx context.Context, parentID, title, icon string, blocks []notion.Block) (string, error) {
+	c.record("CreateChildPage %s %s", parentID, title)
+	childID := c.newID()
+	if c.childPages[parentID] == nil {
+		c.childPages[parentID] = make(map[string]string)
+	}
+	c.childPages[parentID][title] = childID
+	if icon != "" {
+		c.icons[childID] = icon
+	}
+	c.content[parentID] = append(c.content[parentID], withID(notion.ChildPageBlock{Title: title}, childID))
+	for _, block := range blocks {
+		c.content[childID] = append(c.content[childID], withID(block, c.newID()))
+	}
+	return childID, nil
+}
+
+func (c *fakeNotionClient) CreateDatabasePage(ctx context.Context, databaseID string, title []notion.RichText, values map[string]string, blocks []notion.Block) (string, error) {
+	c.record("CreateDatabasePage %s %s", databaseID, richTextPlainText(title))
+	pageID := c.newID()
+	c.databases[databaseID] = append(c.databases[databaseID], pageID)
+	c.titles[pageID] = richTextPlainText(title)
+	c.properties[pageID] = maps.Clone(values)
+	for _, block := range blocks {
+		c.content[pageID] = append(c.content[pageID], withID(block, c.newID()))
+	}
+	return pageID, nil
+}
+
+// addChildPage adds an existing child page titled title to parentID, as if created earlier
+func (c *fakeNotionClient) addChildPage(parentID, title string, blocks ...notion.Block) string {
+	calls := c.calls
+	childID, _ := c.CreateChildPage(context.Background(), parentID, title, "", blocks)
+	c.calls = calls
+	return childID
+}
+
+func (c *fakeNotionClient) GetChildPages(ctx context.Context, parentID string) (map[string]string, error) {
+	c.record("GetChildPages %s", parentID)
+	return maps.Clone(c.childPages[parentID]), nil
+}
+
+func (c *fakeNotionClient) AddComment(ctx context.Context, pageID, text string) error {
+	c.record("AddComment %s", pageID)
+	c.comments[pageID] = append(c.comments[pageID], text)
+	return nil
+}
+
+func (c *fakeNotionClient) AddBlockComment(ctx context.Context, blockID string, richText []notion.RichText) error {
+	c.record("AddBlockComment %s", blockID)
+	c.comments[blockID] = append(c.comments[blockID], richTextPlainText(richText))
+	return nil
+}
+
+func (c *fakeNotionClient) SetPageIconAndCover(ctx context.Context, pageID, icon, cover string) error {
+	c.record("SetPageIconAndCover %s %s %s", pageID, icon, cover)
+	return nil
+}
+
+func TestWithIDKeepsTheBlock(t *testing.T) {
+	block := withID(notion.ParagraphBlock{RichText: plainRichText("hello")}, "id-1")
+	if block.ID() != "id-1" {
+		t.Errorf("ID() = %q, want id-1", block.ID())
+	}
+	if got := richTextPlainText(blockRichText(block)); got != "hello" {
+		t.Errorf("text = %q, want hello", got)
+	}
+}
+
+func TestWithIDKeepsChildren(t *testing.T) {
+	toggle := notion.ToggleBlock{
+		RichText: plainRichText("toggle"),
+		Children: []notion.Block{notion.ParagraphBlock{RichText: plainRichText("child")}},
+	}
+	children := blockChildren(withID(toggle, "id-1"))
+	if len(children) != 1 || children[0].ID() != "id-1-1" || ownText(children[0]) != "child" {
+		t.Errorf("children = %v, want the child paragraph with an ID", blockTypes(children))
+	}
+}
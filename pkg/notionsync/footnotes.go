@@ -0,0 +1,182 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// FootnoteModes are the accepted --footnotes values: a numbered list at the end of the page
+// (list), the footnote text in parentheses at its reference (inline) or a comment on the block
+// holding the reference (comments)
+var FootnoteModes = []string{"list", "inline", "comments"}
+
+// Regular expression to find a footnote definition: [^label]: text
+var footnoteDefinitionRegex = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]*(.*)$`)
+
+// Regular expression to find a footnote reference: [^label]
+var footnoteReferenceRegex = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+
+// Regular expression to find the marker a footnote reference is replaced with: [1]
+var footnoteMarkerRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+// footnote is a footnote definition, numbered in the order of its first reference
+type footnote struct {
+	Number int
+	Label  string
+	Text   string
+}
+
+// extractFootnotes removes the footnote definitions from content and rewrites the references
+// for the given mode: "[n]" markers for list and comments, the footnote text in parentheses for
+// inline. In list mode the footnotes are appended as a numbered list. Returns the new content
+// and the referenced footnotes in order. Fenced code is left alone.
+func extractFootnotes(content, mode string) (string, []footnote) {
+	lines := strings.Split(content, "\n")
+	definitions := map[string]string{}
+	body := make([]string, 0, len(lines))
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			body = append(body, line)
+			continue
+		}
+		if fence = fenceOpening(line); fence != "" {
+			body = append(body, line)
+			continue
+		}
+		match := footnoteDefinitionRegex.FindStringSubmatch(line)
+		if match == nil {
+			body = append(body, line)
+			continue
+		}
+		// Continuation lines are indented, blank lines only continue a definition before more indented text
+		text := []string{strings.TrimSpace(match[2])}
+		for i+1 < len(lines) {
+			next := lines[i+1]
+			if strings.TrimSpace(next) == "" && i+2 < len(lines) && strings.HasPrefix(lines[i+2], "    ") {
+				i++
+				continue
+			}
+			if !strings.HasPrefix(next, "    ") && !strings.HasPrefix(next, "\t") {
+				break
+			}
+			text = append(text, strings.TrimSpace(next))
+			i++
+		}
+		if _, ok := definitions[match[1]]; !ok {
+			definitions[match[1]] = strings.Join(text, " ")
+		}
+	}
+	if len(definitions) == 0 {
+		return content, nil
+	}
+
+	var notes []footnote
+	numbers := map[string]int{}
+	fence = ""
+	for i, line := range body {
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence = fenceOpening(line); fence != "" {
+			continue
+		}
+		var rewritten strings.Builder
+		last := 0
+		for _, loc := range footnoteReferenceRegex.FindAllStringSubmatchIndex(line, -1) {
+			label := line[loc[2]:loc[3]]
+			text, ok := definitions[label]
+			if !ok {
+				continue
+			}
+			rewritten.WriteString(line[last:loc[0]])
+			last = loc[1]
+			if mode == "inline" {
+				if loc[0] > 0 && line[loc[0]-1] != ' ' {
+					rewritten.WriteString(" ")
+				}
+				rewritten.WriteString("(" + text + ")")
+				continue
+			}
+			number, ok := numbers[label]
+			if !ok {
+				number = len(notes) + 1
+				numbers[label] = number
+				notes = append(notes, footnote{Number: number, Label: label, Text: text})
+			}
+			fmt.Fprintf(&rewritten, "[%d]", number)
+		}
+		body[i] = rewritten.String() + line[last:]
+	}
+
+	content = strings.Join(body, "\n")
+	if mode == "list" && len(notes) > 0 {
+		var list strings.Builder
+		list.WriteString("\n\n### Footnotes\n\n")
+		for _, note := range notes {
+			fmt.Fprintf(&list, "%d. %s\n", note.Number, note.Text)
+		}
+		content = strings.TrimRight(content, "\n") + list.String()
+	}
+	return content, notes
+}
+
+// footnoteComment is a comment to post on a synced block for a footnote referenced in it
+type footnoteComment struct {
+	BlockID  string
+	RichText []notion.RichText
+}
+
+// footnoteComments pairs each footnote with the first top level block referencing it, looking
+// for its marker in the block's text and its children. blockIDs are the IDs Notion gave blocks.
+func footnoteComments(ctx context.Context, blocks []notion.Block, blockIDs []string, notes []footnote) []footnoteComment {
+	byNumber := map[int]footnote{}
+	for _, note := range notes {
+		byNumber[note.Number] = note
+	}
+	var comments []footnoteComment
+	for i, block := range blocks {
+		if i >= len(blockIDs) {
+			break
+		}
+		for _, match := range footnoteMarkerRegex.FindAllStringSubmatch(blockText(block), -1) {
+			number, _ := strconv.Atoi(match[1])
+			note, ok := byNumber[number]
+			if !ok {
+				continue
+			}
+			delete(byNumber, number)
+			comments = append(comments, footnoteComment{
+				BlockID:  blockIDs[i],
+				RichText: append(plainRichText(fmt.Sprintf("[%d] ", note.Number)), inlineRichText(note.Text)...),
+			})
+		}
+	}
+	for _, note := range notes {
+		if _, ok := byNumber[note.Number]; ok {
+			warnf(ctx, "Footnote [^%s] is referenced in a block without an ID, it isn't posted as a comment\n", note.Label)
+		}
+	}
+	return comments
+}
+
+// blockText returns the plain text of a block and all its children
+func blockText(block notion.Block) string {
+	text := richTextPlainText(blockRichText(block))
+	for _, child := range blockChildren(block) {
+		text += "\n" + blockText(child)
+	}
+	return text
+}
@@ -0,0 +1,105 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractFootnotes(t *testing.T) {
+	const markdown = "Claim[^src] and another[^2].\n\nAgain[^src].\n\n" +
+		"```\nnot[^src] a reference\n```\n\n" +
+		"[^src]: The source,\n    continued.\n[^2]: Second note.\n[^unused]: Never referenced.\n"
+	tests := []struct {
+		mode      string
+		want      string
+		wantNotes []footnote
+	}{
+		{
+			mode: "list",
+			want: "Claim[1] and another[2].\n\nAgain[1].\n\n```\nnot[^src] a reference\n```\n\n### Footnotes\n\n1. The source, continued.\n2. Second note.\n",
+			wantNotes: []footnote{
+				{Number: 1, Label: "src", Text: "The source, continued."},
+				{Number: 2, Label: "2", Text: "Second note."},
+			},
+		},
+		{
+			mode: "comments",
+			want: "Claim[1] and another[2].\n\nAgain[1].\n\n```\nnot[^src] a reference\n```\n\n",
+			wantNotes: []footnote{
+				{Number: 1, Label: "src", Text: "The source, continued."},
+				{Number: 2, Label: "2", Text: "Second note."},
+			},
+		},
+		{
+			mode: "inline",
+			want: "Claim (The source, continued.) and another (Second note.).\n\nAgain (The source, continued.).\n\n```\nnot[^src] a reference\n```\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, notes := extractFootnotes(markdown, tt.mode)
+			if got != tt.want {
+				t.Errorf("content =\n%q\nwant\n%q", got, tt.want)
+			}
+			if !slices.Equal(notes, tt.wantNotes) {
+				t.Errorf("notes = %+v, want %+v", notes, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestFootnoteComments(t *testing.T) {
+	blocks := convert(t, "Intro without notes.\n\nClaim[1] and another[2].\n\n- item\n    - nested[3]\n\nLast[1][4].\n")
+	notes := []footnote{
+		{Number: 1, Label: "a", Text: "First *note*."},
+		{Number: 2, Label: "b", Text: "Second note."},
+		{Number: 3, Label: "c", Text: "Nested note."},
+		{Number: 4, Label: "d", Text: "Beyond the IDs."},
+	}
+	ctx := NewContext(context.Background(), testOptions())
+	// The last block has no ID, as if it was never created
+	comments := footnoteComments(ctx, blocks, []string{"b-intro", "b-claim", "b-list"}, notes)
+
+	var got []string
+	for _, comment := range comments {
+		got = append(got, comment.BlockID+": "+richTextPlainText(comment.RichText))
+	}
+	want := []string{"b-claim: [1] First note.", "b-claim: [2] Second note.", "b-list: [3] Nested note."}
+	if !slices.Equal(got, want) {
+		t.Errorf("comments = %q, want %q", got, want)
+	}
+	if italic := comments[0].RichText[len(comments[0].RichText)-2]; italic.Annotations == nil || !italic.Annotations.Italic {
+		t.Errorf("comment runs = %q, want the note's emphasis kept", annotatedRuns(comments[0].RichText))
+	}
+	if n := warningCount(ctx); n != 1 {
+		t.Errorf("got %d warnings, want 1 for the footnote without a block ID", n)
+	}
+}
+
+func TestSyncFileFootnoteComments(t *testing.T) {
+	const markdown = "# Title\n\nFirst paragraph.\n\nA claim[^1].\n\n[^1]: The evidence.\n"
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.Footnotes = "comments"
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if len(content) != 2 || ownText(content[1]) != "A claim[1]." {
+		t.Fatalf("content = %v, want the two paragraphs without a footnote list", blockTypes(content))
+	}
+	claimID := content[1].ID()
+	if got := client.comments[claimID]; !slices.Equal(got, []string{"[1] The evidence."}) {
+		t.Errorf("comments on the claim = %q, want the footnote", got)
+	}
+	for id, comments := range client.comments {
+		if id != claimID {
+			t.Errorf("unexpected comments on %s: %q", id, comments)
+		}
+	}
+	if strings.Contains(blockText(content[0]), "evidence") {
+		t.Error("the footnote text was added to the page")
+	}
+}
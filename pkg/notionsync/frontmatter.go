@@ -0,0 +1,108 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// frontmatterPageKey is the frontmatter key naming the page a markdown file syncs to
+const frontmatterPageKey = "notion_page"
+
+// The frontmatter keys setting the page icon and cover, as --icon and --cover do
+const (
+	frontmatterIconKey  = "notion_icon"
+	frontmatterCoverKey = "notion_cover"
+)
+
+// parseFrontmatter splits a leading "---" delimited frontmatter block off the markdown and
+// returns its top level "key: value" pairs and the remaining content. Block lists ("- item"
+// lines under an empty key) are folded into flow form ("[a, b]"), other nested values are
+// ignored. Content without frontmatter is returned unchanged with a nil map.
+func parseFrontmatter(content []byte) (map[string]string, []byte) {
+	if !bytes.HasPrefix(content, []byte("---\n")) {
+		return nil, content
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t\n") != "---" {
+			continue
+		}
+		frontmatter := make(map[string]string)
+		var listKey string
+		var items []string
+		for _, line := range lines[1:i] {
+			if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && listKey != "" {
+				items = append(items, unquoteFrontmatter(strings.TrimSpace(item)))
+				frontmatter[listKey] = "[" + strings.Join(items, ", ") + "]"
+				continue
+			}
+			listKey, items = "", nil
+			if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+				continue
+			}
+			key, value, ok := strings.Cut(strings.TrimRight(line, "\n"), ":")
+			if !ok {
+				continue
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			frontmatter[key] = unquoteFrontmatter(value)
+			if value == "" {
+				listKey = key
+			}
+		}
+		return frontmatter, []byte(strings.Join(lines[i+1:], ""))
+	}
+	// No closing delimiter, so the leading "---" is an ordinary thematic break
+	return nil, content
+}
+
+// unquoteFrontmatter strips matching single or double quotes around a frontmatter value
+func unquoteFrontmatter(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// frontmatterList splits a frontmatter list value, in flow form ("[a, b]") or comma separated
+func frontmatterList(value string) []string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = value[1 : len(value)-1]
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = unquoteFrontmatter(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// frontmatterPageID returns the page ID declared in the frontmatter of the markdown file at
+// mdPath, or "" if it declares none
+func frontmatterPageID(ctx context.Context, mdPath string) (string, error) {
+	content, err := readInputFile(ctx, mdPath)
+	if err != nil {
+		return "", err
+	}
+	frontmatter, _ := parseFrontmatter(normalizeLineEndings(content))
+	return frontmatter[frontmatterPageKey], nil
+}
+
+// frontmatterImage resolves an icon or cover image named in the frontmatter of the markdown
+// file at mdPath: relative paths of existing files are taken relative to the markdown file,
+// URLs, emoji and everything else are returned as they are
+func frontmatterImage(value, mdPath string) string {
+	if value == "" || isRemoteImage(value) || filepath.IsAbs(value) {
+		return value
+	}
+	path := filepath.Join(filepath.Dir(mdPath), value)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package notionsync
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        map[string]string
+		wantContent string
+	}{
+		{"page ID", "---\nnotion_page: abc123\n---\n# Title\n", map[string]string{"notion_page": "abc123"}, "# Title\n"},
+		{"quoted page ID", "---\ntitle: Doc\nnotion_page: \"abc123\"\n---\ntext\n", map[string]string{"title": "Doc", "notion_page": "abc123"}, "text\n"},
+		{"block list", "---\ntags:\n  - a\n  - 'b'\n---\n", map[string]string{"tags": "[a, b]"}, ""},
+		{"no frontmatter", "# Title\n", nil, "# Title\n"},
+		{"thematic break", "---\n\ntext\n", nil, "---\n\ntext\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, content := parseFrontmatter([]byte(tt.content))
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("frontmatter = %v, want %v", got, tt.want)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestSyncFileFrontmatterPageID(t *testing.T) {
+	const markdown = "---\nnotion_page: frontmatter-page\n---\n# Title\n\ntext\n"
+	tests := []struct {
+		name     string
+		markdown string
+		pageID   string
+		wantPage string
+		wantErr  string
+	}{
+		{"frontmatter page", markdown, "", "frontmatter-page", ""},
+		{"page flag wins", markdown, "flag-page", "flag-page", ""},
+		{"no page anywhere", "# Title\n\ntext\n", "", "", "No target page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, tt.markdown), tt.pageID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if len(client.calls) != 0 {
+					t.Errorf("calls = %v, want none", client.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pageTexts(client.content[tt.wantPage]); len(got) != 1 || got[0] != "text" {
+				t.Errorf("page %s holds %q, want the text without the frontmatter", tt.wantPage, got)
+			}
+			if len(client.content) != 1 {
+				t.Errorf("synced %d pages, want only %s", len(client.content), tt.wantPage)
+			}
+		})
+	}
+}
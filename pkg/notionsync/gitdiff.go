@@ -0,0 +1,227 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find a hunk header of a unified diff: @@ -12,3 +12,4 @@
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// gitDiff is what a unified diff of one file says about the new version of the file
+type gitDiff struct {
+	// NewFile is set when the file didn't exist before
+	NewFile bool
+	// Changed are the 1-based lines of the new version that were added or changed. Where lines
+	// were only removed, the line before the removal counts as changed.
+	Changed []int
+	// HeadingChanged is set when a heading line was added, removed or changed
+	HeadingChanged bool
+}
+
+// readGitDiff returns the diff of the file at mdPath in the latest commit, without context lines
+func readGitDiff(mdPath string) (string, error) {
+	dir, file := filepath.Split(mdPath)
+	if dir == "" {
+		dir = "."
+	}
+	cmd := exec.Command("git", "-C", dir, "diff", "--no-color", "--unified=0", "HEAD~1", "HEAD", "--", file)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// parseGitDiff reads the changed lines of a file from its unified diff
+func parseGitDiff(diff string) gitDiff {
+	var result gitDiff
+	line := 0
+	inHunk := false
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			match := hunkHeaderRegex.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			inHunk = true
+			line, _ = strconv.Atoi(match[1])
+			if match[2] == "0" {
+				// Only removals: the hunk sits after the given line
+				result.Changed = append(result.Changed, max(line, 1))
+				line++
+			}
+		case !inHunk:
+			// The file header: "--- /dev/null" means the file was added
+			result.NewFile = result.NewFile || text == "--- /dev/null"
+		case strings.HasPrefix(text, "diff "):
+			inHunk = false
+		case strings.HasPrefix(text, "+"):
+			result.Changed = append(result.Changed, line)
+			result.HeadingChanged = result.HeadingChanged || headingLineRegex.MatchString(text[1:])
+			line++
+		case strings.HasPrefix(text, "-"):
+			result.HeadingChanged = result.HeadingChanged || headingLineRegex.MatchString(text[1:])
+		}
+	}
+	return result
+}
+
+// markdownHeadingLines returns the 1-based lines of the top level ATX headings in content,
+// leaving out fenced code
+func markdownHeadingLines(content string) []int {
+	var headings []int
+	fence := ""
+	for i, line := range strings.Split(content, "\n") {
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence = fenceOpening(line); fence != "" {
+			continue
+		}
+		if headingLineRegex.MatchString(line) {
+			headings = append(headings, i+1)
+		}
+	}
+	return headings
+}
+
+// sectionUpdate replaces the body of one section of a page: the blocks between its heading and
+// the next heading
+type sectionUpdate struct {
+	Heading   string
+	HeadingID string
+	OldIDs    []string
+	Blocks    []notion.Block
+}
+
+// gitDiffSections plans the --git-diff sync of the markdown file at mdPath to the page pageID
+func gitDiffSections(ctx context.Context, notionClient NotionClientInterface, mdPath, pageID string, titleBlock notion.Block, blocks []notion.Block) ([]sectionUpdate, error) {
+	diff, err := readGitDiff(mdPath)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	source, err := readInputFile(ctx, mdPath)
+	if err != nil {
+		return nil, err
+	}
+	live, err := notionClient.GetPageContent(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the page: %w", err)
+	}
+	return planGitDiffSync(diff, string(source), titleBlock, blocks, live)
+}
+
+// planGitDiffSync works out the sections of the page to replace for the changes diff made to
+// the markdown file source. blocks are the converted blocks of the whole file and titleBlock
+// the heading taken off them as the page title, live the page's blocks. Fails when the changes can't be matched to sections whose heading is on the
+// page, so the caller can fall back to a full sync.
+func planGitDiffSync(diff, source string, titleBlock notion.Block, blocks, live []notion.Block) ([]sectionUpdate, error) {
+	if strings.TrimSpace(diff) == "" {
+		return nil, fmt.Errorf("the latest commit doesn't change the file")
+	}
+	changes := parseGitDiff(diff)
+	switch {
+	case changes.NewFile:
+		return nil, fmt.Errorf("the file is new in the latest commit")
+	case changes.HeadingChanged:
+		return nil, fmt.Errorf("the latest commit changes headings")
+	}
+
+	// The markdown headings line up with the heading blocks, the title being the first of them
+	var headings []int
+	for i, block := range blocks {
+		if headingLevel(block) > 0 {
+			headings = append(headings, i)
+		}
+	}
+	headingLines := markdownHeadingLines(source)
+	skipped := 0
+	if titleBlock != nil {
+		skipped = 1
+	}
+	if len(headingLines) != len(headings)+skipped {
+		return nil, fmt.Errorf("the markdown headings don't match the converted headings")
+	}
+
+	// Section n follows the n-th heading, section -1 is everything before the first
+	changed := map[int]bool{}
+	for _, line := range changes.Changed {
+		section := -1
+		for n, headingLine := range headingLines {
+			if headingLine <= line {
+				section = n
+			}
+		}
+		if section < skipped {
+			return nil, fmt.Errorf("the latest commit changes the content before the first section heading")
+		}
+		changed[section-skipped] = true
+	}
+
+	var updates []sectionUpdate
+	for n, index := range headings {
+		if !changed[n] {
+			continue
+		}
+		end := len(blocks)
+		if n+1 < len(headings) {
+			end = headings[n+1]
+		}
+		heading := richTextPlainText(blockRichText(blocks[index]))
+		occurrence := 0
+		for _, other := range headings[:n] {
+			if richTextPlainText(blockRichText(blocks[other])) == heading {
+				occurrence++
+			}
+		}
+		start, liveEnd, ok := liveSection(live, heading, occurrence)
+		if !ok {
+			return nil, fmt.Errorf("the page has no heading '%s' to replace the section under", heading)
+		}
+		update := sectionUpdate{Heading: heading, HeadingID: live[start].ID(), Blocks: blocks[index+1 : end]}
+		for _, block := range live[start+1 : liveEnd] {
+			update.OldIDs = append(update.OldIDs, block.ID())
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// liveSection finds the given occurrence (0 is the first) of a heading with the text heading
+// among the page's blocks. Returns the heading's index and the index of the next heading, or
+// the end of the page.
+func liveSection(live []notion.Block, heading string, occurrence int) (start, end int, ok bool) {
+	start = -1
+	for i, block := range live {
+		if headingLevel(block) == 0 {
+			continue
+		}
+		if start >= 0 {
+			return start, i, true
+		}
+		if richTextPlainText(blockRichText(block)) == heading {
+			if occurrence == 0 {
+				start = i
+			}
+			occurrence--
+		}
+	}
+	return start, len(live), start >= 0
+}
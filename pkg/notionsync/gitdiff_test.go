@@ -0,0 +1,134 @@
+package notionsync
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestParseGitDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want gitDiff
+	}{
+		{
+			name: "changed and added lines",
+			diff: "diff --git a/doc.md b/doc.md\n--- a/doc.md\n+++ b/doc.md\n@@ -5 +5 @@\n-old\n+new\n@@ -9,0 +10,2 @@\n+one\n+two\n",
+			want: gitDiff{Changed: []int{5, 10, 11}},
+		},
+		{
+			name: "only removed lines",
+			diff: "--- a/doc.md\n+++ b/doc.md\n@@ -7,2 +6,0 @@\n-gone\n-also gone\n",
+			want: gitDiff{Changed: []int{6}},
+		},
+		{
+			name: "removed from the top",
+			diff: "--- a/doc.md\n+++ b/doc.md\n@@ -1 +0,0 @@\n-first\n",
+			want: gitDiff{Changed: []int{1}},
+		},
+		{
+			name: "heading changed",
+			diff: "--- a/doc.md\n+++ b/doc.md\n@@ -3 +3 @@\n-## Old\n+## New\n",
+			want: gitDiff{Changed: []int{3}, HeadingChanged: true},
+		},
+		{
+			name: "new file",
+			diff: "--- /dev/null\n+++ b/doc.md\n@@ -0,0 +1,2 @@\n+# Title\n+text\n",
+			want: gitDiff{NewFile: true, Changed: []int{1, 2}, HeadingChanged: true},
+		},
+		{
+			name: "removed line that looks like a header",
+			diff: "--- a/doc.md\n+++ b/doc.md\n@@ -4 +4 @@\n--- /dev/null\n+text\n",
+			want: gitDiff{Changed: []int{4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGitDiff(tt.diff)
+			if got.NewFile != tt.want.NewFile || got.HeadingChanged != tt.want.HeadingChanged || !slices.Equal(got.Changed, tt.want.Changed) {
+				t.Errorf("parseGitDiff = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownHeadingLines(t *testing.T) {
+	content := "# Title\n\ntext\n\n```sh\n# a comment\n```\n\n## Section\n    # indented code\n### Sub\n"
+	if got := markdownHeadingLines(content); !slices.Equal(got, []int{1, 9, 11}) {
+		t.Errorf("markdownHeadingLines = %v, want [1 9 11]", got)
+	}
+}
+
+func TestPlanGitDiffSync(t *testing.T) {
+	const source = "# Title\n\nIntro.\n\n## One\n\nFirst.\n\n## Two\n\nSecond.\n\n## Two\n\nAgain.\n"
+	client := syncedClient(t, source)
+	live := client.content["page"]
+	titleBlock, blocks := FilterTitleBlock(convert(t, source), 1)
+
+	tests := []struct {
+		name         string
+		diff         string
+		wantHeadings []string
+		wantBlocks   []string
+		wantErr      string
+	}{
+		{
+			name:         "one section",
+			diff:         "--- a/doc.md\n+++ b/doc.md\n@@ -7 +7 @@\n-Old first.\n+First.\n",
+			wantHeadings: []string{"One"},
+			wantBlocks:   []string{"First."},
+		},
+		{
+			name:         "repeated heading",
+			diff:         "--- a/doc.md\n+++ b/doc.md\n@@ -15 +15 @@\n-Old.\n+Again.\n",
+			wantHeadings: []string{"Two"},
+			wantBlocks:   []string{"Again."},
+		},
+		{
+			name:         "two sections",
+			diff:         "--- a/doc.md\n+++ b/doc.md\n@@ -7 +7 @@\n-x\n+First.\n@@ -11 +11 @@\n-y\n+Second.\n",
+			wantHeadings: []string{"One", "Two"},
+			wantBlocks:   []string{"First.", "Second."},
+		},
+		{name: "no change", diff: "", wantErr: "doesn't change the file"},
+		{name: "new file", diff: "--- /dev/null\n+++ b/doc.md\n@@ -0,0 +1 @@\n+Intro.\n", wantErr: "file is new"},
+		{name: "heading changed", diff: "--- a/doc.md\n+++ b/doc.md\n@@ -5 +5 @@\n-## Uno\n+## One\n", wantErr: "changes headings"},
+		{name: "intro changed", diff: "--- a/doc.md\n+++ b/doc.md\n@@ -3 +3 @@\n-Old intro.\n+Intro.\n", wantErr: "before the first section heading"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, err := planGitDiffSync(tt.diff, source, titleBlock, blocks, live)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var headings, texts []string
+			for _, update := range updates {
+				headings = append(headings, update.Heading)
+				texts = append(texts, pageTexts(update.Blocks)...)
+				// The update replaces exactly the live blocks between the heading and the next one
+				start := slices.IndexFunc(live, func(b notion.Block) bool { return b.ID() == update.HeadingID })
+				if start < 0 || len(update.OldIDs) != 1 || live[start+1].ID() != update.OldIDs[0] || ownText(live[start+1]) != ownText(update.Blocks[0]) {
+					t.Errorf("update of %s = heading %s, old blocks %v", update.Heading, update.HeadingID, update.OldIDs)
+				}
+			}
+			if !slices.Equal(headings, tt.wantHeadings) || !slices.Equal(texts, tt.wantBlocks) {
+				t.Errorf("updates = %q holding %q, want %q holding %q", headings, texts, tt.wantHeadings, tt.wantBlocks)
+			}
+		})
+	}
+
+	// The page lost the heading the changed section sits under
+	_, err := planGitDiffSync("--- a/doc.md\n+++ b/doc.md\n@@ -7 +7 @@\n-x\n+First.\n", source, titleBlock, blocks, live[2:])
+	if err == nil || !strings.Contains(err.Error(), "has no heading 'One'") {
+		t.Errorf("err = %v, want the missing heading reported", err)
+	}
+}
@@ -0,0 +1,67 @@
+package notionsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// PageMetadata is the metadata stored in the code block
+type PageMetadata struct {
+	ContentHash string `json:"content_hash"`
+}
+
+// Regular expression to find a content hash stored in an HTML comment: <!-- content_hash:abc123 -->
+var hashCommentRegex = regexp.MustCompile(`^<!--\s*content_hash:([0-9a-fA-F]+)\s*-->$`)
+
+// HashStorageModes are the supported places to keep the content hash
+var HashStorageModes = []string{"property", "code", "comment"}
+
+// newHashBlock builds the metadata block holding the content hash for the given storage mode
+func newHashBlock(storage, hash string) (notion.Block, error) {
+	switch storage {
+	case "comment":
+		return notion.ParagraphBlock{
+			RichText: plainRichText(fmt.Sprintf("<!-- content_hash:%s -->", hash)),
+		}, nil
+	case "code":
+		data, err := json.Marshal(PageMetadata{ContentHash: hash})
+		if err != nil {
+			return nil, err
+		}
+		language := "json"
+		return notion.CodeBlock{
+			RichText: plainRichText(string(data)),
+			Language: &language,
+		}, nil
+	}
+	return nil, fmt.Errorf("hash storage '%s' does not use a metadata block", storage)
+}
+
+// readHashBlock returns the content hash held by block if it is a metadata block for the given storage mode
+func readHashBlock(storage string, block notion.Block) (string, bool) {
+	switch storage {
+	case "comment":
+		if _, ok := block.(*notion.ParagraphBlock); !ok {
+			return "", false
+		}
+		match := hashCommentRegex.FindStringSubmatch(strings.TrimSpace(richTextPlainText(blockRichText(block))))
+		if match == nil {
+			return "", false
+		}
+		return match[1], true
+	case "code":
+		if _, ok := block.(*notion.CodeBlock); !ok {
+			return "", false
+		}
+		var metadata PageMetadata
+		if err := json.Unmarshal([]byte(richTextPlainText(blockRichText(block))), &metadata); err != nil || metadata.ContentHash == "" {
+			return "", false
+		}
+		return metadata.ContentHash, true
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+package notionsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestHashBlockRoundTrip(t *testing.T) {
+	for _, storage := range []string{"comment", "code"} {
+		t.Run(storage, func(t *testing.T) {
+			block, err := newHashBlock(storage, "abc123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash, ok := readHashBlock(storage, withID(block, "hash-block"))
+			if !ok || hash != "abc123" {
+				t.Errorf("readHashBlock = %q, %v, want abc123, true", hash, ok)
+			}
+		})
+	}
+}
+
+func TestReadHashBlockIgnoresOtherBlocks(t *testing.T) {
+	comment, err := newHashBlock("comment", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		storage string
+		block   notion.Block
+	}{
+		{"ordinary paragraph", "comment", notion.ParagraphBlock{RichText: plainRichText("content_hash:abc123")}},
+		{"other html comment", "comment", notion.ParagraphBlock{RichText: plainRichText("<!-- draft -->")}},
+		{"comment block read as code", "code", comment},
+		{"property storage", "property", comment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hash, ok := readHashBlock(tt.storage, withID(tt.block, "id")); ok {
+				t.Errorf("readHashBlock = %q, want no hash", hash)
+			}
+		})
+	}
+}
+
+func TestGetStoredHashFromComment(t *testing.T) {
+	c, rt := newRecordingClient()
+	rt.body = `{"object": "list", "has_more": false, "results": [
+		{"object": "block", "id": "b1", "type": "paragraph", "paragraph": {"rich_text": [{"type": "text", "text": {"content": "Intro"}, "plain_text": "Intro"}]}},
+		{"object": "block", "id": "b2", "type": "paragraph", "paragraph": {"rich_text": [{"type": "text", "text": {"content": "<!-- content_hash:abc123 -->"}, "plain_text": "<!-- content_hash:abc123 -->"}]}}
+	]}`
+	hash, err := c.GetStoredHash(context.Background(), "page", "comment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "abc123" {
+		t.Errorf("hash = %q, want abc123", hash)
+	}
+}
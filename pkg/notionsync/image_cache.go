@@ -0,0 +1,129 @@
+package notionsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// ImageCache keeps downloaded remote images on disk keyed by URL. Cached files are
+// revalidated with the server's ETag / Last-Modified so unchanged images aren't downloaded again.
+type ImageCache struct {
+	Dir    string
+	Client *http.Client
+	// mu makes concurrent fetches take turns, so two never write the same cached file
+	mu      sync.Mutex
+	entries map[string]imageCacheEntry
+}
+
+// imageCacheEntry is the index record for one cached URL
+type imageCacheEntry struct {
+	File         string `json:"file"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+const imageCacheIndex = "index.json"
+
+// NewImageCache opens (creating if needed) the cache in dir
+func NewImageCache(ctx context.Context, dir string) (*ImageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	cache := &ImageCache{
+		Dir:     dir,
+		Client:  &http.Client{},
+		entries: make(map[string]imageCacheEntry),
+	}
+	data, err := os.ReadFile(filepath.Join(dir, imageCacheIndex))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read cache index: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		debugf(ctx, "[DEBUG] Ignoring unreadable image cache index: %s\n", err)
+	}
+	return cache, nil
+}
+
+// Fetch returns the local path and SHA-256 of the image at url, downloading it only
+// when it isn't cached yet or the server reports it changed
+func (c *ImageCache) Fetch(ctx context.Context, url string) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, cached := c.entries[url]
+	if cached {
+		if _, err := os.Stat(filepath.Join(c.Dir, entry.File)); err != nil {
+			cached = false
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if cached {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if cached && resp.StatusCode == http.StatusNotModified {
+		debugf(ctx, "[DEBUG] Image cache hit for %s\n", url)
+		return filepath.Join(c.Dir, entry.File), entry.SHA256, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("download error %d for %s", resp.StatusCode, url)
+	}
+
+	debugf(ctx, "[DEBUG] Image cache miss for %s, downloading\n", url)
+	urlHash := sha256.Sum256([]byte(url))
+	entry = imageCacheEntry{
+		File:         fmt.Sprintf("%x%s", urlHash[:8], path.Ext(req.URL.Path)),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	file, err := os.Create(filepath.Join(c.Dir, entry.File))
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hasher), resp.Body); err != nil {
+		return "", "", err
+	}
+	entry.SHA256 = fmt.Sprintf("%x", hasher.Sum(nil))
+
+	c.entries[url] = entry
+	if err := c.save(); err != nil {
+		return "", "", err
+	}
+	return filepath.Join(c.Dir, entry.File), entry.SHA256, nil
+}
+
+// save writes the cache index to disk
+func (c *ImageCache) save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.Dir, imageCacheIndex), data, 0o644)
+}
@@ -0,0 +1,94 @@
+package notionsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestImageCacheFetch(t *testing.T) {
+	etag, body := `"v1"`, "png data v1"
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	ctx := NewContext(context.Background(), testOptions())
+	dir := t.TempDir()
+	url := server.URL + "/chart.png"
+	fetch := func() string {
+		t.Helper()
+		// Reopen the cache each time, like separate runs of the CLI
+		cache, err := NewImageCache(ctx, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, hash, err := cache.Fetch(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("%x", sha256.Sum256(data)); hash != want {
+			t.Errorf("hash = %s, want the SHA-256 of the cached file %s", hash, want)
+		}
+		return string(data)
+	}
+
+	if got := fetch(); got != body || downloads != 1 {
+		t.Fatalf("first fetch got %q after %d downloads, want %q after 1", got, downloads, body)
+	}
+	if got := fetch(); got != body || downloads != 1 {
+		t.Errorf("cache hit got %q after %d downloads, want %q without downloading again", got, downloads, body)
+	}
+	etag, body = `"v2"`, "png data v2"
+	if got := fetch(); got != body || downloads != 2 {
+		t.Errorf("changed image got %q after %d downloads, want %q downloaded again", got, downloads, body)
+	}
+}
+
+func TestImageCacheRefetchesMissingFile(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "png data")
+	}))
+	defer server.Close()
+
+	ctx := NewContext(context.Background(), testOptions())
+	cache, err := NewImageCache(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _, err := cache.Fetch(ctx, server.URL+"/chart.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cache.Fetch(ctx, server.URL+"/chart.png"); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 2 {
+		t.Errorf("downloaded %d times, want the deleted file downloaded again", downloads)
+	}
+}
@@ -0,0 +1,69 @@
+package notionsync
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// sizedImage is an image block sent with its display size in the block's format. Notion's
+// public API doesn't document image sizing, so Fallback (the same image with the size in
+// its caption) is sent instead when the API rejects it.
+type sizedImage struct {
+	notion.Block
+	Fallback notion.Block
+	Width    int
+	Height   int
+}
+
+// newSizedImage builds a sized image from the image without and with the caption size fallback
+func newSizedImage(image, fallback notion.Block, width, height int) sizedImage {
+	return sizedImage{Block: image, Fallback: fallback, Width: width, Height: height}
+}
+
+func (s sizedImage) MarshalJSON() ([]byte, error) {
+	base, err := structToMap(s.Block)
+	if err != nil {
+		return nil, err
+	}
+	format := map[string]int{}
+	if s.Width > 0 {
+		format["block_width"] = s.Width
+	}
+	if s.Height > 0 {
+		format["block_height"] = s.Height
+	}
+	base["format"] = format
+	return json.Marshal(base)
+}
+
+// isSizingRejected reports whether a Notion API error body rejects the image sizing format
+func isSizingRejected(body string) bool {
+	return strings.Contains(body, "validation_error") && strings.Contains(body, "format")
+}
+
+// hasSizedImages reports whether blocks or their children contain a sized image
+func hasSizedImages(blocks []notion.Block) bool {
+	for _, block := range blocks {
+		if _, ok := block.(sizedImage); ok || hasSizedImages(blockChildren(block)) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutSizedImages replaces every sized image with its caption based fallback
+func withoutSizedImages(blocks []notion.Block) []notion.Block {
+	result := make([]notion.Block, len(blocks))
+	for i, block := range blocks {
+		if sized, ok := block.(sizedImage); ok {
+			block = sized.Fallback
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, withoutSizedImages(children))
+		}
+		result[i] = block
+	}
+	return result
+}
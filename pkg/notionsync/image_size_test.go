@@ -0,0 +1,81 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestProcessImageBlocksNativeSize(t *testing.T) {
+	const markdown = "![Chart](https://example.com/chart.png?width=400&height=300)\n"
+	tests := []struct {
+		name        string
+		nativeSize  bool
+		wantFormat  string
+		wantCaption string
+	}{
+		{"native size", true, `{"block_height":300,"block_width":400}`, "Chart"},
+		{"caption size", false, "", "Chart (width: 400px, height: 300px)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := processImages(t, newFakeNotionClient(), writeMarkdown(t, ""), markdown, ImageOptions{NativeSize: tt.nativeSize})
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one image", blockTypes(blocks))
+			}
+			data, err := json.Marshal(blocks[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sent struct {
+				Format json.RawMessage   `json:"format"`
+				Image  notion.ImageBlock `json:"image"`
+			}
+			if err := json.Unmarshal(data, &sent); err != nil {
+				t.Fatal(err)
+			}
+			if string(sent.Format) != tt.wantFormat {
+				t.Errorf("format = %s, want %q", sent.Format, tt.wantFormat)
+			}
+			if got := richTextPlainText(sent.Image.Caption); got != tt.wantCaption {
+				t.Errorf("caption = %q, want %q", got, tt.wantCaption)
+			}
+			if sent.Image.External == nil || sent.Image.External.URL != "https://example.com/chart.png" {
+				t.Errorf("image = %+v, want the URL without the size parameters", sent.Image.External)
+			}
+		})
+	}
+}
+
+func TestAddPageContentFallsBackToCaptionSize(t *testing.T) {
+	var bodies []string
+	accept := appendChildrenAPI()
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		if strings.Contains(string(data), `"format"`) {
+			body := `{"object": "error", "status": 400, "code": "validation_error", "message": "body.children[0].format should be not present"}`
+			return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+		}
+		req.Body = io.NopCloser(strings.NewReader(string(data)))
+		return accept(req)
+	})}
+
+	blocks := processImages(t, newFakeNotionClient(), writeMarkdown(t, ""), "![Chart](https://example.com/chart.png?width=400)\n", ImageOptions{NativeSize: true})
+	ids, err := c.AddPageContent(NewContext(context.Background(), testOptions()), "page", blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || len(bodies) != 2 {
+		t.Fatalf("got %d IDs after %d requests, want 1 after a rejected and an accepted one", len(ids), len(bodies))
+	}
+	if !strings.Contains(bodies[1], "(width: 400px)") {
+		t.Errorf("retry = %s, want the size in the caption", bodies[1])
+	}
+}
@@ -0,0 +1,668 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find Markdown image references: ![alt text](path/to/image.jpg)
+var markdownImageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
+
+// Regular expression to split the title off a markdown image destination: path "title"
+var imageTitleRegex = regexp.MustCompile(`^(\S+)\s+(?:"([^"]*)"|'([^']*)')$`)
+
+// Regular expression to find HTML img tags: <img src="path/to/image.jpg" alt="alt text" width="500" height="300">
+var htmlImageRegex = regexp.MustCompile(`(?i)<img((?:\s+[^\s"'>/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*/?>`)
+
+// Regular expression to find an attribute of an HTML tag: name="value", name='value', name=value or name
+var htmlAttributeRegex = regexp.MustCompile(`\s([A-Za-z_:][\w:.-]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+
+// ImageReference represents an image reference in a Markdown document
+type ImageReference struct {
+	AltText string
+	Title   string // Optional title: ![alt](path "title")
+	Path    string
+	IsLocal bool
+	Width   int // Optional width from URL parameters
+	Height  int // Optional height from URL parameters
+}
+
+// ImageOptions controls how image references are turned into image blocks
+type ImageOptions struct {
+	// Cache memoizes remote image downloads between runs, nil disables caching
+	Cache *ImageCache
+	// PathRewrites maps image path fragments to their replacement, applied to image references only
+	PathRewrites map[string]string
+	// NativeSize sends width/height as the image block's display size instead of caption text.
+	// Notion's public API may reject it, AddPageContent then falls back to the caption.
+	NativeSize bool
+	// ContinueOnError replaces images that fail to process with a link instead of aborting
+	ContinueOnError bool
+	// CaptionSource picks the caption text from the alt text and title, see ImageCaptionSources
+	CaptionSource string
+	// CaptionPosition places the caption in the image block (caption) or in a separate
+	// paragraph above or below it, see CaptionPositions
+	CaptionPosition string
+	// CaptionFormat formats the width and height appended to image captions, nil uses the default
+	CaptionFormat *template.Template
+	// NoDimensionCaption leaves the width and height out of captions
+	NoDimensionCaption bool
+	// VideoEmbeds turns images pointing at YouTube/Vimeo videos or their thumbnails into video embeds
+	VideoEmbeds bool
+	// UploadRemote downloads remote images and uploads them to Notion instead of linking them
+	UploadRemote bool
+	// HTTPClient downloads remote images, nil uses the default client
+	HTTPClient *http.Client
+	// UploadConcurrency is how many images are processed at the same time, 1 or less one by one
+	UploadConcurrency int
+}
+
+type FileUpload struct {
+	ID string `json:"id"`
+}
+
+type ImageBlock struct {
+	notion.ImageBlock
+	FileUpload FileUpload `json:"file_upload,omitempty"`
+}
+
+func (b ImageBlock) MarshalJSON() ([]byte, error) {
+	base, err := structToMap(b.ImageBlock)
+	if err != nil {
+		return nil, err
+	}
+	if img, ok := base["image"].(map[string]interface{}); ok {
+		img["file_upload"] = b.FileUpload
+	}
+	return json.Marshal(base)
+}
+
+func structToMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FindImageReferences scans content for image references (both Markdown and HTML)
+func FindImageReferences(content string) []ImageReference {
+	refs := make([]ImageReference, 0)
+
+	// Find Markdown image references
+	mdMatches := markdownImageRegex.FindAllStringSubmatch(content, -1)
+	for _, match := range mdMatches {
+		if len(match) >= 3 {
+			altText := match[1]
+			origPath, title := strings.TrimSpace(match[2]), ""
+			if titleMatch := imageTitleRegex.FindStringSubmatch(origPath); titleMatch != nil {
+				origPath, title = titleMatch[1], titleMatch[2]+titleMatch[3]
+			}
+
+			// Parse the path to extract any width/height parameters
+			path, width, height := parseImagePath(origPath)
+			isLocal := !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://")
+
+			refs = append(refs, ImageReference{
+				AltText: altText,
+				Title:   title,
+				Path:    path,
+				IsLocal: isLocal,
+				Width:   width,
+				Height:  height,
+			})
+		}
+	}
+
+	// Find HTML img tags, their attributes may come in any order
+	for _, match := range htmlImageRegex.FindAllStringSubmatch(content, -1) {
+		attrs := htmlAttributes(match[1])
+		src := attrs["src"]
+		if src == "" {
+			continue
+		}
+		width, height := htmlDimension(attrs["width"]), htmlDimension(attrs["height"])
+
+		// Check for URL parameters in src that might also specify dimensions
+		// This allows for both <img src="image.jpg?width=500&height=300"> and <img src="image.jpg" width="500" height="300">
+		srcPath, srcWidth, srcHeight := parseImagePath(src)
+
+		// Use explicit width/height attributes if available, otherwise use URL parameters
+		if width == 0 {
+			width = srcWidth
+		}
+		if height == 0 {
+			height = srcHeight
+		}
+
+		isLocal := !strings.HasPrefix(srcPath, "http://") && !strings.HasPrefix(srcPath, "https://")
+
+		refs = append(refs, ImageReference{
+			AltText: attrs["alt"],
+			Title:   attrs["title"],
+			Path:    srcPath,
+			IsLocal: isLocal,
+			Width:   width,
+			Height:  height,
+		})
+	}
+
+	return refs
+}
+
+// htmlAttributes parses the attributes of an HTML tag, keyed by lower case name with entities
+// in the values decoded. Attributes without a value map to "", the first of repeated ones wins.
+func htmlAttributes(attributes string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttributeRegex.FindAllStringSubmatch(attributes, -1) {
+		name := strings.ToLower(match[1])
+		if _, seen := attrs[name]; !seen {
+			attrs[name] = html.UnescapeString(match[2] + match[3] + match[4])
+		}
+	}
+	return attrs
+}
+
+// htmlDimension parses an img width or height given in pixels ("500" or "500px"), returning 0
+// for anything else such as percentages
+func htmlDimension(value string) int {
+	pixels, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil || pixels < 0 {
+		return 0
+	}
+	return pixels
+}
+
+// ImageCaptionSources are the accepted --image-caption values: the title if the image has one,
+// otherwise the alt text (title), only the alt text (alt) or both joined by a dash (both)
+var ImageCaptionSources = []string{"title", "alt", "both"}
+
+// Caption returns the caption text of the image for the given caption source
+func (ref ImageReference) Caption(source string) string {
+	switch {
+	case source == "alt" || ref.Title == "":
+		return ref.AltText
+	case source == "both" && ref.AltText != "" && ref.AltText != ref.Title:
+		return ref.AltText + " — " + ref.Title
+	}
+	return ref.Title
+}
+
+// CaptionPositions are the accepted --caption-position values: in the image block's own
+// caption, or as a separate paragraph above or below the image
+var CaptionPositions = []string{"caption", "above", "below"}
+
+// captions returns the caption of the image block for ref, the caption of its natively sized
+// variant and, when the caption is placed outside the image, the text of the caption paragraph.
+// Dimensions stay in the image block's caption only when they are also sent as its native size,
+// and never with NoDimensionCaption.
+func (opts ImageOptions) captions(ctx context.Context, ref ImageReference) (caption, sizedCaption, paragraph []notion.RichText) {
+	text := ref.Caption(opts.CaptionSource)
+	if opts.NoDimensionCaption {
+		ref.Width, ref.Height = 0, 0
+	}
+	if opts.CaptionPosition == "above" || opts.CaptionPosition == "below" {
+		if opts.NativeSize && (ref.Width > 0 || ref.Height > 0) {
+			return imageCaption(ctx, "", ref.Width, ref.Height, opts.CaptionFormat), imageCaption(ctx, "", 0, 0, nil), imageCaption(ctx, text, 0, 0, nil)
+		}
+		return imageCaption(ctx, "", 0, 0, nil), imageCaption(ctx, "", 0, 0, nil), imageCaption(ctx, text, ref.Width, ref.Height, opts.CaptionFormat)
+	}
+	return imageCaption(ctx, text, ref.Width, ref.Height, opts.CaptionFormat), imageCaption(ctx, text, 0, 0, nil), nil
+}
+
+// withCaptionParagraph returns imageBlock with the caption paragraph placed above or below it
+func withCaptionParagraph(imageBlock notion.Block, paragraph []notion.RichText, position string) []notion.Block {
+	if len(paragraph) == 0 {
+		return []notion.Block{imageBlock}
+	}
+	captionBlock := &notion.ParagraphBlock{RichText: paragraph}
+	if position == "above" {
+		return []notion.Block{captionBlock, imageBlock}
+	}
+	return []notion.Block{imageBlock, captionBlock}
+}
+
+// parseImagePath extracts width and height parameters from image URLs
+// Returns the cleaned path (without dimension parameters), width, and height
+func parseImagePath(path string) (string, int, int) {
+	// Check if the path contains URL parameters
+	queryIndex := strings.IndexAny(path, "?")
+	if queryIndex == -1 {
+		return path, 0, 0 // No parameters found
+	}
+
+	// Split the path into base and query parts
+	basePath := path[:queryIndex]
+	queryPart := path[queryIndex+1:]
+
+	// Parse the query parameters
+	params := strings.Split(queryPart, "&")
+	width, height := 0, 0
+	keepParams := []string{}
+
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 {
+			keepParams = append(keepParams, param)
+			continue
+		}
+
+		key, value := parts[0], parts[1]
+		switch key {
+		case "width":
+			if w, err := strconv.Atoi(value); err == nil {
+				width = w
+			} else {
+				keepParams = append(keepParams, param)
+			}
+		case "height":
+			if h, err := strconv.Atoi(value); err == nil {
+				height = h
+			} else {
+				keepParams = append(keepParams, param)
+			}
+		default:
+			keepParams = append(keepParams, param)
+		}
+	}
+
+	// Reconstruct the path without width/height parameters
+	if len(keepParams) > 0 {
+		basePath += "?" + strings.Join(keepParams, "&")
+	}
+
+	return basePath, width, height
+}
+
+// ProcessImageBlocks processes Notion blocks and replaces image references with actual image blocks
+// basePath is the path to the markdown file, used to resolve relative image paths
+// The images are processed by up to opts.UploadConcurrency workers at a time, then put back in
+// the order of the blocks. The error of the first failed image in block order is returned.
+func ProcessImageBlocks(ctx context.Context, blocks []notion.Block, basePath string, notionClient NotionClientInterface, opts ImageOptions) ([]notion.Block, error) {
+	jobs := collectImageJobs(blocks, nil)
+	runImageJobs(jobs, opts.UploadConcurrency, !opts.ContinueOnError, func(job *imageJob) {
+		job.run(ctx, basePath, notionClient, opts)
+	})
+	if !opts.ContinueOnError {
+		for _, job := range jobs {
+			if job.err != nil {
+				return nil, job.err
+			}
+		}
+	}
+	return spliceImageJobs(ctx, blocks, &jobs), nil
+}
+
+// imageJob is the processing of one block that may be an image: a paragraph that may hold an
+// image reference, or an inline SVG
+type imageJob struct {
+	block    notion.Block
+	blocks   []notion.Block
+	replaced bool
+	err      error
+}
+
+// collectImageJobs appends the image jobs of blocks and their children to jobs, in block order.
+// Paragraphs are jobs of their own, images nested in callouts, quotes, toggles and list items are
+// collected from their children.
+func collectImageJobs(blocks []notion.Block, jobs []*imageJob) []*imageJob {
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case inlineSVGBlock:
+			jobs = append(jobs, &imageJob{block: b})
+			continue
+		case *notion.ParagraphBlock:
+			if b != nil {
+				jobs = append(jobs, &imageJob{block: b})
+				continue
+			}
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			jobs = collectImageJobs(children, jobs)
+		}
+	}
+	return jobs
+}
+
+// run processes the job's block
+func (job *imageJob) run(ctx context.Context, basePath string, notionClient NotionClientInterface, opts ImageOptions) {
+	switch b := job.block.(type) {
+	case inlineSVGBlock:
+		// Inline SVG is uploaded as an image, falling back to showing its source
+		job.blocks, job.replaced = []notion.Block{processInlineSVG(ctx, b, notionClient)}, true
+	case *notion.ParagraphBlock:
+		job.blocks, job.replaced, job.err = processImageInParagraph(ctx, b, basePath, notionClient, opts)
+	}
+}
+
+// runImageJobs runs the jobs on up to concurrency workers. With stopOnError no new job starts
+// once one has failed. A concurrency of 1 runs them one after the other in order.
+func runImageJobs(jobs []*imageJob, concurrency int, stopOnError bool, run func(*imageJob)) {
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+	)
+	slots := make(chan struct{}, max(concurrency, 1))
+	for _, job := range jobs {
+		slots <- struct{}{}
+		if failed.Load() {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run(job)
+			if job.err != nil && stopOnError {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// spliceImageJobs puts the results of the jobs collected from blocks back in their place,
+// taking them off the front of jobs. Failed images become links to them.
+func spliceImageJobs(ctx context.Context, blocks []notion.Block, jobs *[]*imageJob) []notion.Block {
+	result := make([]notion.Block, 0, len(blocks))
+	for _, block := range blocks {
+		_, isSVG := block.(inlineSVGBlock)
+		if paragraphBlock, ok := block.(*notion.ParagraphBlock); isSVG || (ok && paragraphBlock != nil) {
+			job := (*jobs)[0]
+			*jobs = (*jobs)[1:]
+			if job.err != nil {
+				warnf(ctx, "Image failed, linking to it instead: %s\n", job.err)
+				result = append(result, newFailedImageBlock(paragraphBlock, job.err))
+				continue
+			}
+			result = append(result, job.blocks...)
+			continue
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, spliceImageJobs(ctx, children, jobs))
+		}
+		result = append(result, block)
+	}
+	return result
+}
+
+// failedImageBlock stands in for an image that failed to process with --continue-on-image-error.
+// It marshals as a paragraph linking to the image, or naming it if it is a local file.
+type failedImageBlock struct {
+	notion.ParagraphBlock
+	Path string
+	Err  error
+}
+
+// newFailedImageBlock builds the stand-in for the image referenced in paragraphBlock
+func newFailedImageBlock(paragraphBlock *notion.ParagraphBlock, err error) failedImageBlock {
+	ref := FindImageReferences(richTextPlainText(paragraphBlock.RichText))[0]
+	text := ref.AltText
+	if text == "" {
+		text = ref.Path
+	}
+	richText := plainRichText("🖼️ " + text)
+	if !ref.IsLocal {
+		richText[0].Text.Link = &notion.Link{URL: ref.Path}
+	} else if text != ref.Path {
+		richText[0].Text.Content += " (" + ref.Path + ")"
+		richText[0].PlainText = richText[0].Text.Content
+	}
+	return failedImageBlock{
+		ParagraphBlock: notion.ParagraphBlock{RichText: richText},
+		Path:           ref.Path,
+		Err:            err,
+	}
+}
+
+// failedImages returns the stand-ins for failed images among blocks and their children
+func failedImages(blocks []notion.Block) []failedImageBlock {
+	var failed []failedImageBlock
+	for _, block := range blocks {
+		if f, ok := block.(failedImageBlock); ok {
+			failed = append(failed, f)
+		}
+		failed = append(failed, failedImages(blockChildren(block))...)
+	}
+	return failed
+}
+
+// processImageInParagraph checks if a paragraph block contains an image reference and processes it
+// Returns the processed blocks, a boolean indicating if the paragraph was replaced, and any error
+func processImageInParagraph(ctx context.Context, paragraphBlock *notion.ParagraphBlock, basePath string, notionClient NotionClientInterface, opts ImageOptions) ([]notion.Block, bool, error) {
+	// Extract text content from the paragraph
+	var fullText string
+	for _, richText := range paragraphBlock.RichText {
+		if richText.Text != nil {
+			fullText += richText.Text.Content
+		}
+	}
+
+	// Check if the paragraph contains an image reference
+	imageRefs := FindImageReferences(fullText)
+	if len(imageRefs) == 0 {
+		// No image references found, return the original paragraph
+		return []notion.Block{paragraphBlock}, false, nil
+	}
+
+	// Process the first image reference (typically there should only be one per paragraph)
+	ref := imageRefs[0]
+	if isDataURI(ref.Path) {
+		return processDataURIImage(ctx, ref, notionClient, opts)
+	}
+	if len(opts.PathRewrites) > 0 {
+		ref.Path = rewriteImagePath(ctx, ref.Path, opts.PathRewrites)
+		ref.IsLocal = !strings.HasPrefix(ref.Path, "http://") && !strings.HasPrefix(ref.Path, "https://")
+	}
+
+	// Create the appropriate image block
+	var imageBlock notion.Block
+	caption, sizedCaption, captionParagraph := opts.captions(ctx, ref)
+
+	if ref.IsLocal {
+		// Process local image
+		imagePath := ref.Path
+		if !filepath.IsAbs(imagePath) {
+			imagePath = filepath.Join(filepath.Dir(basePath), imagePath)
+		}
+
+		// Check if file exists
+		if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+			return nil, false, fmt.Errorf("local image file not found: %s", imagePath)
+		}
+
+		// Create image block from local file with dimensions
+		fileUploadID, err := notionClient.UploadFile(ctx, imagePath)
+		if err != nil {
+			return nil, false, err
+		}
+		imageBlock = createImageBlockWithFileUpload(fileUploadID, caption)
+		if opts.NativeSize && (ref.Width > 0 || ref.Height > 0) {
+			imageBlock = newSizedImage(createImageBlockWithFileUpload(fileUploadID, sizedCaption), imageBlock, ref.Width, ref.Height)
+		}
+	} else if videoURL, ok := videoEmbedURL(ref.Path); ok && opts.VideoEmbeds {
+		// Video thumbnails embed the video itself
+		imageBlock = createVideoBlock(videoURL, richTextPlainText(sizedCaption))
+	} else {
+		// Process external image URL with dimensions, uploading it if asked to
+		var fileUploadID string
+		if opts.UploadRemote {
+			var err error
+			if fileUploadID, err = uploadRemoteImage(ctx, ref.Path, notionClient, opts); err != nil {
+				return nil, false, err
+			}
+		}
+		if fileUploadID != "" {
+			imageBlock = createImageBlockWithFileUpload(fileUploadID, caption)
+			if opts.NativeSize && (ref.Width > 0 || ref.Height > 0) {
+				imageBlock = newSizedImage(createImageBlockWithFileUpload(fileUploadID, sizedCaption), imageBlock, ref.Width, ref.Height)
+			}
+		} else {
+			imageBlock = createImageBlockFromURL(ref.Path, caption)
+			if opts.NativeSize && (ref.Width > 0 || ref.Height > 0) {
+				imageBlock = newSizedImage(createImageBlockFromURL(ref.Path, sizedCaption), imageBlock, ref.Width, ref.Height)
+			}
+		}
+	}
+
+	// Return the image block, indicating the paragraph was replaced
+	return withCaptionParagraph(imageBlock, captionParagraph, opts.CaptionPosition), true, nil
+}
+
+// processDataURIImage uploads an image embedded as a data URI. Types Notion can't show are
+// dropped with a warning, leaving their alt text if they have any.
+func processDataURIImage(ctx context.Context, ref ImageReference, notionClient NotionClientInterface, opts ImageOptions) ([]notion.Block, bool, error) {
+	imagePath, err := writeDataURIImage(ref.Path)
+	if errors.Is(err, errUnsupportedDataURI) {
+		warnf(ctx, "Skipping inline image: %s\n", err)
+		if ref.AltText == "" {
+			return nil, true, nil
+		}
+		return []notion.Block{&notion.ParagraphBlock{RichText: plainRichText(ref.AltText)}}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(imagePath)
+
+	fileUploadID, err := notionClient.UploadFile(ctx, imagePath)
+	if err != nil {
+		return nil, false, err
+	}
+	caption, _, captionParagraph := opts.captions(ctx, ref)
+	return withCaptionParagraph(createImageBlockWithFileUpload(fileUploadID, caption), captionParagraph, opts.CaptionPosition), true, nil
+}
+
+// rewriteImagePath replaces every occurrence of a mapping key in path. Longer keys are
+// applied first so a specific rewrite wins over a more general one.
+func rewriteImagePath(ctx context.Context, path string, rewrites map[string]string) string {
+	original := path
+	path = rewriteReplacer(rewrites).Replace(path)
+	if path != original {
+		debugf(ctx, "[DEBUG] Rewrote image path '%s' -> '%s'\n", original, path)
+	}
+	return path
+}
+
+// LoadImageRewrites reads a JSON object mapping image path fragments to their replacement
+func LoadImageRewrites(ctx context.Context, path string) (map[string]string, error) {
+	data, err := readInputFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading rewrite-images mapping file: %w", err)
+	}
+	var rewrites map[string]string
+	if err := json.Unmarshal(data, &rewrites); err != nil {
+		return nil, fmt.Errorf("Error decoding rewrite-images mapping file: %w", err)
+	}
+	return rewrites, nil
+}
+
+// processInlineSVG uploads an inline SVG through a temporary file and returns its image block,
+// or the code block showing the SVG source if the upload fails
+func processInlineSVG(ctx context.Context, svgBlock inlineSVGBlock, notionClient NotionClientInterface) notion.Block {
+	file, err := os.CreateTemp("", "notionmd-inline-*.svg")
+	if err != nil {
+		warnf(ctx, "Failed to write inline SVG, keeping it as code: %s\n", err)
+		return svgBlock.CodeBlock
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(svgBlock.Source)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		warnf(ctx, "Failed to write inline SVG, keeping it as code: %s\n", err)
+		return svgBlock.CodeBlock
+	}
+
+	fileUploadID, err := notionClient.UploadFile(ctx, file.Name())
+	if err != nil {
+		warnf(ctx, "Failed to upload inline SVG, keeping it as code: %s\n", err)
+		return svgBlock.CodeBlock
+	}
+	return createImageBlockWithFileUpload(fileUploadID, imageCaption(ctx, "", 0, 0, nil))
+}
+
+// DefaultDimensionCaptionFormat is the template appending an image's width and height to its caption
+const DefaultDimensionCaptionFormat = ` ({{if .Width}}width: {{.Width}}px{{end}}{{if and .Width .Height}}, {{end}}{{if .Height}}height: {{.Height}}px{{end}})`
+
+// ParseDimensionCaptionFormat parses a --dimension-caption-format template, trying it on
+// sample dimensions so mistakes such as unknown fields show up before anything is synced
+func ParseDimensionCaptionFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("dimension-caption").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, imageDimensions{Width: 500, Height: 300}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// imageDimensions is the data a dimension caption template is executed with
+type imageDimensions struct {
+	Width  int
+	Height int
+}
+
+// imageCaption builds an image caption from its alt text followed by its dimensions, if it
+// has any, formatted with the given template (the default format if nil)
+func imageCaption(ctx context.Context, altText string, width, height int, format *template.Template) []notion.RichText {
+	caption := []notion.RichText{}
+	if altText != "" {
+		caption = append(caption, plainRichText(altText)...)
+	}
+	if width > 0 || height > 0 {
+		if format == nil {
+			format = template.Must(template.New("dimension-caption").Parse(DefaultDimensionCaptionFormat))
+		}
+		var dimensionInfo strings.Builder
+		if err := format.Execute(&dimensionInfo, imageDimensions{Width: width, Height: height}); err != nil {
+			warnf(ctx, "Failed to format image dimensions: %s\n", err)
+		} else if dimensionInfo.Len() > 0 {
+			caption = append(caption, plainRichText(dimensionInfo.String())...)
+		}
+	}
+	return caption
+}
+
+// createImageBlockWithFileUpload creates a Notion image block using a file upload ID
+func createImageBlockWithFileUpload(fileUploadID string, caption []notion.RichText) ImageBlock {
+	// Create an image block referencing the uploaded file (Notion API expects type: "file" and a file object with id)
+	return ImageBlock{
+		ImageBlock: notion.ImageBlock{
+			Type:    "file_upload",
+			Caption: caption,
+		},
+		FileUpload: FileUpload{
+			ID: fileUploadID,
+		},
+	}
+}
+
+// createImageBlockFromURL creates a Notion image block from a URL
+func createImageBlockFromURL(url string, caption []notion.RichText) notion.Block {
+	return &notion.ImageBlock{
+		Caption:  caption,
+		External: &notion.FileExternal{URL: url},
+	}
+}
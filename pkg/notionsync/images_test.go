@@ -0,0 +1,621 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// writeImage writes a small PNG named name next to the markdown file mdPath
+func writeImage(t *testing.T, mdPath, name string) {
+	t.Helper()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := os.WriteFile(filepath.Join(filepath.Dir(mdPath), name), png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncFileSkipImages(t *testing.T) {
+	const markdown = "# Title\n\n![Chart](chart.png)\n\n![Missing](missing.png)\n"
+	mdPath := writeMarkdown(t, markdown)
+	writeImage(t, mdPath, "chart.png")
+
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.SkipImages = true
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.uploads) != 0 {
+		t.Errorf("uploaded %v, want no uploads", client.uploads)
+	}
+	var texts []string
+	for _, block := range client.content["page"] {
+		texts = append(texts, ownText(block))
+	}
+	if want := []string{"![Chart](chart.png)", "![Missing](missing.png)"}; !slices.Equal(texts, want) {
+		t.Errorf("content = %q, want the image markdown kept as text %q", texts, want)
+	}
+
+	mdPath = writeMarkdown(t, "# Title\n\n![Chart](chart.png)\n")
+	writeImage(t, mdPath, "chart.png")
+	client = newFakeNotionClient()
+	opts.SkipImages = false
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.uploads) != 1 {
+		t.Errorf("uploaded %v without skipping images, want the chart", client.uploads)
+	}
+}
+
+// failingUploadClient is a fakeNotionClient whose uploads of files matching fail don't succeed
+type failingUploadClient struct {
+	*fakeNotionClient
+	fail func(filePath string) bool
+}
+
+func (c *failingUploadClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	if c.fail(filePath) {
+		c.record("UploadFile %s", filePath)
+		return "", fmt.Errorf("upload of %s failed", filepath.Base(filePath))
+	}
+	return c.fakeNotionClient.UploadFile(ctx, filePath)
+}
+
+func TestSyncFileInlineSVG(t *testing.T) {
+	const svg = `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><circle cx="5" cy="5" r="4"/></svg>`
+	markdown := "# Title\n\nBefore.\n\n" + svg + "\n\nAfter.\n"
+	tests := []struct {
+		name      string
+		fail      bool
+		wantTypes []string
+	}{
+		{"uploaded as an image", false, []string{"notion.ParagraphBlock", "notion.ImageBlock", "notion.ParagraphBlock"}},
+		{"source kept when the upload fails", true, []string{"notion.ParagraphBlock", "notion.CodeBlock", "notion.ParagraphBlock"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeNotionClient()
+			client := &failingUploadClient{fakeNotionClient: fake, fail: func(string) bool { return tt.fail }}
+			if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			content := fake.content["page"]
+			if got := blockTypes(content); !slices.Equal(got, tt.wantTypes) {
+				t.Fatalf("content = %v, want %v", got, tt.wantTypes)
+			}
+			if tt.fail {
+				if got := ownText(content[1]); got != svg {
+					t.Errorf("code block = %q, want the SVG source", got)
+				}
+				return
+			}
+			if len(fake.uploads) != 1 || filepath.Ext(fake.uploads[0]) != ".svg" {
+				t.Errorf("uploads = %v, want one .svg file", fake.uploads)
+			}
+		})
+	}
+}
+
+// processImages converts markdown read from mdPath and runs ProcessImageBlocks over it
+func processImages(t *testing.T, client NotionClientInterface, mdPath, markdown string, opts ImageOptions) []notion.Block {
+	t.Helper()
+	ctx := NewContext(context.Background(), testOptions())
+	blocks, err := ProcessImageBlocks(ctx, convert(t, markdown), mdPath, client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks
+}
+
+func TestProcessImageBlocksVideoEmbeds(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		videoEmbeds bool
+		wantType    string
+		wantURL     string
+	}{
+		{"youtube thumbnail", "https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg", true, "notion.VideoBlock", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"youtube thumbnail without the option", "https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg", false, "notion.ImageBlock", "https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg"},
+		{"vimeo thumbnail", "https://vumbnail.com/76979871.jpg", true, "notion.VideoBlock", "https://vimeo.com/76979871"},
+		{"ordinary image", "https://example.com/chart.png", true, "notion.ImageBlock", "https://example.com/chart.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := processImages(t, newFakeNotionClient(), writeMarkdown(t, ""), "![Demo]("+tt.url+")\n", ImageOptions{VideoEmbeds: tt.videoEmbeds})
+			if got := blockTypes(blocks); !slices.Equal(got, []string{tt.wantType}) {
+				t.Fatalf("blocks = %v, want a %s", got, tt.wantType)
+			}
+			var url string
+			switch b := blocks[0].(type) {
+			case *notion.VideoBlock:
+				url = b.External.URL
+			case notion.ImageBlock:
+				url = b.External.URL
+			case *notion.ImageBlock:
+				url = b.External.URL
+			}
+			if url != tt.wantURL {
+				t.Errorf("url = %q, want %q", url, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestSyncFileCRLF(t *testing.T) {
+	markdown := strings.ReplaceAll("---\ntags: docs\n---\n# Title\n\nIntro line one\nline two.\n\n![Chart](chart.png)\n\n- first\n- second\n\n```yaml\nkey:\n  nested: value\n```\n", "\n", "\r\n")
+	if refs := FindImageReferences(markdown); len(refs) != 1 || refs[0].Path != "chart.png" || refs[0].AltText != "Chart" {
+		t.Errorf("image references = %+v, want chart.png", refs)
+	}
+
+	mdPath := writeMarkdown(t, markdown)
+	writeImage(t, mdPath, "chart.png")
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), testOptions(), client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if client.titles["page"] != "Title" {
+		t.Errorf("title = %q, want Title", client.titles["page"])
+	}
+	content := client.content["page"]
+	want := []string{"notion.ParagraphBlock", "notion.ImageBlock", "notion.BulletedListItemBlock", "notion.BulletedListItemBlock", "notion.CodeBlock"}
+	if got := blockTypes(content); !slices.Equal(got, want) {
+		t.Fatalf("content = %v, want %v", got, want)
+	}
+	if len(client.uploads) != 1 || filepath.Base(client.uploads[0]) != "chart.png" {
+		t.Errorf("uploads = %v, want chart.png", client.uploads)
+	}
+	for _, block := range content {
+		if text := ownText(block); strings.Contains(text, "\r") {
+			t.Errorf("block text %q keeps a carriage return", text)
+		}
+	}
+	if got := ownText(content[4]); got != "key:\n  nested: value" {
+		t.Errorf("code = %q, want the YAML with LF line endings", got)
+	}
+}
+
+func TestSyncFileImagePathRewrites(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.Images.PathRewrites = map[string]string{"https://old.example.com/": "https://cdn.example.com/"}
+	markdown := "# Title\n\nSee [the docs](https://old.example.com/docs) and https://old.example.com/faq.\n\n![Chart](https://old.example.com/chart.png)\n"
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if got := blockTypes(content); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.ImageBlock"}) {
+		t.Fatalf("content = %v, want the paragraph and the image", got)
+	}
+	if got := content[1].(*notion.ImageBlock).External.URL; got != "https://cdn.example.com/chart.png" {
+		t.Errorf("image = %q, want it rewritten", got)
+	}
+	richText := blockRichText(content[0])
+	if got := richTextPlainText(richText); got != "See the docs and https://old.example.com/faq." {
+		t.Errorf("text = %q, want it untouched", got)
+	}
+	for _, rt := range richText {
+		if rt.Text != nil && rt.Text.Link != nil && !strings.HasPrefix(rt.Text.Link.URL, "https://old.example.com/") {
+			t.Errorf("link = %q, want it untouched", rt.Text.Link.URL)
+		}
+	}
+}
+
+func TestSyncFileContinueOnImageError(t *testing.T) {
+	const markdown = "# Title\n\n![Good](good.png)\n\n![Bad](bad.png)\n\n![](missing.png)\n\nText after.\n"
+	mdPath := writeMarkdown(t, markdown)
+	writeImage(t, mdPath, "good.png")
+	writeImage(t, mdPath, "bad.png")
+	failBad := func(filePath string) bool { return filepath.Base(filePath) == "bad.png" }
+
+	fake := newFakeNotionClient()
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.Images.ContinueOnError = true
+	opts.StatusOutput = &out
+	if err := SyncFile(context.Background(), opts, &failingUploadClient{fake, failBad}, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := fake.content["page"]
+	if got := blockTypes(content); !slices.Equal(got, []string{"notion.ImageBlock", "notion.ParagraphBlock", "notion.ParagraphBlock", "notion.ParagraphBlock"}) {
+		t.Fatalf("content = %v, want the good image, two stand-ins and the text", got)
+	}
+	if got := []string{ownText(content[1]), ownText(content[2])}; !slices.Equal(got, []string{"🖼️ Bad (bad.png)", "🖼️ missing.png"}) {
+		t.Errorf("stand-ins = %q, want the failed images named", got)
+	}
+	if !strings.Contains(out.String(), "2 images could not be processed") {
+		t.Errorf("output doesn't report the failures:\n%s", out.String())
+	}
+
+	fake = newFakeNotionClient()
+	opts.Images.ContinueOnError = false
+	if err := SyncFile(context.Background(), opts, &failingUploadClient{fake, failBad}, mdPath, "page"); err == nil {
+		t.Error("sync succeeded, want the image failure to abort it")
+	}
+	if slices.Contains(fake.callNames(), "AddPageContent") {
+		t.Errorf("calls = %v, want nothing added after the failure", fake.callNames())
+	}
+}
+
+func TestImageCaptionFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		alt    string
+		width  int
+		height int
+		want   string
+	}{
+		{name: "default", format: DefaultDimensionCaptionFormat, alt: "Logo", width: 500, height: 300, want: "Logo (width: 500px, height: 300px)"},
+		{name: "default width only", format: DefaultDimensionCaptionFormat, alt: "Logo", width: 500, want: "Logo (width: 500px)"},
+		{name: "times sign", format: " {{.Width}}×{{.Height}}", alt: "Logo", width: 500, height: 300, want: "Logo 500×300"},
+		{name: "without units", format: " ({{.Width}}, {{.Height}})", width: 500, height: 300, want: " (500, 300)"},
+		{name: "empty template adds nothing", format: "", alt: "Logo", width: 500, height: 300, want: "Logo"},
+		{name: "no dimensions", format: " {{.Width}}×{{.Height}}", alt: "Logo", want: "Logo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ParseDimensionCaptionFormat(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := NewContext(context.Background(), testOptions())
+			if got := richTextPlainText(imageCaption(ctx, tt.alt, tt.width, tt.height, format)); got != tt.want {
+				t.Errorf("caption = %q, want %q", got, tt.want)
+			}
+			// A nil template falls back to the default format
+			if tt.format == DefaultDimensionCaptionFormat {
+				if got := richTextPlainText(imageCaption(ctx, tt.alt, tt.width, tt.height, nil)); got != tt.want {
+					t.Errorf("caption without a template = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDimensionCaptionFormatErrors(t *testing.T) {
+	for _, format := range []string{" {{.Width", " {{.Depth}}"} {
+		if _, err := ParseDimensionCaptionFormat(format); err == nil {
+			t.Errorf("ParseDimensionCaptionFormat(%q) succeeded, want an error", format)
+		}
+	}
+}
+
+func TestFindImageReferencesTitles(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		wantAlt   string
+		wantTitle string
+		wantPath  string
+	}{
+		{name: "title only", markdown: `![](chart.png "Quarterly sales")`, wantTitle: "Quarterly sales", wantPath: "chart.png"},
+		{name: "alt only", markdown: `![A chart](chart.png)`, wantAlt: "A chart", wantPath: "chart.png"},
+		{name: "alt and title", markdown: `![A chart](chart.png "Quarterly sales")`, wantAlt: "A chart", wantTitle: "Quarterly sales", wantPath: "chart.png"},
+		{name: "single quoted title", markdown: `![A chart](chart.png 'Sales')`, wantAlt: "A chart", wantTitle: "Sales", wantPath: "chart.png"},
+		{name: "title after size parameters", markdown: `![A chart](chart.png?width=400 "Sales")`, wantAlt: "A chart", wantTitle: "Sales", wantPath: "chart.png"},
+		{name: "html title", markdown: `<img title="Sales" src="chart.png" alt="A chart">`, wantAlt: "A chart", wantTitle: "Sales", wantPath: "chart.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := FindImageReferences(tt.markdown)
+			if len(refs) != 1 {
+				t.Fatalf("got %d references, want 1", len(refs))
+			}
+			if ref := refs[0]; ref.AltText != tt.wantAlt || ref.Title != tt.wantTitle || ref.Path != tt.wantPath {
+				t.Errorf("reference = %+v, want alt %q, title %q and path %q", ref, tt.wantAlt, tt.wantTitle, tt.wantPath)
+			}
+		})
+	}
+}
+
+// sentImage returns the image of block as it is sent to Notion, whichever image type it is
+func sentImage(t *testing.T, block notion.Block) notion.ImageBlock {
+	t.Helper()
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent struct {
+		Image notion.ImageBlock `json:"image"`
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		t.Fatal(err)
+	}
+	return sent.Image
+}
+
+func TestImageCaptionSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		source   string
+		want     string
+	}{
+		{name: "title preferred", markdown: `![A chart](https://example.com/chart.png "Sales")`, source: "title", want: "Sales"},
+		{name: "default prefers the title", markdown: `![A chart](https://example.com/chart.png "Sales")`, want: "Sales"},
+		{name: "alt without a title", markdown: `![A chart](https://example.com/chart.png)`, source: "title", want: "A chart"},
+		{name: "title without alt", markdown: `![](https://example.com/chart.png "Sales")`, source: "both", want: "Sales"},
+		{name: "alt policy", markdown: `![A chart](https://example.com/chart.png "Sales")`, source: "alt", want: "A chart"},
+		{name: "both", markdown: `![A chart](https://example.com/chart.png "Sales")`, source: "both", want: "A chart — Sales"},
+		{name: "both when equal", markdown: `![Sales](https://example.com/chart.png "Sales")`, source: "both", want: "Sales"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := processImages(t, newFakeNotionClient(), writeMarkdown(t, ""), tt.markdown+"\n", ImageOptions{CaptionSource: tt.source})
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one image", blockTypes(blocks))
+			}
+			if got := richTextPlainText(sentImage(t, blocks[0]).Caption); got != tt.want {
+				t.Errorf("caption = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageCaptionPositions(t *testing.T) {
+	tests := []struct {
+		name        string
+		markdown    string
+		position    string
+		wantTypes   []string
+		wantCaption string
+		wantText    string
+	}{
+		{
+			name:        "in the caption",
+			markdown:    "![A chart](https://example.com/chart.png?width=400)",
+			position:    "caption",
+			wantTypes:   []string{"notion.ImageBlock"},
+			wantCaption: "A chart (width: 400px)",
+		},
+		{
+			name:        "default is the caption",
+			markdown:    "![A chart](https://example.com/chart.png)",
+			wantTypes:   []string{"notion.ImageBlock"},
+			wantCaption: "A chart",
+		},
+		{
+			name:      "above",
+			markdown:  "![A chart](https://example.com/chart.png?width=400)",
+			position:  "above",
+			wantTypes: []string{"notion.ParagraphBlock", "notion.ImageBlock"},
+			wantText:  "A chart (width: 400px)",
+		},
+		{
+			name:      "below",
+			markdown:  "![A chart](https://example.com/chart.png)",
+			position:  "below",
+			wantTypes: []string{"notion.ImageBlock", "notion.ParagraphBlock"},
+			wantText:  "A chart",
+		},
+		{
+			name:      "below an uploaded image",
+			markdown:  "![Logo](logo.png)",
+			position:  "below",
+			wantTypes: []string{"notionsync.ImageBlock", "notion.ParagraphBlock"},
+			wantText:  "Logo",
+		},
+		{
+			name:      "no paragraph without a caption",
+			markdown:  "![](https://example.com/chart.png)",
+			position:  "above",
+			wantTypes: []string{"notion.ImageBlock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdPath := writeMarkdown(t, "")
+			writeImage(t, mdPath, "logo.png")
+			blocks := processImages(t, newFakeNotionClient(), mdPath, tt.markdown+"\n", ImageOptions{CaptionPosition: tt.position})
+			if got := blockTypes(blocks); !slices.Equal(got, tt.wantTypes) {
+				t.Fatalf("blocks = %v, want %v", got, tt.wantTypes)
+			}
+			for _, block := range blocks {
+				if _, ok := block.(*notion.ParagraphBlock); ok {
+					if got := ownText(block); got != tt.wantText {
+						t.Errorf("caption paragraph = %q, want %q", got, tt.wantText)
+					}
+				} else if got := richTextPlainText(sentImage(t, block).Caption); got != tt.wantCaption {
+					t.Errorf("image caption = %q, want %q", got, tt.wantCaption)
+				}
+			}
+		})
+	}
+}
+
+func TestFindImageReferencesHTMLAttributeOrder(t *testing.T) {
+	want := ImageReference{AltText: "A chart", Path: "chart.png", IsLocal: true, Width: 500, Height: 300}
+	tests := []struct {
+		name string
+		html string
+		want ImageReference
+	}{
+		{name: "src alt width height", html: `<img src="chart.png" alt="A chart" width="500" height="300">`, want: want},
+		{name: "width first", html: `<img width="500" src="chart.png" alt="A chart" height="300">`, want: want},
+		{name: "height before width", html: `<img height="300" width="500" alt="A chart" src="chart.png" />`, want: want},
+		{name: "unquoted and single quoted", html: `<IMG HEIGHT=300px alt='A chart' Width=500 src=chart.png>`, want: want},
+		{name: "extra attributes", html: `<img class="wide" loading="lazy" width="500" data-src="other.png" alt="A chart" decoding=async height="300" src="chart.png">`, want: want},
+		{name: "multi-line tag", html: "<img\n  loading=\"lazy\"\n  src=\"chart.png\"\n  width=\"500\"\n  height=\"300\"\n  alt=\"A chart\"\n>", want: want},
+		{name: "size from the src", html: `<img alt="A chart" class="wide" src="chart.png?width=500&height=300">`, want: want},
+		{name: "remote with percent width", html: `<img width="50%" alt="x" src="https://example.com/chart.png">`, want: ImageReference{AltText: "x", Path: "https://example.com/chart.png"}},
+		{name: "entities in the alt text", html: `<img alt="Q&amp;A &quot;chart&quot;" src="chart.png">`, want: ImageReference{AltText: `Q&A "chart"`, Path: "chart.png", IsLocal: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := FindImageReferences("Before\n\n" + tt.html + "\n\nAfter\n")
+			if len(refs) != 1 {
+				t.Fatalf("got %d references, want 1", len(refs))
+			}
+			if refs[0] != tt.want {
+				t.Errorf("reference = %+v, want %+v", refs[0], tt.want)
+			}
+		})
+	}
+
+	if refs := FindImageReferences(`<img alt="no source" width="500">`); len(refs) != 0 {
+		t.Errorf("references = %+v, want none for an img without src", refs)
+	}
+}
+
+// orderedUploadClient is a fake client whose uploads can be made to finish in reverse source
+// order: with reverse set, the upload of each image waits for the one after it in order.
+// Uploads of the images in fail fail.
+type orderedUploadClient struct {
+	*fakeNotionClient
+	order   []string
+	reverse bool
+	fail    map[string]bool
+
+	mu       sync.Mutex
+	started  []string
+	finished []string
+	done     map[string]chan struct{}
+}
+
+func newOrderedUploadClient(order []string, reverse bool, fail ...string) *orderedUploadClient {
+	c := &orderedUploadClient{fakeNotionClient: newFakeNotionClient(), order: order, reverse: reverse, fail: map[string]bool{}, done: map[string]chan struct{}{}}
+	for _, name := range order {
+		c.done[name] = make(chan struct{})
+	}
+	for _, name := range fail {
+		c.fail[name] = true
+	}
+	return c
+}
+
+func (c *orderedUploadClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	name := filepath.Base(filePath)
+	c.mu.Lock()
+	c.started = append(c.started, name)
+	c.mu.Unlock()
+	if i := slices.Index(c.order, name); c.reverse && i+1 < len(c.order) {
+		select {
+		case <-c.done[c.order[i+1]]:
+		case <-time.After(5 * time.Second):
+			return "", fmt.Errorf("%s waited too long for %s", name, c.order[i+1])
+		}
+	}
+	c.mu.Lock()
+	c.finished = append(c.finished, name)
+	c.mu.Unlock()
+	close(c.done[name])
+	if c.fail[name] {
+		return "", fmt.Errorf("upload of %s failed", name)
+	}
+	return "upload-" + name, nil
+}
+
+// imageOutline describes blocks and their children in order: "image <upload ID>" for uploaded
+// images, "failed <path>" for failed image links and the text of anything else
+func imageOutline(blocks []notion.Block) []string {
+	var lines []string
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case ImageBlock:
+			lines = append(lines, "image "+b.FileUpload.ID)
+		case failedImageBlock:
+			lines = append(lines, "failed "+b.Path)
+		default:
+			lines = append(lines, ownText(block))
+		}
+		lines = append(lines, imageOutline(blockChildren(block))...)
+	}
+	return lines
+}
+
+// concurrentImagesMarkdown holds images at the top level and nested in a toggle and a callout
+const concurrentImagesMarkdown = "Intro.\n\n![A](a.png)\n\n" +
+	"<details>\n<summary>More</summary>\n\n![B](b.png)\n\nBetween.\n\n![C](c.png)\n\n</details>\n\n" +
+	"> [!NOTE]\n> Note.\n>\n> ![D](d.png)\n\n![E](e.png)\n"
+
+var concurrentImages = []string{"a.png", "b.png", "c.png", "d.png", "e.png"}
+
+func writeConcurrentImages(t *testing.T) string {
+	t.Helper()
+	mdPath := writeMarkdown(t, concurrentImagesMarkdown)
+	for _, name := range concurrentImages {
+		writeImage(t, mdPath, name)
+	}
+	return mdPath
+}
+
+func TestProcessImageBlocksConcurrentOrder(t *testing.T) {
+	want := []string{"Intro.", "image upload-a.png", "More", "image upload-b.png", "Between.", "image upload-c.png", "Note", "Note.", "image upload-d.png", "image upload-e.png"}
+	for _, concurrency := range []int{1, len(concurrentImages)} {
+		t.Run(fmt.Sprint(concurrency), func(t *testing.T) {
+			mdPath := writeConcurrentImages(t)
+			// One at a time the uploads can't be held back, they finish in order
+			reverse := concurrency > 1
+			client := newOrderedUploadClient(concurrentImages, reverse)
+			blocks := processImages(t, client, mdPath, concurrentImagesMarkdown, ImageOptions{UploadConcurrency: concurrency})
+			if got := imageOutline(blocks); !slices.Equal(got, want) {
+				t.Errorf("blocks = %q, want %q", got, want)
+			}
+			wantFinished := slices.Clone(concurrentImages)
+			if reverse {
+				slices.Reverse(wantFinished)
+			}
+			if !slices.Equal(client.finished, wantFinished) {
+				t.Errorf("uploads finished in order %q, want %q", client.finished, wantFinished)
+			}
+			if !reverse && !slices.Equal(client.started, concurrentImages) {
+				t.Errorf("uploads started in order %q, want one after the other in source order", client.started)
+			}
+		})
+	}
+}
+
+func TestProcessImageBlocksConcurrentErrors(t *testing.T) {
+	t.Run("first failure in block order", func(t *testing.T) {
+		mdPath := writeConcurrentImages(t)
+		// d.png fails before b.png does, b.png comes first in the document
+		client := newOrderedUploadClient(concurrentImages, true, "b.png", "d.png")
+		ctx := NewContext(context.Background(), testOptions())
+		_, err := ProcessImageBlocks(ctx, convert(t, concurrentImagesMarkdown), mdPath, client, ImageOptions{UploadConcurrency: len(concurrentImages)})
+		if err == nil || err.Error() != "upload of b.png failed" {
+			t.Errorf("err = %v, want the failure of b.png", err)
+		}
+		if i, j := slices.Index(client.finished, "d.png"), slices.Index(client.finished, "b.png"); i < 0 || j < 0 || i > j {
+			t.Errorf("uploads finished in order %q, want d.png to fail first", client.finished)
+		}
+	})
+
+	t.Run("no new jobs after a failure", func(t *testing.T) {
+		mdPath := writeConcurrentImages(t)
+		client := newOrderedUploadClient(concurrentImages, false, "b.png")
+		ctx := NewContext(context.Background(), testOptions())
+		_, err := ProcessImageBlocks(ctx, convert(t, concurrentImagesMarkdown), mdPath, client, ImageOptions{UploadConcurrency: 1})
+		if err == nil || err.Error() != "upload of b.png failed" {
+			t.Errorf("err = %v, want the failure of b.png", err)
+		}
+		if !slices.Equal(client.started, []string{"a.png", "b.png"}) {
+			t.Errorf("uploads started = %q, want none after the failure", client.started)
+		}
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		mdPath := writeConcurrentImages(t)
+		client := newOrderedUploadClient(concurrentImages, true, "b.png", "d.png")
+		blocks := processImages(t, client, mdPath, concurrentImagesMarkdown, ImageOptions{UploadConcurrency: len(concurrentImages), ContinueOnError: true})
+		want := []string{"Intro.", "image upload-a.png", "More", "failed b.png", "Between.", "image upload-c.png", "Note", "Note.", "failed d.png", "image upload-e.png"}
+		if got := imageOutline(blocks); !slices.Equal(got, want) {
+			t.Errorf("blocks = %q, want %q", got, want)
+		}
+		if len(client.started) != len(concurrentImages) {
+			t.Errorf("uploads started = %q, want all of them", client.started)
+		}
+	})
+}
@@ -0,0 +1,447 @@
+package notionsync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find an autolink: <https://example.com>
+var autolinkRegex = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+
+// inlineNode is a piece of inline markdown: text with the formatting applied to it, or a run
+// of emphasis delimiters (*, _ or ~) that may still open or close emphasis
+type inlineNode struct {
+	text        string
+	link        string
+	annotations notion.Annotations
+
+	delim     byte
+	count     int
+	origCount int
+	canOpen   bool
+	canClose  bool
+}
+
+// parseInline converts inline markdown into rich text. Emphasis follows the CommonMark delimiter
+// rules, so nested and combined emphasis (***both***, **bold _and italic_**) gives runs carrying
+// every annotation that applies to them. Code spans, links, autolinks, ~~strikethrough~~ and
+// backslash escapes are supported, images are kept as text.
+func parseInline(text string) []notion.RichText {
+	nodes := scanInline(text, "")
+	processEmphasis(nodes)
+	return inlineRuns(nodes)
+}
+
+// scanInline splits text into inline nodes, every node belonging to the given link
+func scanInline(text, link string) []*inlineNode {
+	var nodes []*inlineNode
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, &inlineNode{text: buf.String(), link: link})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			buf.WriteByte(text[i+1])
+			i += 2
+		case c == '`':
+			n := delimiterRunLength(text, i)
+			end := codeSpanEnd(text, i+n, n)
+			if end < 0 {
+				buf.WriteString(text[i : i+n])
+				i += n
+				continue
+			}
+			flush()
+			node := &inlineNode{text: codeSpanContent(text[i+n : end]), link: link}
+			node.annotations.Code = true
+			nodes = append(nodes, node)
+			i = end + n
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			// Images are handled as blocks, inline ones stay as their markdown
+			if _, _, end, ok := parseInlineLink(text, i+1); ok {
+				buf.WriteString(text[i:end])
+				i = end
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+		case c == '[' && link == "":
+			label, dest, end, ok := parseInlineLink(text, i)
+			if !ok {
+				buf.WriteByte(c)
+				i++
+				continue
+			}
+			flush()
+			labelNodes := scanInline(label, dest)
+			processEmphasis(labelNodes)
+			nodes = append(nodes, labelNodes...)
+			i = end
+		case c == '<' && link == "":
+			match := autolinkRegex.FindStringSubmatch(text[i:])
+			if match == nil {
+				buf.WriteByte(c)
+				i++
+				continue
+			}
+			flush()
+			nodes = append(nodes, &inlineNode{text: match[1], link: match[1]})
+			i += len(match[0])
+		case c == '*' || c == '_' || c == '~':
+			n := delimiterRunLength(text, i)
+			if c == '~' && n != 2 {
+				buf.WriteString(text[i : i+n])
+				i += n
+				continue
+			}
+			flush()
+			nodes = append(nodes, newDelimiterNode(text, i, n, link))
+			i += n
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return nodes
+}
+
+// newDelimiterNode builds the node for the delimiter run text[i:i+n], working out from the
+// characters around it whether it can open or close emphasis
+func newDelimiterNode(text string, i, n int, link string) *inlineNode {
+	prev, next := ' ', ' '
+	if i > 0 {
+		prev, _ = utf8.DecodeLastRuneInString(text[:i])
+	}
+	if i+n < len(text) {
+		next, _ = utf8.DecodeRuneInString(text[i+n:])
+	}
+	leftFlanking := !unicode.IsSpace(next) && (!isPunctuation(next) || unicode.IsSpace(prev) || isPunctuation(prev))
+	rightFlanking := !unicode.IsSpace(prev) && (!isPunctuation(prev) || unicode.IsSpace(next) || isPunctuation(next))
+
+	node := &inlineNode{text: text[i : i+n], link: link, delim: text[i], count: n, origCount: n}
+	if node.delim == '_' {
+		// Underscores inside words don't emphasize: snake_case_names stay as they are
+		node.canOpen = leftFlanking && (!rightFlanking || isPunctuation(prev))
+		node.canClose = rightFlanking && (!leftFlanking || isPunctuation(next))
+	} else {
+		node.canOpen, node.canClose = leftFlanking, rightFlanking
+	}
+	return node
+}
+
+// processEmphasis matches emphasis delimiters, innermost first, and applies the formatting to
+// everything between them. Unmatched delimiters are left as literal text.
+func processEmphasis(nodes []*inlineNode) {
+	for c, closer := range nodes {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			o := findOpener(nodes, c)
+			if o < 0 {
+				break
+			}
+			opener := nodes[o]
+			use := 1
+			if opener.count >= 2 && closer.count >= 2 {
+				use = 2
+			}
+			for _, node := range nodes[o+1 : c] {
+				switch {
+				case closer.delim == '~':
+					node.annotations.Strikethrough = true
+				case use == 2:
+					node.annotations.Bold = true
+				default:
+					node.annotations.Italic = true
+				}
+				// Delimiters inside a matched pair can't match anything outside it
+				node.canOpen, node.canClose = false, false
+			}
+			opener.count -= use
+			closer.count -= use
+		}
+	}
+}
+
+// findOpener returns the index of the nearest delimiter before nodes[c] that can be closed by
+// it, or -1 if there is none
+func findOpener(nodes []*inlineNode, c int) int {
+	closer := nodes[c]
+	for o := c - 1; o >= 0; o-- {
+		opener := nodes[o]
+		if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+			continue
+		}
+		if closer.delim == '~' {
+			return o
+		}
+		// The "rule of three" keeps runs like ***a** b* from pairing up wrongly
+		if (opener.canClose || closer.canOpen) && (opener.origCount+closer.origCount)%3 == 0 &&
+			!(opener.origCount%3 == 0 && closer.origCount%3 == 0) {
+			continue
+		}
+		return o
+	}
+	return -1
+}
+
+// inlineRuns turns inline nodes into rich text, merging neighbours with the same formatting
+func inlineRuns(nodes []*inlineNode) []notion.RichText {
+	richText := []notion.RichText{}
+	for _, node := range nodes {
+		text := node.text
+		if node.delim != 0 {
+			text = strings.Repeat(string(node.delim), node.count)
+		}
+		if text == "" {
+			continue
+		}
+		var annotations *notion.Annotations
+		if node.annotations != (notion.Annotations{}) {
+			ann := node.annotations
+			annotations = &ann
+		}
+		run := textRun(text, annotations)
+		if node.link != "" {
+			run.Text.Link = &notion.Link{URL: node.link}
+		}
+		if n := len(richText); n > 0 && sameFormatting(richText[n-1], run) {
+			richText[n-1].Text.Content += text
+			richText[n-1].PlainText = richText[n-1].Text.Content
+			continue
+		}
+		richText = append(richText, run)
+	}
+	return richText
+}
+
+// hasCombinedEmphasis reports whether any run carries more than one of bold, italic,
+// strikethrough and code, the formatting notionmd flattens or drops
+func hasCombinedEmphasis(richText []notion.RichText) bool {
+	for _, rt := range richText {
+		if ann := rt.Annotations; ann != nil {
+			count := 0
+			for _, set := range []bool{ann.Bold, ann.Italic, ann.Strikethrough, ann.Code} {
+				if set {
+					count++
+				}
+			}
+			if count > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseInlineLink parses a link "[label](destination "title")" starting at text[start],
+// returning the label, the destination and the index after the link
+func parseInlineLink(text string, start int) (label, dest string, end int, ok bool) {
+	depth := 0
+	closing := -1
+	for i := start; i < len(text) && closing < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(text) || text[closing+1] != '(' {
+		return "", "", 0, false
+	}
+
+	i := closing + 2
+	for i < len(text) && text[i] == ' ' {
+		i++
+	}
+	destStart := i
+	if i < len(text) && text[i] == '<' {
+		gt := strings.IndexByte(text[i:], '>')
+		if gt < 0 {
+			return "", "", 0, false
+		}
+		dest, i = text[i+1:i+gt], i+gt+1
+	} else {
+		parens := 0
+		for ; i < len(text) && text[i] != ' '; i++ {
+			if text[i] == '(' {
+				parens++
+			} else if text[i] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = text[destStart:i]
+	}
+	// An optional title is accepted but not used, Notion links have none
+	rest := strings.TrimLeft(text[i:], " ")
+	i = len(text) - len(rest)
+	if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+		quote := strings.IndexByte(rest[1:], rest[0])
+		if quote < 0 {
+			return "", "", 0, false
+		}
+		rest = strings.TrimLeft(rest[quote+2:], " ")
+		i = len(text) - len(rest)
+	}
+	if len(rest) == 0 || rest[0] != ')' {
+		return "", "", 0, false
+	}
+	return text[start+1 : closing], dest, i + 1, true
+}
+
+// delimiterRunLength returns how many times the character at text[i] repeats from i
+func delimiterRunLength(text string, i int) int {
+	n := 1
+	for i+n < len(text) && text[i+n] == text[i] {
+		n++
+	}
+	return n
+}
+
+// codeSpanEnd returns the index of the backtick run of exactly n closing a code span opened
+// before from, or -1 if there is none
+func codeSpanEnd(text string, from, n int) int {
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := delimiterRunLength(text, i)
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// codeSpanContent normalizes code span content: line breaks become spaces and a single space
+// padding both ends is stripped
+func codeSpanContent(content string) string {
+	content = strings.ReplaceAll(content, "\n", " ")
+	if len(content) > 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.TrimSpace(content) != "" {
+		return content[1 : len(content)-1]
+	}
+	return content
+}
+
+// isASCIIPunctuation reports whether c may be backslash escaped
+func isASCIIPunctuation(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// isPunctuation reports whether r counts as punctuation for emphasis flanking rules
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// Regular expression to find the block markers in front of a line's inline content: list
+// markers (with a task checkbox), ATX heading markers and blockquote markers
+var blockPrefixRegex = regexp.MustCompile(`^(?:[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?|[ \t]*#{1,6}[ \t]+|[ \t]*>[ \t]?)*[ \t]*`)
+
+// Regular expression to find the placeholder words standing in for parsed inline content
+var inlinePlaceholderRegex = regexp.MustCompile(`NOTIONMDINLINE\d+`)
+
+// inlinePlaceholder returns line with its inline content swapped for a placeholder word if
+// that content has combined emphasis, which notionmd would flatten. Lines that may be code,
+// HTML or thematic breaks are left alone.
+func (c *markdownConverter) inlinePlaceholder(line string, afterBlank bool) (string, bool) {
+	if !strings.ContainsAny(line, "*_~") || thematicBreakRegex.MatchString(line) {
+		return "", false
+	}
+	prefix := blockPrefixRegex.FindString(line)
+	content := strings.TrimRight(line[len(prefix):], " \t")
+	if content == "" || content[0] == '<' || strings.HasPrefix(content, "[") && strings.Contains(content, "]:") {
+		return "", false
+	}
+	// An indented line after a blank one is indented code, unless it is a list item
+	if afterBlank && strings.HasPrefix(line, "    ") && !listItemRegex.MatchString(strings.TrimLeft(line, " ")) {
+		return "", false
+	}
+	richText := parseInline(content)
+	if !hasCombinedEmphasis(richText) {
+		return "", false
+	}
+	token := fmt.Sprintf("NOTIONMDINLINE%d", len(c.inline))
+	c.inline[token] = richText
+	return prefix + token + line[len(prefix)+len(content):], true
+}
+
+// expandInlinePlaceholders replaces the placeholder words in rich text with the rich text they
+// stand for, adding the formatting of the text around them
+func (c *markdownConverter) expandInlinePlaceholders(richText []notion.RichText) []notion.RichText {
+	var result []notion.RichText
+	for _, rt := range richText {
+		if rt.Text == nil || !inlinePlaceholderRegex.MatchString(rt.Text.Content) {
+			result = append(result, rt)
+			continue
+		}
+		content := rt.Text.Content
+		last := 0
+		for _, loc := range inlinePlaceholderRegex.FindAllStringIndex(content, -1) {
+			if loc[0] > last {
+				result = append(result, withContent(rt, content[last:loc[0]]))
+			}
+			for _, parsed := range c.inline[content[loc[0]:loc[1]]] {
+				result = append(result, withOuterFormatting(parsed, rt))
+			}
+			last = loc[1]
+		}
+		if last < len(content) {
+			result = append(result, withContent(rt, content[last:]))
+		}
+	}
+	return result
+}
+
+// withContent returns a copy of the text run rt holding content instead
+func withContent(rt notion.RichText, content string) notion.RichText {
+	text := *rt.Text
+	text.Content = content
+	rt.Text = &text
+	rt.PlainText = content
+	return rt
+}
+
+// withOuterFormatting adds the annotations and link of the surrounding run outer to rt
+func withOuterFormatting(rt, outer notion.RichText) notion.RichText {
+	if outer.Annotations != nil {
+		var ann notion.Annotations
+		if rt.Annotations != nil {
+			ann = *rt.Annotations
+		}
+		ann.Bold = ann.Bold || outer.Annotations.Bold
+		ann.Italic = ann.Italic || outer.Annotations.Italic
+		ann.Strikethrough = ann.Strikethrough || outer.Annotations.Strikethrough
+		ann.Underline = ann.Underline || outer.Annotations.Underline
+		ann.Code = ann.Code || outer.Annotations.Code
+		rt.Annotations = &ann
+	}
+	if rt.Text.Link == nil && outer.Text.Link != nil {
+		text := *rt.Text
+		text.Link = outer.Text.Link
+		rt.Text = &text
+	}
+	return rt
+}
@@ -0,0 +1,64 @@
+package notionsync
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// annotatedRuns describes each run of richText as its text followed by its annotations, for
+// example "word[bi]" for bold italic: b bold, i italic, s strikethrough, c code
+func annotatedRuns(richText []notion.RichText) []string {
+	runs := make([]string, len(richText))
+	for i, rt := range richText {
+		flags := ""
+		if a := rt.Annotations; a != nil {
+			for _, flag := range []struct {
+				set  bool
+				name string
+			}{{a.Bold, "b"}, {a.Italic, "i"}, {a.Strikethrough, "s"}, {a.Code, "c"}} {
+				if flag.set {
+					flags += flag.name
+				}
+			}
+		}
+		runs[i] = rt.PlainText
+		if rt.Text != nil {
+			runs[i] = rt.Text.Content
+		}
+		if flags != "" {
+			runs[i] += "[" + flags + "]"
+		}
+	}
+	return runs
+}
+
+func TestConvertNestedEmphasis(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{"triple asterisks", "***bold italic***", []string{"bold italic[bi]"}},
+		{"triple underscores", "___bold italic___", []string{"bold italic[bi]"}},
+		{"italic inside bold", "**bold _with italic_**", []string{"bold [b]", "with italic[bi]"}},
+		{"bold inside italic", "*italic **with bold***", []string{"italic [i]", "with bold[bi]"}},
+		{"italic between bold", "__bold *it* more__ end", []string{"bold [b]", "it[bi]", " more[b]", " end"}},
+		{"separate emphasis", "**a** *b* ***c***", []string{"a[b]", " ", "b[i]", " ", "c[bi]"}},
+		{"code inside bold", "**bold `code`**", []string{"bold [b]", "code[bc]"}},
+		{"bold strikethrough", "~~**struck bold**~~", []string{"struck bold[bs]"}},
+		{"strikethrough inside bold", "**bold ~~struck~~**", []string{"bold [b]", "struck[bs]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown+"\n")
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one paragraph", blockTypes(blocks))
+			}
+			if got := annotatedRuns(blockRichText(blocks[0])); !slices.Equal(got, tt.want) {
+				t.Errorf("runs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package notionsync
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// inputPollInterval is how often readInputFile checks a file it is waiting for
+const inputPollInterval = 100 * time.Millisecond
+
+// readInputFile reads a markdown or configuration file. With SyncOptions.InputWait it first
+// waits that long at most for the file to exist and keep the same size and modification time
+// between two checks, for files still being written by a previous CI step.
+func readInputFile(ctx context.Context, path string) ([]byte, error) {
+	if wait := sessionFrom(ctx).inputWait; wait > 0 {
+		waitForStableFile(ctx, path, time.Now().Add(wait))
+	}
+	return os.ReadFile(path)
+}
+
+// waitForStableFile polls path until it exists and is unchanged since the previous check, or
+// the deadline passes
+func waitForStableFile(ctx context.Context, path string, deadline time.Time) {
+	var previous os.FileInfo
+	for {
+		info, err := os.Stat(path)
+		if err == nil && previous != nil && info.Size() == previous.Size() && info.ModTime().Equal(previous.ModTime()) {
+			return
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				debugf(ctx, "[DEBUG] Gave up waiting for %s: %s\n", path, err)
+			}
+			return
+		}
+		if err == nil {
+			previous = info
+		} else {
+			previous = nil
+			debugf(ctx, "[DEBUG] Waiting for %s to appear\n", path)
+		}
+		time.Sleep(inputPollInterval)
+	}
+}
@@ -0,0 +1,78 @@
+package notionsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLater writes content to path after delay, the way a previous CI step would
+func writeLater(t *testing.T, path, content string, delay time.Duration) {
+	t.Helper()
+	done := make(chan struct{})
+	t.Cleanup(func() { <-done })
+	go func() {
+		defer close(done)
+		time.Sleep(delay)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Error(err)
+		}
+	}()
+}
+
+func TestReadInputFileWaitsForFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.md")
+	writeLater(t, path, "# Late\n", 250*time.Millisecond)
+
+	opts := testOptions()
+	opts.InputWait = 5 * time.Second
+	content, err := readInputFile(NewContext(context.Background(), opts), path)
+	if err != nil {
+		t.Fatalf("readInputFile = %v, want the file once it appears", err)
+	}
+	if string(content) != "# Late\n" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestReadInputFileWithoutWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.md")
+	writeLater(t, path, "# Late\n", 250*time.Millisecond)
+
+	started := time.Now()
+	if _, err := readInputFile(NewContext(context.Background(), testOptions()), path); !os.IsNotExist(err) {
+		t.Errorf("readInputFile = %v, want the file not to exist yet", err)
+	}
+	if elapsed := time.Since(started); elapsed > 200*time.Millisecond {
+		t.Errorf("readInputFile waited %s without --input-wait", elapsed)
+	}
+}
+
+func TestReadInputFileGivesUp(t *testing.T) {
+	opts := testOptions()
+	opts.InputWait = 300 * time.Millisecond
+	started := time.Now()
+	if _, err := readInputFile(NewContext(context.Background(), opts), filepath.Join(t.TempDir(), "missing.md")); !os.IsNotExist(err) {
+		t.Errorf("readInputFile = %v, want the file not to exist", err)
+	}
+	if elapsed := time.Since(started); elapsed < opts.InputWait {
+		t.Errorf("readInputFile gave up after %s, want it to wait %s", elapsed, opts.InputWait)
+	}
+}
+
+func TestSyncFileWaitsForMarkdown(t *testing.T) {
+	mdPath := filepath.Join(t.TempDir(), "doc.md")
+	writeLater(t, mdPath, "# Title\n\nWritten late.\n", 250*time.Millisecond)
+
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.InputWait = 5 * time.Second
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if content := client.content["page"]; len(content) != 1 || ownText(content[0]) != "Written late." {
+		t.Errorf("content = %v, want the late paragraph", blockTypes(content))
+	}
+}
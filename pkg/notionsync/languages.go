@@ -0,0 +1,94 @@
+package notionsync
+
+import "strings"
+
+// notionCodeLanguages are the languages Notion accepts for a code block
+// https://developers.notion.com/reference/block#code
+var notionCodeLanguages = map[string]bool{
+	"abap":          true,
+	"arduino":       true,
+	"bash":          true,
+	"basic":         true,
+	"c":             true,
+	"clojure":       true,
+	"coffeescript":  true,
+	"c++":           true,
+	"c#":            true,
+	"css":           true,
+	"dart":          true,
+	"diff":          true,
+	"docker":        true,
+	"elixir":        true,
+	"elm":           true,
+	"erlang":        true,
+	"flow":          true,
+	"fortran":       true,
+	"f#":            true,
+	"gherkin":       true,
+	"glsl":          true,
+	"go":            true,
+	"graphql":       true,
+	"groovy":        true,
+	"haskell":       true,
+	"html":          true,
+	"java":          true,
+	"javascript":    true,
+	"json":          true,
+	"julia":         true,
+	"kotlin":        true,
+	"latex":         true,
+	"less":          true,
+	"lisp":          true,
+	"livescript":    true,
+	"lua":           true,
+	"makefile":      true,
+	"markdown":      true,
+	"markup":        true,
+	"matlab":        true,
+	"mermaid":       true,
+	"nix":           true,
+	"objective-c":   true,
+	"ocaml":         true,
+	"pascal":        true,
+	"perl":          true,
+	"php":           true,
+	"plain text":    true,
+	"powershell":    true,
+	"prolog":        true,
+	"protobuf":      true,
+	"python":        true,
+	"r":             true,
+	"reason":        true,
+	"ruby":          true,
+	"rust":          true,
+	"sass":          true,
+	"scala":         true,
+	"scheme":        true,
+	"scss":          true,
+	"shell":         true,
+	"sql":           true,
+	"swift":         true,
+	"typescript":    true,
+	"vb.net":        true,
+	"verilog":       true,
+	"vhdl":          true,
+	"visual basic":  true,
+	"webassembly":   true,
+	"xml":           true,
+	"yaml":          true,
+	"java/c/c++/c#": true,
+}
+
+// codeLanguage returns the Notion language for a fence info string such as "yml title=a.yml",
+// mapping common aliases. Unknown or missing languages give nil.
+func codeLanguage(info string) *string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return nil
+	}
+	language := mapLanguageToNotionCompatible(strings.ToLower(fields[0]))
+	if !notionCodeLanguages[language] {
+		return nil
+	}
+	return &language
+}
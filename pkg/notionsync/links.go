@@ -0,0 +1,153 @@
+package notionsync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find HTML anchor tags: <a href="..." target="_blank"> and </a>
+var anchorTagRegex = regexp.MustCompile(`(?i)<a(?:\s[^>]*)?>|</a\s*>`)
+
+// Regular expression to find the href attribute of an anchor tag, quoted or not
+var hrefAttrRegex = regexp.MustCompile(`(?i)\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// linkReference is an external link found in the converted content
+type linkReference struct {
+	Text string
+	URL  string
+}
+
+// collectLinks returns the unique external links in blocks (and their children) in document order
+func collectLinks(blocks []notion.Block) []linkReference {
+	var refs []linkReference
+	seen := make(map[string]bool)
+	var walk func(blocks []notion.Block)
+	walk = func(blocks []notion.Block) {
+		for _, block := range blocks {
+			for _, rt := range blockRichText(block) {
+				if rt.Text == nil || rt.Text.Link == nil {
+					continue
+				}
+				url := rt.Text.Link.URL
+				if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+					continue
+				}
+				if seen[url] {
+					continue
+				}
+				seen[url] = true
+				refs = append(refs, linkReference{Text: rt.Text.Content, URL: url})
+			}
+			walk(blockChildren(block))
+		}
+	}
+	walk(blocks)
+	return refs
+}
+
+// appendLinkIndex appends a "References" heading and a numbered list of every unique external link
+func appendLinkIndex(ctx context.Context, blocks []notion.Block) []notion.Block {
+	refs := collectLinks(blocks)
+	if len(refs) == 0 {
+		return blocks
+	}
+	debugf(ctx, "[DEBUG] Appending link index with %d references\n", len(refs))
+	blocks = append(blocks, notion.Heading2Block{RichText: plainRichText("References")})
+	for _, ref := range refs {
+		text := ref.Text
+		if text == "" {
+			text = ref.URL
+		}
+		blocks = append(blocks, notion.NumberedListItemBlock{
+			RichText: []notion.RichText{
+				{
+					Type:      notion.RichTextTypeText,
+					Text:      &notion.Text{Content: text, Link: &notion.Link{URL: ref.URL}},
+					PlainText: text,
+				},
+				plainRichText(" — " + ref.URL)[0],
+			},
+		})
+	}
+	return blocks
+}
+
+// anchorPiece is a rich text run or an anchor tag split out of one
+type anchorPiece struct {
+	RichText notion.RichText
+	Tag      string // "open", "close" or "" for ordinary text
+	Href     string
+}
+
+// splitAnchorTags splits the anchor tags out of plain text runs, other runs pass through whole
+func splitAnchorTags(richText []notion.RichText) []anchorPiece {
+	var pieces []anchorPiece
+	for _, rt := range richText {
+		if !isPlainTextRun(rt) {
+			pieces = append(pieces, anchorPiece{RichText: rt})
+			continue
+		}
+		content := rt.Text.Content
+		last := 0
+		for _, loc := range anchorTagRegex.FindAllStringIndex(content, -1) {
+			if loc[0] > last {
+				pieces = append(pieces, anchorPiece{RichText: textRun(content[last:loc[0]], rt.Annotations)})
+			}
+			tag := content[loc[0]:loc[1]]
+			piece := anchorPiece{RichText: textRun(tag, rt.Annotations), Tag: "open"}
+			if strings.HasPrefix(tag, "</") {
+				piece.Tag = "close"
+			} else if m := hrefAttrRegex.FindStringSubmatch(tag); m != nil {
+				piece.Href = m[1] + m[2] + m[3]
+			}
+			pieces = append(pieces, piece)
+			last = loc[1]
+		}
+		if last == 0 {
+			pieces = append(pieces, anchorPiece{RichText: rt})
+		} else if last < len(content) {
+			pieces = append(pieces, anchorPiece{RichText: textRun(content[last:], rt.Annotations)})
+		}
+	}
+	return pieces
+}
+
+// convertHTMLAnchors turns raw <a href="...">text</a> HTML into linked rich text. The runs
+// between the tags keep their formatting and gain the link, other attributes are ignored.
+// Anchors that are never closed are left as they were.
+func convertHTMLAnchors(richText []notion.RichText) []notion.RichText {
+	var (
+		result []notion.RichText
+		open   *anchorPiece
+		inside []notion.RichText
+	)
+	for _, piece := range splitAnchorTags(richText) {
+		switch {
+		case piece.Tag == "open":
+			if open != nil {
+				// Anchors can't nest, the earlier tag was never closed
+				result = append(append(result, open.RichText), inside...)
+			}
+			open, inside = &piece, nil
+		case piece.Tag == "close" && open != nil:
+			for _, rt := range inside {
+				if open.Href != "" && rt.Text != nil && rt.Text.Link == nil {
+					rt.Text = &notion.Text{Content: rt.Text.Content, Link: &notion.Link{URL: open.Href}}
+				}
+				result = append(result, rt)
+			}
+			open, inside = nil, nil
+		case open != nil:
+			inside = append(inside, piece.RichText)
+		default:
+			result = append(result, piece.RichText)
+		}
+	}
+	if open != nil {
+		result = append(append(result, open.RichText), inside...)
+	}
+	return result
+}
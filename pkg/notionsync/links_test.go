@@ -0,0 +1,93 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppendLinkIndex(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	blocks := convert(t, "See [Go](https://go.dev) and [the docs](https://example.com/docs).\n\n"+
+		"- [Go again](https://go.dev)\n- [a page](other.md)\n\n"+
+		"Finally [Notion](https://notion.so).\n")
+	indexed := appendLinkIndex(ctx, blocks)
+	refs := indexed[len(blocks):]
+	if got := blockTypes(refs); !slices.Equal(got, []string{"notion.Heading2Block", "notion.NumberedListItemBlock", "notion.NumberedListItemBlock", "notion.NumberedListItemBlock"}) {
+		t.Fatalf("appended %v, want a heading and three references", got)
+	}
+	if got := ownText(refs[0]); got != "References" {
+		t.Errorf("heading = %q, want References", got)
+	}
+	want := []string{
+		"Go — https://go.dev",
+		"the docs — https://example.com/docs",
+		"Notion — https://notion.so",
+	}
+	for i, ref := range refs[1:] {
+		if got := ownText(ref); got != want[i] {
+			t.Errorf("reference %d = %q, want %q", i+1, got, want[i])
+		}
+	}
+}
+
+func TestAppendLinkIndexWithoutLinks(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	blocks := convert(t, "No links, only [a page](other.md).\n")
+	if got := appendLinkIndex(ctx, blocks); len(got) != len(blocks) {
+		t.Errorf("appended %d blocks, want none", len(got)-len(blocks))
+	}
+}
+
+func TestConvertHTMLAnchors(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		wantText string
+		wantLink string
+		linked   string
+	}{
+		{"simple anchor", `See <a href="https://go.dev">the Go site</a> now`, "See the Go site now", "https://go.dev", "the Go site"},
+		{"extra attributes", `See <a target="_blank" href='https://go.dev' rel="noopener noreferrer">Go</a>.`, "See Go.", "https://go.dev", "Go"},
+		{"unquoted href", `See <A HREF=https://go.dev>Go</A>.`, "See Go.", "https://go.dev", "Go"},
+		{"never closed", `See <a href="https://go.dev">Go`, `See <a href="https://go.dev">Go`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := transformRichText(convert(t, tt.markdown), convertHTMLAnchors)
+			richText := blockRichText(blocks[0])
+			if got := richTextPlainText(richText); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			var linked strings.Builder
+			for _, rt := range richText {
+				if rt.Text != nil && rt.Text.Link != nil {
+					if rt.Text.Link.URL != tt.wantLink {
+						t.Errorf("link = %q, want %q", rt.Text.Link.URL, tt.wantLink)
+					}
+					linked.WriteString(rt.Text.Content)
+				}
+			}
+			if linked.String() != tt.linked {
+				t.Errorf("linked text = %q, want %q", linked.String(), tt.linked)
+			}
+		})
+	}
+}
+
+func TestConvertHTMLAnchorsKeepsFormatting(t *testing.T) {
+	blocks := transformRichText(convert(t, `<a href="https://go.dev">plain **bold**</a>`), convertHTMLAnchors)
+	var bold []string
+	for _, rt := range blockRichText(blocks[0]) {
+		if rt.Text == nil || rt.Text.Link == nil || rt.Text.Link.URL != "https://go.dev" {
+			t.Errorf("run %q isn't linked", rt.PlainText)
+		}
+		if rt.Annotations != nil && rt.Annotations.Bold {
+			bold = append(bold, rt.Text.Content)
+		}
+	}
+	if !slices.Equal(bold, []string{"bold"}) {
+		t.Errorf("bold runs = %q, want only bold", bold)
+	}
+}
@@ -0,0 +1,154 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// applyDateMentions converts prefix-marked dates (e.g. @today, @2024-01-15) into Notion date mentions
+func applyDateMentions(ctx context.Context, blocks []notion.Block, prefix string, now time.Time) []notion.Block {
+	re := regexp.MustCompile(regexp.QuoteMeta(prefix) + `(today|\d{4}-\d{2}-\d{2})\b`)
+	return transformRichText(blocks, func(richText []notion.RichText) []notion.RichText {
+		return replaceInTextRuns(richText, re, func(content string, loc []int) *notion.RichText {
+			if precededByWordChar(content, loc[0]) {
+				return nil
+			}
+			value := content[loc[2]:loc[3]]
+			if value == "today" {
+				value = now.Format("2006-01-02")
+			}
+			start, err := notion.ParseDateTime(value)
+			if err != nil {
+				debugf(ctx, "[DEBUG] Leaving invalid date mention '%s' as text\n", content[loc[0]:loc[1]])
+				return nil
+			}
+			return &notion.RichText{
+				Type:      notion.RichTextTypeMention,
+				PlainText: value,
+				Mention: &notion.Mention{
+					Type: notion.MentionTypeDate,
+					Date: &notion.Date{Start: start},
+				},
+			}
+		})
+	})
+}
+
+// Regular expression to find @handle user references
+var userHandleRegex = regexp.MustCompile(`@([A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*)`)
+
+// LoadUserMap reads a JSON object mapping handles to Notion user IDs
+func LoadUserMap(ctx context.Context, path string) (map[string]string, error) {
+	data, err := readInputFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading user map file: %w", err)
+	}
+	var users map[string]string
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("Error decoding user map file: %w", err)
+	}
+	return users, nil
+}
+
+// applyUserMentions converts @handle references into Notion user mentions using the
+// handle to user ID map. Unknown handles stay as text with a warning.
+func applyUserMentions(ctx context.Context, blocks []notion.Block, users map[string]string) []notion.Block {
+	return transformRichText(blocks, func(richText []notion.RichText) []notion.RichText {
+		return replaceInTextRuns(richText, userHandleRegex, func(content string, loc []int) *notion.RichText {
+			if precededByWordChar(content, loc[0]) {
+				return nil
+			}
+			handle := content[loc[2]:loc[3]]
+			userID, ok := users[handle]
+			if !ok {
+				warnf(ctx, "Unknown user handle '@%s', leaving it as text\n", handle)
+				return nil
+			}
+			return &notion.RichText{
+				Type:      notion.RichTextTypeMention,
+				PlainText: "@" + handle,
+				Mention: &notion.Mention{
+					Type: notion.MentionTypeUser,
+					User: &notion.User{BaseUser: notion.BaseUser{ID: userID}, Type: notion.UserTypePerson},
+				},
+			}
+		})
+	})
+}
+
+// notionPageIDPattern matches a Notion page ID, 32 hex digits with or without UUID dashes
+const notionPageIDPattern = `[0-9a-fA-F]{32}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+
+// Regular expression to find the page ID of a notion://page/<id> link
+var notionPageURIRegex = regexp.MustCompile(`^/(` + notionPageIDPattern + `)/?$`)
+
+// Regular expression to find the page ID ending the path of a Notion page URL, after the title
+// slug: https://www.notion.so/workspace/Design-Doc-0123456789abcdef0123456789abcdef
+var notionPageURLRegex = regexp.MustCompile(`(?:/|-)(` + notionPageIDPattern + `)/?$`)
+
+// notionPageLink returns the ID of the Notion page a link points to, either a notion://page/<id>
+// link or a Notion page URL (notion.so, notion.site). URLs pointing at a block of the page, with
+// a #fragment, stay links.
+func notionPageLink(link string) (string, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	var match []string
+	switch host := strings.ToLower(parsed.Hostname()); {
+	case parsed.Scheme == "notion" && host == "page":
+		match = notionPageURIRegex.FindStringSubmatch(parsed.Path)
+	case (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Fragment == "" &&
+		(host == "notion.so" || host == "www.notion.so" || strings.HasSuffix(host, ".notion.site")):
+		match = notionPageURLRegex.FindStringSubmatch(parsed.Path)
+	}
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// applyPageMentions converts links to Notion pages into page mentions, see notionPageLink.
+// Consecutive runs of the same link, such as link text that is partly bold, become a single
+// mention. Other links are left alone.
+func applyPageMentions(ctx context.Context, blocks []notion.Block) []notion.Block {
+	return transformRichText(blocks, func(richText []notion.RichText) []notion.RichText {
+		result := make([]notion.RichText, 0, len(richText))
+		for i := 0; i < len(richText); i++ {
+			rt := richText[i]
+			if rt.Text == nil || rt.Text.Link == nil {
+				result = append(result, rt)
+				continue
+			}
+			link := rt.Text.Link.URL
+			pageID, ok := notionPageLink(link)
+			if !ok {
+				result = append(result, rt)
+				continue
+			}
+			text := rt.Text.Content
+			for i+1 < len(richText) && richText[i+1].Text != nil && richText[i+1].Text.Link != nil && richText[i+1].Text.Link.URL == link {
+				i++
+				text += richText[i].Text.Content
+			}
+			debugf(ctx, "[DEBUG] Converting link '%s' to a mention of page %s\n", link, pageID)
+			result = append(result, notion.RichText{
+				Type:        notion.RichTextTypeMention,
+				PlainText:   text,
+				Annotations: rt.Annotations,
+				Mention: &notion.Mention{
+					Type: notion.MentionTypePage,
+					Page: &notion.ID{ID: pageID},
+				},
+			})
+		}
+		return result
+	})
+}
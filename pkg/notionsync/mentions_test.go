@@ -0,0 +1,130 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestPageMentions(t *testing.T) {
+	const pageID = "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name       string
+		markdown   string
+		wantPageID string
+	}{
+		{"notion URL", "[Design](https://www.notion.so/team/Design-" + pageID + ")", pageID},
+		{"notion site URL", "[Design](https://team.notion.site/" + pageID + ")", pageID},
+		{"notion URI", "[Design](notion://page/" + pageID + ")", pageID},
+		{"notion URI with dashes", "[Design](notion://page/01234567-89ab-cdef-0123-456789abcdef)", "01234567-89ab-cdef-0123-456789abcdef"},
+		{"block of a notion page", "[Design](https://www.notion.so/Design-" + pageID + "#abc)", ""},
+		{"external link", "[Example](https://example.com/" + pageID + ")", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := convertMarkdown(context.Background(), tt.markdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks = applyPageMentions(context.Background(), blocks)
+			richText := blockRichText(blocks[0])
+			if len(richText) != 1 {
+				t.Fatalf("got %d rich texts, want 1: %#v", len(richText), richText)
+			}
+			rt := richText[0]
+			if tt.wantPageID == "" {
+				if rt.Type == notion.RichTextTypeMention || rt.Text == nil || rt.Text.Link == nil {
+					t.Errorf("rich text = %#v, want the link kept", rt)
+				}
+				return
+			}
+			if rt.Mention == nil || rt.Mention.Page == nil || rt.Mention.Page.ID != tt.wantPageID {
+				t.Fatalf("rich text = %#v, want a mention of page %s", rt, tt.wantPageID)
+			}
+			if rt.PlainText != "Design" {
+				t.Errorf("plain text = %q, want the link text", rt.PlainText)
+			}
+		})
+	}
+}
+
+func TestDateMentions(t *testing.T) {
+	now := time.Date(2024, 3, 9, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		markdown string
+		wantDate string
+		wantText []string
+	}{
+		{"today", "Due @today.", "2024-03-09", []string{"Due ", "2024-03-09", "."}},
+		{"explicit date", "Shipped @2024-01-15 to users", "2024-01-15", []string{"Shipped ", "2024-01-15", " to users"}},
+		{"invalid date", "Due @2024-13-45 maybe", "", []string{"Due @2024-13-45 maybe"}},
+		{"email address", "Mail me@today.com", "", []string{"Mail me@today.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			blocks := applyDateMentions(ctx, convert(t, tt.markdown), "@", now)
+			richText := blockRichText(blocks[0])
+			var texts []string
+			var dates []string
+			for _, rt := range richText {
+				texts = append(texts, rt.PlainText)
+				if rt.Mention != nil && rt.Mention.Type == notion.MentionTypeDate {
+					dates = append(dates, rt.Mention.Date.Start.Format("2006-01-02"))
+				}
+			}
+			if !slices.Equal(texts, tt.wantText) {
+				t.Errorf("rich text = %q, want %q", texts, tt.wantText)
+			}
+			if tt.wantDate == "" {
+				if len(dates) != 0 {
+					t.Errorf("date mentions = %v, want none", dates)
+				}
+			} else if !slices.Equal(dates, []string{tt.wantDate}) {
+				t.Errorf("date mentions = %v, want %s", dates, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestUserMentions(t *testing.T) {
+	users := map[string]string{"alice": "user-alice", "bob.smith": "user-bob"}
+	tests := []struct {
+		name         string
+		markdown     string
+		wantText     []string
+		wantUsers    []string
+		wantWarnings int
+	}{
+		{"known handle", "Ask @alice today", []string{"Ask ", "@alice", " today"}, []string{"user-alice"}, 0},
+		{"dotted handle", "cc @bob.smith.", []string{"cc ", "@bob.smith", "."}, []string{"user-bob"}, 0},
+		{"unknown handle", "Ask @carol today", []string{"Ask @carol today"}, nil, 1},
+		{"email address", "Mail alice@example.com", []string{"Mail alice@example.com"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			blocks := applyUserMentions(ctx, convert(t, tt.markdown), users)
+			var texts, ids []string
+			for _, rt := range blockRichText(blocks[0]) {
+				texts = append(texts, rt.PlainText)
+				if rt.Mention != nil && rt.Mention.Type == notion.MentionTypeUser {
+					ids = append(ids, rt.Mention.User.ID)
+				}
+			}
+			if !slices.Equal(texts, tt.wantText) {
+				t.Errorf("rich text = %q, want %q", texts, tt.wantText)
+			}
+			if !slices.Equal(ids, tt.wantUsers) {
+				t.Errorf("user mentions = %v, want %v", ids, tt.wantUsers)
+			}
+			if got := warningCount(ctx); got != tt.wantWarnings {
+				t.Errorf("%d warnings, want %d", got, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Regular expression to find a top level "key: value" frontmatter line
+var frontmatterLineRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+[ \t]*:`)
+
+// splitDocuments splits a file concatenating several markdown documents, each opening with its
+// own frontmatter, into the documents. A "---" line only starts a document if it opens a block
+// of frontmatter lines closed by another "---", so thematic breaks inside a document are kept.
+func splitDocuments(content []byte) ([][]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	var starts []int
+	for i := 0; i < len(lines); i++ {
+		if end := frontmatterEnd(lines, i); end > 0 {
+			starts = append(starts, i)
+			i = end
+		}
+	}
+	if len(starts) == 0 || starts[0] != 0 {
+		return nil, errors.New("a multi-document file must start with frontmatter")
+	}
+
+	documents := make([][]byte, len(starts))
+	for n, start := range starts {
+		end := len(lines)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		documents[n] = []byte(strings.Join(lines[start:end], ""))
+	}
+	return documents, nil
+}
+
+// frontmatterEnd returns the index of the "---" line closing the frontmatter opened at
+// lines[start], or 0 if lines[start] doesn't open frontmatter
+func frontmatterEnd(lines []string, start int) int {
+	if strings.TrimRight(lines[start], " \t\n") != "---" {
+		return 0
+	}
+	keys := 0
+	for i := start + 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		switch {
+		case strings.TrimRight(line, " \t") == "---":
+			if keys == 0 {
+				return 0
+			}
+			return i
+		case frontmatterLineRegex.MatchString(line):
+			keys++
+		case strings.TrimSpace(line) == "", line[0] == ' ', line[0] == '\t', line[0] == '#':
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// SyncMultiDocument syncs every document of the multi-document file at mdPath to the page
+// declared in its frontmatter, prints a per-document summary and returns the exit code:
+// 1 if any document failed
+func SyncMultiDocument(ctx context.Context, opts SyncOptions, notionClient NotionClientInterface, mdPath string) int {
+	ctx = NewContext(ctx, opts)
+	content, err := readInputFile(ctx, mdPath)
+	if err != nil {
+		fmt.Fprintln(output(ctx), "Error reading markdown file:", err)
+		return 1
+	}
+	documents, err := splitDocuments(normalizeLineEndings(content))
+	if err != nil {
+		fmt.Fprintf(output(ctx), "Error splitting %s into documents: %s\n", mdPath, err)
+		return 1
+	}
+
+	var results []fileResult
+	for n, document := range documents {
+		if ctx.Err() != nil {
+			break
+		}
+		frontmatter, _ := parseFrontmatter(document)
+		result := fileResult{File: fmt.Sprintf("%s (document %d)", mdPath, n+1), PageID: frontmatter[frontmatterPageKey]}
+		if result.PageID == "" && !opts.Offline() {
+			result.Status, result.Err = "failed", fmt.Errorf("its frontmatter has no %s", frontmatterPageKey)
+			results = append(results, result)
+			continue
+		}
+		err := syncContent(ctx, opts, notionClient, mdPath, result.PageID, document)
+		if result.Status = syncStatus(err); result.Status == "failed" {
+			result.Err = err
+		}
+		results = append(results, result)
+	}
+	return printDirectorySummary(ctx, results, "documents")
+}
@@ -0,0 +1,109 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "two documents",
+			content: "---\nnotion_page: a\n---\n# A\n---\nnotion_page: b\n---\n# B\n",
+			want:    []string{"---\nnotion_page: a\n---\n# A\n", "---\nnotion_page: b\n---\n# B\n"},
+		},
+		{
+			name:    "thematic breaks stay in the document",
+			content: "---\nnotion_page: a\n---\nabove\n\n---\n\nbelow\n",
+			want:    []string{"---\nnotion_page: a\n---\nabove\n\n---\n\nbelow\n"},
+		},
+		{
+			name:    "frontmatter comments and indented values",
+			content: "---\n# a comment\ntags:\n  - x\n---\nbody\n",
+			want:    []string{"---\n# a comment\ntags:\n  - x\n---\nbody\n"},
+		},
+		{
+			name:    "content before the first frontmatter",
+			content: "# Stray\n---\nnotion_page: a\n---\n# A\n",
+			wantErr: true,
+		},
+		{
+			name:    "no frontmatter",
+			content: "# Just markdown\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := splitDocuments([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(documents) != len(tt.want) {
+				t.Fatalf("got %d documents, want %d", len(documents), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := string(documents[i]); got != want {
+					t.Errorf("document %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncMultiDocument(t *testing.T) {
+	const markdown = "---\nnotion_page: page-a\n---\n# First\n\nalpha\n\n---\n\nmore alpha\n" +
+		"---\nnotion_page: page-b\ntags: [x]\n---\n# Second\n\nbeta\n"
+
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.StatusOutput = &out
+	client := newFakeNotionClient()
+	mdPath := writeMarkdown(t, markdown)
+	if code := SyncMultiDocument(context.Background(), opts, client, mdPath); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n%s", code, out.String())
+	}
+	for pageID, title := range map[string]string{"page-a": "First", "page-b": "Second"} {
+		if got := client.titles[pageID]; got != title {
+			t.Errorf("%s title = %q, want %q", pageID, got, title)
+		}
+	}
+	if got := blockTypes(client.content["page-a"]); strings.Join(got, ",") != "notion.ParagraphBlock,notion.DividerBlock,notion.ParagraphBlock" {
+		t.Errorf("page-a content = %v, want both paragraphs around the divider", got)
+	}
+	if got := blockTypes(client.content["page-b"]); len(got) != 1 || ownText(client.content["page-b"][0]) != "beta" {
+		t.Errorf("page-b content = %v, want only the second document", got)
+	}
+	if summary := out.String(); !strings.Contains(summary, "2 succeeded, 0 failed, 0 skipped") {
+		t.Errorf("summary:\n%s", summary)
+	}
+}
+
+func TestSyncMultiDocumentMissingPage(t *testing.T) {
+	const markdown = "---\nnotion_page: page-a\n---\n# First\n" +
+		"---\ntitle: no page\n---\n# Second\n"
+
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.StatusOutput = &out
+	client := newFakeNotionClient()
+	if code := SyncMultiDocument(context.Background(), opts, client, writeMarkdown(t, markdown)); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if client.titles["page-a"] != "First" {
+		t.Errorf("the first document wasn't synced: %v", client.titles)
+	}
+	summary := out.String()
+	for _, want := range []string{"(document 2): its frontmatter has no notion_page", "1 succeeded, 1 failed, 0 skipped"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,977 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// NotionClientInterface is every Notion call the sync makes. SyncFile, SyncDirectory and
+// SyncMultiDocument only talk to Notion through it, so any implementation, such as a fake
+// recording the calls, can stand in for NotionClient.
+type NotionClientInterface interface {
+	UploadFile(ctx context.Context, filePath string) (fileID string, err error)
+	AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) (blockIDs []string, err error)
+	ReplaceSection(ctx context.Context, pageID, afterID string, oldIDs []string, blocks []notion.Block) (blockIDs []string, err error)
+	ClearPageContent(ctx context.Context, pageID string) error
+	ClearPageContentAfter(ctx context.Context, pageID string, keep int) error
+	UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error
+	GetProperty(ctx context.Context, pageID, propName string) (string, error)
+	SetProperty(ctx context.Context, pageID, propName, value string) error
+	SetProperties(ctx context.Context, pageID string, values map[string]string) error
+	GetLastEdit(ctx context.Context, pageID string) (PageEdit, error)
+	GetPageContent(ctx context.Context, pageID string) ([]notion.Block, error)
+	VerifyPage(ctx context.Context, pageID string) error
+	GetStoredHash(ctx context.Context, pageID, storage string) (string, error)
+	SetStoredHash(ctx context.Context, pageID, storage, hash string) error
+	CreateChildPage(ctx context.Context, parentID, title, icon string, blocks []notion.Block) (string, error)
+	CreateDatabasePage(ctx context.Context, databaseID string, title []notion.RichText, values map[string]string, blocks []notion.Block) (string, error)
+	GetChildPages(ctx context.Context, parentID string) (map[string]string, error)
+	AddComment(ctx context.Context, pageID, text string) error
+	AddBlockComment(ctx context.Context, blockID string, richText []notion.RichText) error
+	SetPageIconAndCover(ctx context.Context, pageID, icon, cover string) error
+}
+
+var (
+	_ NotionClientInterface = (*NotionClient)(nil)
+	_ NotionClientInterface = OfflineNotionClient{}
+	_ NotionClientInterface = reportingClient{}
+)
+
+// OfflineNotionClient satisfies NotionClientInterface without touching the network.
+// Uploads resolve to a placeholder ID, every other call fails.
+type OfflineNotionClient struct{}
+
+var errOffline = fmt.Errorf("no Notion access in offline mode")
+
+func (OfflineNotionClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	return "offline-" + filepath.Base(filePath), nil
+}
+
+func (OfflineNotionClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	return nil, errOffline
+}
+
+func (OfflineNotionClient) ReplaceSection(ctx context.Context, pageID, afterID string, oldIDs []string, blocks []notion.Block) ([]string, error) {
+	return nil, errOffline
+}
+
+func (OfflineNotionClient) ClearPageContent(ctx context.Context, pageID string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) ClearPageContentAfter(ctx context.Context, pageID string, keep int) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) SetPageIconAndCover(ctx context.Context, pageID, icon, cover string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) GetProperty(ctx context.Context, pageID, propName string) (string, error) {
+	return "", errOffline
+}
+
+func (OfflineNotionClient) SetProperty(ctx context.Context, pageID, propName, value string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) SetProperties(ctx context.Context, pageID string, values map[string]string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) GetLastEdit(ctx context.Context, pageID string) (PageEdit, error) {
+	return PageEdit{}, errOffline
+}
+
+func (OfflineNotionClient) GetPageContent(ctx context.Context, pageID string) ([]notion.Block, error) {
+	return nil, errOffline
+}
+
+func (OfflineNotionClient) VerifyPage(ctx context.Context, pageID string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) GetStoredHash(ctx context.Context, pageID, storage string) (string, error) {
+	return "", errOffline
+}
+
+func (OfflineNotionClient) SetStoredHash(ctx context.Context, pageID, storage, hash string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) CreateChildPage(ctx context.Context, parentID, title, icon string, blocks []notion.Block) (string, error) {
+	return "", errOffline
+}
+
+func (OfflineNotionClient) CreateDatabasePage(ctx context.Context, databaseID string, title []notion.RichText, values map[string]string, blocks []notion.Block) (string, error) {
+	return "", errOffline
+}
+
+func (OfflineNotionClient) GetChildPages(ctx context.Context, parentID string) (map[string]string, error) {
+	return nil, errOffline
+}
+
+func (OfflineNotionClient) AddComment(ctx context.Context, pageID, text string) error {
+	return errOffline
+}
+
+func (OfflineNotionClient) AddBlockComment(ctx context.Context, blockID string, richText []notion.RichText) error {
+	return errOffline
+}
+
+type NotionClient struct {
+	NotionToken  string
+	NotionClient *notion.Client
+	NotionHTTP   *NotionHTTP
+
+	// UploadFieldName is the multipart form field carrying the file content
+	UploadFieldName string
+	// UploadFormFields are extra form fields sent alongside the file content
+	UploadFormFields map[string]string
+	// UploadTimeout bounds each file content upload request, zero means no timeout
+	UploadTimeout time.Duration
+	// UploadRetries is how many times a failed file content upload is retried
+	UploadRetries int
+	// TitleOverflow controls over-long titles: "truncate" (default) or "error"
+	TitleOverflow string
+	// UploadCache reuses the file uploads of unchanged files, nil uploads every time
+	UploadCache *UploadCache
+
+	// schemas caches the database schemas fetched by GetDatabaseSchema, pageDatabases the
+	// database each page is in ("" for pages outside a database)
+	schemas       map[string]notion.DatabaseProperties
+	pageDatabases map[string]string
+	// pages caches the pages fetched by getPageCached until they are updated
+	pages map[string]notion.Page
+
+	// botUserID is the integration's own user, looked up by GetLastEdit
+	botUserID string
+}
+
+// maxTitleLength is the longest text Notion accepts in a single title rich text
+const maxTitleLength = 2000
+
+type fileUploadResponse struct {
+	ID        string `json:"id"`
+	UploadURL string `json:"upload_url"`
+}
+
+// NewNotionClient returns a client whose go-notion requests go through the same auth header
+// and rate limiter settings as its raw HTTP requests. version is the Notion-Version of the raw
+// HTTP requests, go-notion sends the version it was built for.
+func NewNotionClient(token, version string) *NotionClient {
+	notionHTTP := NewNotionHTTP(token, version)
+	httpClient := &http.Client{Transport: notionTransport{http: notionHTTP}}
+	return &NotionClient{
+		NotionToken:  token,
+		NotionClient: notion.NewClient(token, notion.WithHTTPClient(httpClient)),
+		NotionHTTP:   notionHTTP,
+
+		UploadFieldName: "file",
+		TitleOverflow:   "truncate",
+	}
+}
+
+// SetAuthHeader sends the token in the given header and format, for both the go-notion
+// client and the raw HTTP requests
+func (c *NotionClient) SetAuthHeader(name, format string) {
+	c.NotionHTTP.AuthHeader = name
+	c.NotionHTTP.AuthFormat = format
+}
+
+func (c *NotionClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	filename := filepath.Base(filePath)
+
+	var hash string
+	if c.UploadCache != nil {
+		var cachedID string
+		if cachedID, hash = c.cachedFileUpload(ctx, filePath); cachedID != "" {
+			fmt.Fprintf(output(ctx), "File %s is unchanged, reusing file upload %s\n", filePath, cachedID)
+			return cachedID, nil
+		}
+	}
+
+	uploadResp, err := c.createFileUploadObject(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file upload object: %w", err)
+	}
+
+	err = c.uploadFileContent(ctx, uploadResp.UploadURL, filePath, filename)
+	if errors.Is(err, errUploadExpired) {
+		// The upload URL is only valid for a while, start over once with a fresh one
+		fmt.Fprintf(output(ctx), "Upload URL for %s expired, creating a new file upload\n", filename)
+		if uploadResp, err = c.createFileUploadObject(ctx); err != nil {
+			return "", fmt.Errorf("failed to create file upload object: %w", err)
+		}
+		err = c.uploadFileContent(ctx, uploadResp.UploadURL, filePath, filename)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file content: %w", err)
+	}
+
+	if hash != "" {
+		if err := c.UploadCache.store(hash, uploadResp.ID); err != nil {
+			warnf(ctx, "Failed to write upload cache '%s': %s\n", c.UploadCache.Path, err)
+		}
+	}
+	return uploadResp.ID, nil
+}
+
+func getFileContentType(filePath string) string {
+	ext := filepath.Ext(filePath)
+	mimeType := mime.TypeByExtension(ext)
+	if mimeType != "" {
+		return mimeType
+	}
+	// Fallback: try to sniff the content
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+	buf := make([]byte, 512)
+	n, _ := file.Read(buf)
+	return http.DetectContentType(buf[:n])
+}
+
+func (c *NotionClient) createFileUploadObject(ctx context.Context) (*fileUploadResponse, error) {
+	emptyBody := []byte("{}")
+	resp, err := c.NotionHTTP.Post(ctx, "https://api.notion.com/v1/file_uploads", emptyBody, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Notion API error %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	var uploadResp fileUploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
+		return nil, err
+	}
+	return &uploadResp, nil
+}
+
+func (c *NotionClient) uploadFileContent(ctx context.Context, uploadURL, filePath, filename string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var requestBodyBuf bytes.Buffer
+	writer := multipart.NewWriter(&requestBodyBuf)
+	if err := c.writeUploadFormFields(writer); err != nil {
+		return err
+	}
+	fieldName := c.UploadFieldName
+	if fieldName == "" {
+		fieldName = "file"
+	}
+	contentType := getFileContentType(filePath)
+	headers := make(textproto.MIMEHeader)
+	headers.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, fieldName, filename))
+	headers.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(headers)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(output(ctx), "Uploading file %s to %s\n", filePath, uploadURL)
+	if err := c.postUpload(ctx, uploadURL, requestBodyBuf.Bytes(), writer.FormDataContentType()); err != nil {
+		return err
+	}
+	fmt.Fprintf(output(ctx), "File %s uploaded successfully\n", filePath)
+	return nil
+}
+
+// postUpload sends the multipart upload body using the upload specific timeout and retries.
+// Network errors, rate limiting and server errors are retried, other failures are returned immediately.
+func (c *NotionClient) postUpload(ctx context.Context, uploadURL string, body []byte, contentType string) error {
+	uploadHTTP := *c.NotionHTTP
+	uploadHTTP.MaxRetries = 0
+	uploadHTTP.Client = &http.Client{
+		Transport: c.NotionHTTP.Client.Transport,
+		Timeout:   c.UploadTimeout,
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.UploadRetries; attempt++ {
+		if attempt > 0 {
+			debugf(ctx, "[DEBUG] Retrying upload (attempt %d of %d) after: %s\n", attempt+1, c.UploadRetries+1, lastErr)
+			if err := sleep(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return err
+			}
+		}
+		resp, err := uploadHTTP.Post(ctx, uploadURL, body, contentType)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		lastErr = fmt.Errorf("upload error %d: %s", resp.StatusCode, string(bodyBytes))
+		if isUploadExpired(resp.StatusCode, bodyBytes) {
+			return fmt.Errorf("%w: %s", errUploadExpired, lastErr)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// errUploadExpired is returned when an upload URL is no longer valid and a new file upload is needed
+var errUploadExpired = errors.New("file upload expired")
+
+// isUploadExpired reports whether an upload response says the file upload object has expired
+func isUploadExpired(statusCode int, body []byte) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusGone:
+		return bytes.Contains(bytes.ToLower(body), []byte("expired"))
+	}
+	return false
+}
+
+// writeUploadFormFields writes the configured extra form fields in a stable order
+func (c *NotionClient) writeUploadFormFields(writer *multipart.Writer) error {
+	names := make([]string, 0, len(c.UploadFormFields))
+	for name := range c.UploadFormFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writer.WriteField(name, c.UploadFormFields[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPageContent adds blocks to a Notion page, returning the IDs Notion gave the new top level blocks.
+// Notion accepts at most maxBlocksPerRequest children per request, so longer content is appended
+// in order by consecutive requests, stopping at the first that fails.
+func (c *NotionClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	chunks := chunkBlocks(blocks)
+	if len(chunks) == 1 {
+		return c.appendBlockChildren(ctx, pageID, "", blocks)
+	}
+	blockIDs := make([]string, 0, len(blocks))
+	for i, chunk := range chunks {
+		start := i * maxBlocksPerRequest
+		debugf(ctx, "[DEBUG] Appending chunk %d/%d (blocks %d-%d of %d)\n", i+1, len(chunks), start+1, start+len(chunk), len(blocks))
+		ids, err := c.appendBlockChildren(ctx, pageID, "", chunk)
+		if err != nil {
+			return blockIDs, fmt.Errorf("chunk %d/%d (blocks %d-%d): %w", i+1, len(chunks), start+1, start+len(chunk), err)
+		}
+		blockIDs = append(blockIDs, ids...)
+	}
+	return blockIDs, nil
+}
+
+// chunkBlocks splits blocks into the groups AddPageContent sends, at most maxBlocksPerRequest
+// each. No blocks make a single empty group, as they are still sent as one request.
+func chunkBlocks(blocks []notion.Block) [][]notion.Block {
+	if len(blocks) == 0 {
+		return [][]notion.Block{blocks}
+	}
+	var chunks [][]notion.Block
+	for start := 0; start < len(blocks); start += maxBlocksPerRequest {
+		chunks = append(chunks, blocks[start:min(start+maxBlocksPerRequest, len(blocks))])
+	}
+	return chunks
+}
+
+// appendChildrenURL is the endpoint AddPageContent sends a page's new blocks to
+func appendChildrenURL(pageID string) string {
+	return fmt.Sprintf("https://api.notion.com/v1/blocks/%s/children", pageID)
+}
+
+// appendChildrenBody builds the request body appending blocks as children, after the block with
+// the ID after or at the end if it is empty
+func appendChildrenBody(blocks []notion.Block, after string) ([]byte, error) {
+	body := map[string]interface{}{
+		"children": blocks,
+	}
+	if after != "" {
+		body["after"] = after
+	}
+	return json.Marshal(body)
+}
+
+// appendBlockChildren appends up to maxBlocksPerRequest blocks to a page in a single request,
+// after the block with the ID after or at the end if it is empty. Children nested deeper than
+// Notion accepts are appended to their parent blocks in follow-up requests.
+func (c *NotionClient) appendBlockChildren(ctx context.Context, pageID, after string, blocks []notion.Block) ([]string, error) {
+	deep := detachDeepChildren(blocks, nil)
+	defer reattachDeepChildren(blocks, deep)
+	blockIDs, err := c.sendBlockChildren(ctx, pageID, after, blocks)
+	if err != nil || len(deep) == 0 {
+		return blockIDs, err
+	}
+	return blockIDs, c.appendDeepChildren(ctx, blockIDs, deep)
+}
+
+// appendDeepChildren appends the children detached by detachDeepChildren to their parent
+// blocks, now that those exist. blockIDs are the IDs of the top level blocks, the IDs of the
+// nested parents are looked up by listing their ancestors' children.
+func (c *NotionClient) appendDeepChildren(ctx context.Context, blockIDs []string, detached []deepChildren) error {
+	listed := make(map[string][]notion.Block)
+	for _, deep := range detached {
+		if deep.path[0] >= len(blockIDs) {
+			return fmt.Errorf("Notion returned %d block IDs, can't append the children of block %d", len(blockIDs), deep.path[0]+1)
+		}
+		parentID := blockIDs[deep.path[0]]
+		for _, index := range deep.path[1:] {
+			children, ok := listed[parentID]
+			if !ok {
+				var err error
+				if children, err = c.listPageChildren(ctx, parentID); err != nil {
+					return err
+				}
+				listed[parentID] = children
+			}
+			if index >= len(children) {
+				return fmt.Errorf("block %s has %d children, expected at least %d", parentID, len(children), index+1)
+			}
+			parentID = children[index].ID()
+		}
+		debugf(ctx, "[DEBUG] Appending %d blocks nested too deep for a single request to block %s\n", len(deep.children), parentID)
+		if _, err := c.AddPageContent(ctx, parentID, deep.children); err != nil {
+			return fmt.Errorf("failed to append nested blocks to block %s: %w", parentID, err)
+		}
+	}
+	return nil
+}
+
+// sendBlockChildren sends the request appending blocks, which must not be nested deeper than
+// Notion accepts, retrying without native image sizes if Notion rejects them
+func (c *NotionClient) sendBlockChildren(ctx context.Context, pageID, after string, blocks []notion.Block) ([]string, error) {
+	url := appendChildrenURL(pageID)
+	jsonData, err := appendChildrenBody(blocks, after)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.NotionHTTP.Patch(ctx, url, jsonData, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusBadRequest && hasSizedImages(blocks) && isSizingRejected(string(b)) {
+			warnf(ctx, "Notion rejected native image sizes, adding them to the captions instead\n")
+			return c.sendBlockChildren(ctx, pageID, after, withoutSizedImages(blocks))
+		}
+		if resp.StatusCode == http.StatusBadRequest && c.UploadCache != nil && isFileUploadRejected(string(b)) {
+			// A cached file upload passed its check but Notion won't attach it, upload those files again
+			reuploaded, ok, err := c.reuploadCachedImages(ctx, blocks, make(map[string]string))
+			if err != nil {
+				return nil, fmt.Errorf("failed to upload images again after Notion rejected their cached file uploads: %w", err)
+			}
+			if ok {
+				warnf(ctx, "Notion rejected cached file uploads, uploaded the images again\n")
+				return c.sendBlockChildren(ctx, pageID, after, reuploaded)
+			}
+		}
+		fmt.Fprintf(output(ctx), "Body: %s\n", jsonData)
+		return nil, fmt.Errorf("Notion API error %d: %s", resp.StatusCode, string(b))
+	}
+	var created struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		debugf(ctx, "[DEBUG] Could not decode appended blocks: %s\n", err)
+		return nil, nil
+	}
+	blockIDs := make([]string, 0, len(created.Results))
+	for _, block := range created.Results {
+		blockIDs = append(blockIDs, block.ID)
+	}
+	return blockIDs, nil
+}
+
+// ReplaceSection swaps the blocks with the IDs oldIDs for blocks, inserting them right after the
+// block afterID before deleting the old ones. Returns the IDs of the inserted blocks.
+func (c *NotionClient) ReplaceSection(ctx context.Context, pageID, afterID string, oldIDs []string, blocks []notion.Block) ([]string, error) {
+	var blockIDs []string
+	if len(blocks) > 0 {
+		after := afterID
+		for i, chunk := range chunkBlocks(blocks) {
+			ids, err := c.appendBlockChildren(ctx, pageID, after, chunk)
+			if err != nil {
+				return blockIDs, fmt.Errorf("chunk %d: %w", i+1, err)
+			}
+			if len(ids) != len(chunk) {
+				return blockIDs, fmt.Errorf("chunk %d: Notion returned %d block IDs for %d blocks", i+1, len(ids), len(chunk))
+			}
+			blockIDs = append(blockIDs, ids...)
+			after = ids[len(ids)-1]
+		}
+	}
+	for _, id := range oldIDs {
+		if _, err := c.NotionClient.DeleteBlock(ctx, id); err != nil {
+			return blockIDs, fmt.Errorf("failed to delete block %s: %w", id, err)
+		}
+	}
+	return blockIDs, nil
+}
+
+// ClearPageContent deletes all child blocks of the given page
+func (c *NotionClient) ClearPageContent(ctx context.Context, pageID string) error {
+	startCursor := ""
+	for {
+		resp, err := c.NotionClient.FindBlockChildrenByID(ctx, pageID, &notion.PaginationQuery{StartCursor: startCursor})
+		if err != nil {
+			return fmt.Errorf("failed to fetch children: %w", err)
+		}
+		for _, block := range resp.Results {
+			_, err := c.NotionClient.DeleteBlock(ctx, block.ID())
+			if err != nil {
+				return fmt.Errorf("failed to delete block %s: %w", block.ID(), err)
+			}
+		}
+		if !resp.HasMore || resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		startCursor = *resp.NextCursor
+	}
+	return nil
+}
+
+// ClearPageContentAfter deletes the child blocks of the given page except the first keep blocks
+func (c *NotionClient) ClearPageContentAfter(ctx context.Context, pageID string, keep int) error {
+	blocks, err := c.listPageChildren(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if keep > len(blocks) {
+		warnf(ctx, "Page has only %d blocks, preserving all of them instead of the first %d\n", len(blocks), keep)
+		return nil
+	}
+	for _, block := range blocks[keep:] {
+		if _, err := c.NotionClient.DeleteBlock(ctx, block.ID()); err != nil {
+			return fmt.Errorf("failed to delete block %s: %w", block.ID(), err)
+		}
+	}
+	return nil
+}
+
+// notionPageURL returns the web URL of a page from its ID
+func notionPageURL(pageID string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(pageID, "-", "")
+}
+
+// listPageChildren fetches all top level child blocks of the given page
+func (c *NotionClient) listPageChildren(ctx context.Context, pageID string) ([]notion.Block, error) {
+	var blocks []notion.Block
+	startCursor := ""
+	for {
+		resp, err := c.NotionClient.FindBlockChildrenByID(ctx, pageID, &notion.PaginationQuery{StartCursor: startCursor})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch children: %w", err)
+		}
+		blocks = append(blocks, resp.Results...)
+		if !resp.HasMore || resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		startCursor = *resp.NextCursor
+	}
+	return blocks, nil
+}
+
+// GetPageContent returns the page's top level blocks
+func (c *NotionClient) GetPageContent(ctx context.Context, pageID string) ([]notion.Block, error) {
+	return c.listPageChildren(ctx, pageID)
+}
+
+// GetStoredHash reads the content hash from the page's metadata block, returning "" when there is none
+func (c *NotionClient) GetStoredHash(ctx context.Context, pageID, storage string) (string, error) {
+	blocks, err := c.listPageChildren(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if hash, ok := readHashBlock(storage, blocks[i]); ok {
+			return hash, nil
+		}
+	}
+	return "", nil
+}
+
+// SetStoredHash replaces any metadata block on the page with a new trailing one holding hash
+func (c *NotionClient) SetStoredHash(ctx context.Context, pageID, storage, hash string) error {
+	hashBlock, err := newHashBlock(storage, hash)
+	if err != nil {
+		return err
+	}
+	blocks, err := c.listPageChildren(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if _, ok := readHashBlock(storage, block); !ok {
+			continue
+		}
+		if _, err := c.NotionClient.DeleteBlock(ctx, block.ID()); err != nil {
+			return fmt.Errorf("failed to delete hash block %s: %w", block.ID(), err)
+		}
+	}
+	_, err = c.AddPageContent(ctx, pageID, []notion.Block{hashBlock})
+	return err
+}
+
+// CreateChildPage creates a page titled title under parentID holding blocks, returning the new
+// page's ID. A non-empty icon is an emoji set as the page's icon.
+func (c *NotionClient) CreateChildPage(ctx context.Context, parentID, title, icon string, blocks []notion.Block) (string, error) {
+	title, err := fitTitle(ctx, title, c.TitleOverflow)
+	if err != nil {
+		return "", err
+	}
+	params := notion.CreatePageParams{
+		ParentType: notion.ParentTypePage,
+		ParentID:   parentID,
+		Title:      plainRichText(title),
+	}
+	if icon != "" {
+		params.Icon = &notion.Icon{Type: notion.IconTypeEmoji, Emoji: &icon}
+	}
+	page, err := c.NotionClient.CreatePage(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if len(blocks) > 0 {
+		if _, err := c.AddPageContent(ctx, page.ID, blocks); err != nil {
+			return "", err
+		}
+	}
+	return page.ID, nil
+}
+
+// CreateDatabasePage creates a page in the database titled title and holding blocks, returning
+// the new page's ID. The other properties are set from their text form like SetProperties does.
+func (c *NotionClient) CreateDatabasePage(ctx context.Context, databaseID string, title []notion.RichText, values map[string]string, blocks []notion.Block) (string, error) {
+	schema, err := c.GetDatabaseSchema(ctx, databaseID)
+	if err != nil {
+		return "", err
+	}
+	titleName, err := titleProperty(schema)
+	if err != nil {
+		return "", err
+	}
+	properties := schemaProperties(ctx, schema, values)
+	properties[titleName] = notion.DatabasePageProperty{Type: notion.DBPropTypeTitle, Title: title}
+	page, err := c.NotionClient.CreatePage(ctx, notion.CreatePageParams{
+		ParentType:             notion.ParentTypeDatabase,
+		ParentID:               databaseID,
+		DatabasePageProperties: &properties,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(blocks) > 0 {
+		if _, err := c.AddPageContent(ctx, page.ID, blocks); err != nil {
+			return "", err
+		}
+	}
+	return page.ID, nil
+}
+
+// GetChildPages returns the IDs of the pages directly under parentID keyed by title.
+// When titles repeat the first page wins.
+func (c *NotionClient) GetChildPages(ctx context.Context, parentID string) (map[string]string, error) {
+	blocks, err := c.listPageChildren(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]string)
+	for _, block := range blocks {
+		if page, ok := block.(*notion.ChildPageBlock); ok && page != nil {
+			if _, exists := pages[page.Title]; !exists {
+				pages[page.Title] = page.ID()
+			}
+		}
+	}
+	return pages, nil
+}
+
+// AddComment posts text as a page level comment
+func (c *NotionClient) AddComment(ctx context.Context, pageID, text string) error {
+	_, err := c.NotionClient.CreateComment(ctx, notion.CreateCommentParams{
+		ParentPageID: pageID,
+		RichText:     plainRichText(text),
+	})
+	return err
+}
+
+// AddBlockComment posts a comment on a block. go-notion can only comment on pages and
+// discussions, so the request is sent directly.
+func (c *NotionClient) AddBlockComment(ctx context.Context, blockID string, richText []notion.RichText) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"parent":    map[string]string{"block_id": blockID},
+		"rich_text": richText,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.NotionHTTP.Post(ctx, "https://api.notion.com/v1/comments", body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Notion API error %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// UpdatePageTitle updates the Notion page's title using a heading block
+func (c *NotionClient) UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error {
+	if headingLevel(titleBlock) == 0 {
+		return fmt.Errorf("titleBlock is not a heading block")
+	}
+	richText := blockRichText(titleBlock)
+	if len(richText) == 0 {
+		return fmt.Errorf("heading block has no rich text")
+	}
+	title, err := fitTitle(ctx, richText[0].PlainText, c.TitleOverflow)
+	if err != nil {
+		return err
+	}
+	schema, err := c.pageSchema(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	titleProp, err := titleProperty(schema)
+	if err != nil {
+		return err
+	}
+	_, err = c.NotionClient.UpdatePage(ctx, pageID, notion.UpdatePageParams{
+		DatabasePageProperties: notion.DatabasePageProperties{
+			titleProp: notion.DatabasePageProperty{
+				Type:  "title",
+				Title: []notion.RichText{{Text: &notion.Text{Content: title}}},
+			},
+		},
+	})
+	c.invalidatePage(pageID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetPageIconAndCover sets the icon and the cover of a page, leaving either untouched when it
+// is "". The icon is an emoji, an image URL or a local image file, the cover an image URL or a
+// local image file. Local files are uploaded first.
+func (c *NotionClient) SetPageIconAndCover(ctx context.Context, pageID, icon, cover string) error {
+	body := make(map[string]interface{})
+	if icon != "" {
+		if _, err := os.Stat(icon); err != nil && !isRemoteImage(icon) && !looksLikeImagePath(icon) {
+			body["icon"] = map[string]interface{}{"type": "emoji", "emoji": icon}
+		} else if body["icon"], err = c.pageImage(ctx, icon); err != nil {
+			return fmt.Errorf("Error uploading page icon: %w", err)
+		}
+	}
+	if cover != "" {
+		var err error
+		if body["cover"], err = c.pageImage(ctx, cover); err != nil {
+			return fmt.Errorf("Error uploading page cover: %w", err)
+		}
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf("https://api.notion.com/v1/pages/%s", pageID)
+	jsonData, _ := json.Marshal(body)
+	resp, err := c.NotionHTTP.Patch(ctx, url, jsonData, "application/json")
+	c.invalidatePage(pageID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Notion API error %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// pageImage returns the file object for a page icon or cover: an external file for an image
+// URL, else the local file uploaded through UploadFile
+func (c *NotionClient) pageImage(ctx context.Context, image string) (map[string]interface{}, error) {
+	if isRemoteImage(image) {
+		return map[string]interface{}{"type": "external", "external": map[string]interface{}{"url": image}}, nil
+	}
+	if _, err := os.Stat(image); err != nil {
+		return nil, fmt.Errorf("image file not found: %s", image)
+	}
+	fileID, err := c.UploadFile(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"type": "file_upload", "file_upload": map[string]interface{}{"id": fileID}}, nil
+}
+
+// looksLikeImagePath reports whether a page icon is meant as an image file rather than an
+// emoji: it contains a path separator or ends in an image file extension. A missing file is
+// then reported instead of being sent to Notion as an emoji.
+func looksLikeImagePath(icon string) bool {
+	if strings.ContainsAny(icon, `/\`) {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(icon))
+	if ext == "" {
+		return false
+	}
+	return strings.HasPrefix(mime.TypeByExtension(ext), "image/") || slices.Contains(slices.Collect(maps.Values(dataURIImageTypes)), ext)
+}
+
+// isRemoteImage reports whether an image reference is an http(s) URL rather than a local path
+func isRemoteImage(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// fitTitle enforces Notion's title length limit, counted in UTF-16 code units like textLength,
+// truncating with an ellipsis or failing depending on mode
+func fitTitle(ctx context.Context, title, mode string) (string, error) {
+	length := textLength(title)
+	if length <= maxTitleLength {
+		return title, nil
+	}
+	if mode == "error" {
+		return "", fmt.Errorf("title is %d characters, Notion allows at most %d", length, maxTitleLength)
+	}
+	warnf(ctx, "Title is %d characters, truncating to %d\n", length, maxTitleLength)
+	kept := splitAtBoundaries(title, maxTitleLength-1, func(rune) bool { return false })[0]
+	return kept + "…", nil
+}
+
+// VerifyPage marks a wiki page as verified by setting its "Verification" property.
+// Only pages in a Notion wiki have this property, others reject the request.
+func (c *NotionClient) VerifyPage(ctx context.Context, pageID string) error {
+	url := fmt.Sprintf("https://api.notion.com/v1/pages/%s", pageID)
+	body := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"Verification": map[string]interface{}{
+				"verification": map[string]interface{}{
+					"state": "verified",
+				},
+			},
+		},
+	}
+	jsonData, _ := json.Marshal(body)
+
+	resp, err := c.NotionHTTP.Patch(ctx, url, jsonData, "application/json")
+	c.invalidatePage(pageID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Notion API error %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// errNotDatabasePage is returned by GetProperty for pages outside a database, which have no
+// properties other than their title
+var errNotDatabasePage = errors.New("page is not in a database")
+
+// getPageCached returns the page, fetching it only the first time it is asked for since
+// it was last updated
+func (c *NotionClient) getPageCached(ctx context.Context, pageID string) (notion.Page, error) {
+	if page, ok := c.pages[pageID]; ok {
+		return page, nil
+	}
+	page, err := c.NotionClient.FindPageByID(ctx, pageID)
+	if err != nil {
+		return notion.Page{}, err
+	}
+	if c.pages == nil {
+		c.pages = make(map[string]notion.Page)
+	}
+	c.pages[pageID] = page
+	return page, nil
+}
+
+// invalidatePage drops the cached page after an update, so the next read sees the change
+func (c *NotionClient) invalidatePage(pageID string) {
+	delete(c.pages, pageID)
+}
+
+// GetProperty gets a rich_text property on the Notion page
+func (c *NotionClient) GetProperty(ctx context.Context, pageID, propName string) (string, error) {
+	page, err := c.getPageCached(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+	props, ok := page.Properties.(notion.DatabasePageProperties)
+	if !ok {
+		return "", errNotDatabasePage
+	}
+	prop, ok := props[propName]
+	if !ok {
+		return "", nil
+	}
+	if len(prop.RichText) > 0 {
+		return prop.RichText[0].PlainText, nil
+	}
+	return "", nil
+}
+
+// SetProperty sets a rich_text property on the Notion page, failing without writing anything
+// if the page's database has no rich_text property of that name
+func (c *NotionClient) SetProperty(ctx context.Context, pageID, propName, value string) error {
+	schema, err := c.pageSchema(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if err := checkPropertyType(schema, propName, notion.DBPropTypeRichText); err != nil {
+		return err
+	}
+	property, err := propertyValue(notion.DBPropTypeRichText, value)
+	if err != nil {
+		return err
+	}
+	_, err = c.NotionClient.UpdatePage(ctx, pageID, notion.UpdatePageParams{
+		DatabasePageProperties: notion.DatabasePageProperties{propName: property},
+	})
+	c.invalidatePage(pageID)
+	return err
+}
@@ -0,0 +1,642 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// recordedRequest is a request seen by recordingTransport
+type recordedRequest struct {
+	Method  string
+	Path    string
+	Version string
+	Header  http.Header
+	Body    string
+}
+
+// recordingTransport answers every request with status and body, recording the requests
+type recordingTransport struct {
+	requests []recordedRequest
+	status   int
+	body     string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	rt.requests = append(rt.requests, recordedRequest{Method: req.Method, Path: req.URL.Path, Version: req.Header.Get("Notion-Version"), Header: req.Header.Clone(), Body: string(body)})
+	status := rt.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	respBody := rt.body
+	if respBody == "" {
+		respBody = "{}"
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(respBody)),
+		Request:    req,
+	}, nil
+}
+
+// newRecordingClient returns a NotionClient whose raw HTTP requests go to a recordingTransport
+func newRecordingClient() (*NotionClient, *recordingTransport) {
+	rt := &recordingTransport{}
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: rt}
+	return c, rt
+}
+
+func TestUploadFileContentUsesFieldName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chart.png")
+	if err := os.WriteFile(path, []byte("png data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fieldName := range []string{"", "file", "upload"} {
+		t.Run(fieldName, func(t *testing.T) {
+			var gotField, gotFile, gotContent string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil {
+					t.Errorf("parsing content type: %s", err)
+					return
+				}
+				reader := multipart.NewReader(r.Body, params["boundary"])
+				part, err := reader.NextPart()
+				if err != nil {
+					t.Errorf("reading part: %s", err)
+					return
+				}
+				content, _ := io.ReadAll(part)
+				gotField, gotFile, gotContent = part.FormName(), part.FileName(), string(content)
+			}))
+			defer server.Close()
+
+			c := NewNotionClient("token", DefaultNotionVersion)
+			c.UploadFieldName = fieldName
+			ctx := NewContext(context.Background(), SyncOptions{StatusOutput: io.Discard})
+			if err := c.uploadFileContent(ctx, server.URL, path, "chart.png"); err != nil {
+				t.Fatal(err)
+			}
+
+			want := fieldName
+			if want == "" {
+				want = "file"
+			}
+			if gotField != want {
+				t.Errorf("field name = %q, want %q", gotField, want)
+			}
+			if gotFile != "chart.png" || gotContent != "png data" {
+				t.Errorf("file = %q with %q, want chart.png with the file's content", gotFile, gotContent)
+			}
+		})
+	}
+}
+
+func TestSetPageIconAndCover(t *testing.T) {
+	tests := []struct {
+		name     string
+		icon     string
+		wantIcon string
+		wantErr  string
+	}{
+		{"emoji", "🚀", `{"emoji":"🚀","type":"emoji"}`, ""},
+		{"remote image", "https://example.com/icon.png", `{"external":{"url":"https://example.com/icon.png"},"type":"external"}`, ""},
+		{"missing path", "images/icon.png", "", "file not found"},
+		{"missing image file name", "icon.svg", "", "file not found"},
+		{"missing windows path", `images\icon`, "", "file not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rt := newRecordingClient()
+			ctx := NewContext(context.Background(), SyncOptions{StatusOutput: io.Discard})
+			err := c.SetPageIconAndCover(ctx, "page", tt.icon, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if len(rt.requests) != 0 {
+					t.Errorf("sent %d requests, want none", len(rt.requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rt.requests) != 1 || rt.requests[0].Method != http.MethodPatch || rt.requests[0].Path != "/v1/pages/page" {
+				t.Fatalf("requests = %+v, want one PATCH of the page", rt.requests)
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(rt.requests[0].Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(body["icon"]); got != tt.wantIcon {
+				t.Errorf("icon = %s, want %s", got, tt.wantIcon)
+			}
+		})
+	}
+}
+
+func TestEndpointVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		versions    map[string]string
+		wantUploads string
+		wantBlocks  string
+	}{
+		{"default version everywhere", nil, DefaultNotionVersion, DefaultNotionVersion},
+		{"uploads override", map[string]string{"/v1/file_uploads": "2025-09-03"}, "2025-09-03", DefaultNotionVersion},
+		{"longest prefix wins", map[string]string{"/v1": "2024-01-01", "/v1/blocks": "2025-09-03"}, "2024-01-01", "2025-09-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rt := newRecordingClient()
+			c.NotionHTTP.EndpointVersions = tt.versions
+			ctx := NewContext(context.Background(), testOptions())
+			_, _ = c.createFileUploadObject(ctx)
+			_, _ = c.AddPageContent(ctx, "page", []notion.Block{notion.ParagraphBlock{RichText: plainRichText("text")}})
+			if len(rt.requests) != 2 {
+				t.Fatalf("sent %d requests, want 2", len(rt.requests))
+			}
+			if got := rt.requests[0]; !strings.HasPrefix(got.Path, "/v1/file_uploads") || got.Version != tt.wantUploads {
+				t.Errorf("%s sent Notion-Version %q, want %q", got.Path, got.Version, tt.wantUploads)
+			}
+			if got := rt.requests[1]; !strings.HasPrefix(got.Path, "/v1/blocks") || got.Version != tt.wantBlocks {
+				t.Errorf("%s sent Notion-Version %q, want %q", got.Path, got.Version, tt.wantBlocks)
+			}
+		})
+	}
+}
+
+func TestVerifyPageRequest(t *testing.T) {
+	c, rt := newRecordingClient()
+	if err := c.VerifyPage(context.Background(), "page-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(rt.requests))
+	}
+	req := rt.requests[0]
+	const wantBody = `{"properties":{"Verification":{"verification":{"state":"verified"}}}}`
+	if req.Method != http.MethodPatch || req.Path != "/v1/pages/page-1" || req.Body != wantBody {
+		t.Errorf("request = %s %s %s, want PATCH /v1/pages/page-1 %s", req.Method, req.Path, req.Body, wantBody)
+	}
+
+	rt.status, rt.body = http.StatusBadRequest, `{"message":"no verification property"}`
+	if err := c.VerifyPage(context.Background(), "page-1"); err == nil {
+		t.Error("VerifyPage succeeded, want the API error")
+	}
+}
+
+func TestPostUploadRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		statuses     []int
+		wantErr      bool
+		wantRequests int
+	}{
+		{"retried server error", 1, []int{http.StatusServiceUnavailable, http.StatusOK}, false, 2},
+		{"no upload retries", 0, []int{http.StatusServiceUnavailable, http.StatusOK}, true, 1},
+		{"client error not retried", 1, []int{http.StatusBadRequest, http.StatusOK}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[min(requests, len(tt.statuses)-1)])
+				requests++
+			}))
+			defer server.Close()
+
+			c := NewNotionClient("token", DefaultNotionVersion)
+			// The general retries must not apply to uploads
+			c.NotionHTTP.MaxRetries = 3
+			c.UploadRetries = tt.retries
+			ctx := NewContext(context.Background(), testOptions())
+			err := c.postUpload(ctx, server.URL, []byte("data"), "application/octet-stream")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPostUploadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.UploadTimeout = 50 * time.Millisecond
+	ctx := NewContext(context.Background(), testOptions())
+	if err := c.postUpload(ctx, server.URL, []byte("data"), "application/octet-stream"); err == nil {
+		t.Error("upload succeeded, want it timed out")
+	}
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func TestUploadFileRetriesExpiredURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(path, []byte("png data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var created, sent []string
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		status, body := http.StatusOK, "{}"
+		switch {
+		case req.URL.Path == "/v1/file_uploads":
+			id := fmt.Sprintf("upload-%d", len(created)+1)
+			created = append(created, id)
+			body = fmt.Sprintf(`{"id": %q, "upload_url": "https://api.notion.com/v1/file_uploads/%s/send"}`, id, id)
+		case strings.HasSuffix(req.URL.Path, "/send"):
+			sent = append(sent, req.URL.Path)
+			if len(sent) == 1 {
+				status, body = http.StatusBadRequest, `{"code": "validation_error", "message": "The file upload has expired."}`
+			}
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	})}
+
+	id, err := c.UploadFile(NewContext(context.Background(), testOptions()), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "upload-2" {
+		t.Errorf("file upload = %q, want the second one", id)
+	}
+	if want := []string{"/v1/file_uploads/upload-1/send", "/v1/file_uploads/upload-2/send"}; !slices.Equal(sent, want) {
+		t.Errorf("sent to %v, want %v", sent, want)
+	}
+}
+
+func TestAddCommentRequest(t *testing.T) {
+	c, rt := newRecordingClient()
+	rt.body = `{"object": "comment", "id": "comment-1"}`
+	if err := c.AddComment(context.Background(), "page-1", "Synced by notionmd-cli"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(rt.requests))
+	}
+	req := rt.requests[0]
+	var body struct {
+		Parent struct {
+			PageID string `json:"page_id"`
+		} `json:"parent"`
+		RichText []notion.RichText `json:"rich_text"`
+	}
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodPost || req.Path != "/v1/comments" {
+		t.Errorf("request = %s %s, want POST /v1/comments", req.Method, req.Path)
+	}
+	if body.Parent.PageID != "page-1" || len(body.RichText) != 1 || body.RichText[0].Text.Content != "Synced by notionmd-cli" {
+		t.Errorf("body = %s, want the text commented on page-1", req.Body)
+	}
+}
+
+func TestClearPageContentAfter(t *testing.T) {
+	const children = `{"object": "list", "has_more": false, "results": [
+		{"object": "block", "id": "b1", "type": "divider", "divider": {}},
+		{"object": "block", "id": "b2", "type": "divider", "divider": {}},
+		{"object": "block", "id": "b3", "type": "divider", "divider": {}},
+		{"object": "block", "id": "b4", "type": "divider", "divider": {}}
+	]}`
+	tests := []struct {
+		keep        int
+		wantDeleted []string
+		warnings    int
+	}{
+		{2, []string{"b3", "b4"}, 0},
+		{4, nil, 0},
+		{5, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.keep), func(t *testing.T) {
+			var deleted []string
+			c := NewNotionClient("token", DefaultNotionVersion)
+			c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+				body := children
+				if req.Method == http.MethodDelete {
+					id := path.Base(req.URL.Path)
+					deleted = append(deleted, id)
+					body = fmt.Sprintf(`{"object": "block", "id": %q, "type": "divider", "divider": {}, "archived": true}`, id)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+			})}
+			ctx := NewContext(context.Background(), testOptions())
+			if err := c.ClearPageContentAfter(ctx, "page", tt.keep); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("deleted %v, want %v", deleted, tt.wantDeleted)
+			}
+			if got := warningCount(ctx); got != tt.warnings {
+				t.Errorf("%d warnings, want %d", got, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantName string
+		want     string
+	}{
+		{"default", "", "Authorization", "Bearer token"},
+		{"custom header", "X-Api-Key: {token}", "X-Api-Key", "token"},
+		{"custom format", "Authorization: Token {token}", "Authorization", "Token token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rt := newRecordingClient()
+			if tt.spec != "" {
+				name, format, err := ParseAuthHeader(tt.spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.SetAuthHeader(name, format)
+			}
+			ctx := NewContext(context.Background(), testOptions())
+			// One raw request and one through the go-notion client
+			_, _ = c.createFileUploadObject(ctx)
+			_, _ = c.GetPageContent(ctx, "page")
+			if len(rt.requests) != 2 {
+				t.Fatalf("sent %d requests, want 2", len(rt.requests))
+			}
+			for _, req := range rt.requests {
+				if got := req.Header.Get(tt.wantName); got != tt.want {
+					t.Errorf("%s %s sent %s %q, want %q", req.Method, req.Path, tt.wantName, got, tt.want)
+				}
+				if tt.wantName != "Authorization" && req.Header.Get("Authorization") != "" {
+					t.Errorf("%s %s still sent Authorization", req.Method, req.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAuthHeaderErrors(t *testing.T) {
+	for _, spec := range []string{"X-Api-Key", ": {token}", "X-Api-Key: token", "X Api Key: {token}"} {
+		if _, _, err := ParseAuthHeader(spec); err == nil {
+			t.Errorf("ParseAuthHeader(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// nestedBlockServer is a fake Notion API holding a tree of blocks. It creates the blocks
+// appended to a block together with their children, and records how deep each request nests.
+type nestedBlockServer struct {
+	children map[string][]map[string]any
+	depths   []int
+	nextID   int
+}
+
+func (s *nestedBlockServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	parentID := path.Base(path.Dir(req.URL.Path))
+	body := `{}`
+	switch req.Method {
+	case http.MethodPatch:
+		var appended struct {
+			Children []any `json:"children"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&appended); err != nil {
+			return nil, err
+		}
+		s.depths = append(s.depths, nestingDepth(appended.Children))
+		results, _ := json.Marshal(map[string]any{"results": s.create(parentID, appended.Children)})
+		body = string(results)
+	case http.MethodGet:
+		results, _ := json.Marshal(map[string]any{"object": "list", "results": s.children[parentID], "has_more": false})
+		body = string(results)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+// create stores blocks as the children of parentID, and their children under them
+func (s *nestedBlockServer) create(parentID string, blocks []any) []any {
+	for _, b := range blocks {
+		block := b.(map[string]any)
+		s.nextID++
+		id := fmt.Sprintf("block-%d", s.nextID)
+		blockType, content := blockContent(block)
+		block["object"], block["id"], block["type"] = "block", id, blockType
+		if children, ok := content["children"].([]any); ok {
+			delete(content, "children")
+			s.create(id, children)
+		}
+		block["has_children"] = len(s.children[id]) > 0
+		s.children[parentID] = append(s.children[parentID], block)
+	}
+	return blocks
+}
+
+// outline returns the text of the blocks under parentID one per line, indented by depth
+func (s *nestedBlockServer) outline(parentID string, depth int) []string {
+	var lines []string
+	for _, block := range s.children[parentID] {
+		_, content := blockContent(block)
+		text := content["rich_text"].([]any)[0].(map[string]any)["text"].(map[string]any)["content"].(string)
+		lines = append(lines, strings.Repeat("  ", depth)+text)
+		lines = append(lines, s.outline(block["id"].(string), depth+1)...)
+	}
+	return lines
+}
+
+// blockContent returns the type of a block in a request body and the object holding its content
+func blockContent(block map[string]any) (string, map[string]any) {
+	for key, value := range block {
+		if content, ok := value.(map[string]any); ok {
+			return key, content
+		}
+	}
+	return "", nil
+}
+
+// nestingDepth returns how many levels of blocks a request body's children hold
+func nestingDepth(blocks []any) int {
+	deepest := 0
+	for _, block := range blocks {
+		_, content := blockContent(block.(map[string]any))
+		children, _ := content["children"].([]any)
+		deepest = max(deepest, 1+nestingDepth(children))
+	}
+	return deepest
+}
+
+func TestAddPageContentDeepNesting(t *testing.T) {
+	markdown := "- one\n    - two\n        - three\n            - four\n        - three again\n            - four again\n- top\n    - two\n"
+	server := &nestedBlockServer{children: map[string][]map[string]any{}}
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: server}
+	ctx := NewContext(context.Background(), testOptions())
+
+	blocks := ValidateContentBlocks(ctx, convert(t, markdown))
+	ids, err := c.AddPageContent(ctx, "page", blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("got %d block IDs, want the 2 top level items", len(ids))
+	}
+	for i, depth := range server.depths {
+		if depth > maxNestingDepth+1 {
+			t.Errorf("request %d nests %d levels, want at most %d", i+1, depth, maxNestingDepth+1)
+		}
+	}
+	if len(server.depths) != 3 {
+		t.Errorf("sent %d append requests, want 1 and a follow-up for each item at the third level", len(server.depths))
+	}
+	want := []string{"one", "  two", "    three", "      four", "    three again", "      four again", "top", "  two"}
+	if got := server.outline("page", 0); !slices.Equal(got, want) {
+		t.Errorf("page =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// The blocks passed in are left as they were
+	three := blockChildren(blockChildren(blocks[0])[0])[0]
+	if got := pageTexts(blockChildren(three)); !slices.Equal(got, []string{"four"}) {
+		t.Errorf("third level item holds %q after appending, want its children put back", got)
+	}
+}
+
+func TestAddPageContentChunks(t *testing.T) {
+	tests := []struct {
+		blocks     int
+		wantChunks []int
+	}{
+		{blocks: 100, wantChunks: []int{100}},
+		{blocks: 101, wantChunks: []int{100, 1}},
+		{blocks: 250, wantChunks: []int{100, 100, 50}},
+	}
+	for _, tt := range tests {
+		for _, failSecond := range []bool{false, true} {
+			if failSecond && len(tt.wantChunks) < 2 {
+				continue
+			}
+			name := fmt.Sprint(tt.blocks)
+			if failSecond {
+				name += " failing the second chunk"
+			}
+			t.Run(name, func(t *testing.T) {
+				// Every block is answered with an ID made from its text, so order shows in the IDs
+				var chunks [][]string
+				c := NewNotionClient("token", DefaultNotionVersion)
+				c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+					if req.Method != http.MethodPatch || req.URL.Path != "/v1/blocks/page/children" {
+						t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+					}
+					var body struct {
+						Children []struct {
+							Paragraph struct {
+								RichText []struct {
+									PlainText string `json:"plain_text"`
+								} `json:"rich_text"`
+							} `json:"paragraph"`
+						} `json:"children"`
+					}
+					if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+						t.Fatal(err)
+					}
+					var texts []string
+					results := make([]map[string]string, len(body.Children))
+					for i, child := range body.Children {
+						text := child.Paragraph.RichText[0].PlainText
+						texts = append(texts, text)
+						results[i] = map[string]string{"id": "id-" + text}
+					}
+					chunks = append(chunks, texts)
+					status, respBody := http.StatusOK, []byte{}
+					if failSecond && len(chunks) == 2 {
+						status, respBody = http.StatusBadRequest, []byte(`{"code":"validation_error"}`)
+					} else {
+						respBody, _ = json.Marshal(map[string]any{"results": results})
+					}
+					return &http.Response{StatusCode: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(bytes.NewReader(respBody))}
+				})}
+
+				blocks := make([]notion.Block, tt.blocks)
+				var wantIDs []string
+				for i := range blocks {
+					blocks[i] = &notion.ParagraphBlock{RichText: plainRichText(fmt.Sprint(i + 1))}
+					wantIDs = append(wantIDs, fmt.Sprintf("id-%d", i+1))
+				}
+				ctx := NewContext(context.Background(), testOptions())
+				ids, err := c.AddPageContent(ctx, "page", blocks)
+
+				var sizes []int
+				var sent []string
+				for _, chunk := range chunks {
+					sizes = append(sizes, len(chunk))
+					sent = append(sent, chunk...)
+				}
+				if failSecond {
+					wantErr := fmt.Sprintf("chunk 2/%d (blocks 101-%d)", len(tt.wantChunks), 100+tt.wantChunks[1])
+					if err == nil || !strings.Contains(err.Error(), wantErr) {
+						t.Errorf("err = %v, want it to name %q", err, wantErr)
+					}
+					if !slices.Equal(ids, wantIDs[:100]) {
+						t.Errorf("got %d IDs, want the 100 of the first chunk", len(ids))
+					}
+					if !slices.Equal(sizes, tt.wantChunks[:2]) {
+						t.Errorf("chunk sizes = %v, want no chunks after the failed one", sizes)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(sizes, tt.wantChunks) {
+					t.Errorf("chunk sizes = %v, want %v", sizes, tt.wantChunks)
+				}
+				var wantSent []string
+				for i := range tt.blocks {
+					wantSent = append(wantSent, fmt.Sprint(i+1))
+				}
+				if !slices.Equal(sent, wantSent) {
+					t.Errorf("blocks were sent out of order: %v", sent)
+				}
+				if !slices.Equal(ids, wantIDs) {
+					t.Errorf("IDs = %v, want them in block order", ids)
+				}
+			})
+		}
+	}
+}
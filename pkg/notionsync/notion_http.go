@@ -0,0 +1,256 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotionHTTP wraps HTTP logic for Notion API
+// It sets required headers and exposes helper methods
+// for POST, PATCH, PUT, GET requests.
+type NotionHTTP struct {
+	Token   string
+	Version string
+	Client  *http.Client
+
+	// AuthHeader and AuthFormat set the header carrying the token, for gateways in front of
+	// Notion that expect it elsewhere. "{token}" in AuthFormat is replaced by the token.
+	AuthHeader string
+	AuthFormat string
+
+	// EndpointVersions overrides Version for requests whose URL path starts
+	// with the key, e.g. "/v1/file_uploads". The longest matching key wins.
+	EndpointVersions map[string]string
+
+	// MaxRetries is how many times a request answered with 429 or a 5xx status is retried,
+	// waiting as long as the Retry-After header asks
+	MaxRetries int
+
+	// RateLimiter is shared by all requests to Notion, including those of the go-notion
+	// client, keeping the process under the rate limit. nil means no limit.
+	RateLimiter *RateLimiter
+}
+
+// DefaultNotionVersion is the Notion-Version sent unless --notion-version says otherwise
+const DefaultNotionVersion = "2022-06-28"
+
+func NewNotionHTTP(token, version string) *NotionHTTP {
+	return &NotionHTTP{
+		Token:      token,
+		Version:    version,
+		Client:     &http.Client{},
+		AuthHeader: "Authorization",
+		AuthFormat: "Bearer {token}",
+	}
+}
+
+func (n *NotionHTTP) setHeaders(req *http.Request) {
+	n.setAuthHeader(req)
+	req.Header.Set("Notion-Version", n.versionFor(req.URL.Path))
+}
+
+// setAuthHeader sets the configured authorization header, replacing the default one
+func (n *NotionHTTP) setAuthHeader(req *http.Request) {
+	req.Header.Del("Authorization")
+	req.Header.Set(n.AuthHeader, strings.ReplaceAll(n.AuthFormat, "{token}", n.Token))
+}
+
+// ParseAuthHeader parses an authorization header spec such as "X-Api-Key: {token}" into
+// the header name and value format
+func ParseAuthHeader(spec string) (string, string, error) {
+	name, format, ok := strings.Cut(spec, ":")
+	name, format = strings.TrimSpace(name), strings.TrimSpace(format)
+	if !ok || name == "" || format == "" {
+		return "", "", fmt.Errorf("must look like 'Header-Name: format', e.g. 'X-Api-Key: {token}'")
+	}
+	if strings.ContainsFunc(name, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r)
+	}) {
+		return "", "", fmt.Errorf("'%s' is not a valid header name", name)
+	}
+	if !strings.Contains(format, "{token}") {
+		return "", "", fmt.Errorf("format '%s' doesn't contain {token}", format)
+	}
+	return name, format, nil
+}
+
+// notionTransport sends the requests of the go-notion client the way NotionHTTP sends its own:
+// with the token in the configured header, through the shared rate limiter and retried on
+// 429 and 5xx answers
+type notionTransport struct {
+	http *NotionHTTP
+}
+
+func (t notionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.http.Client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	// A body that can't be read again can only be sent once
+	maxRetries := t.http.MaxRetries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		maxRetries = 0
+	}
+	attempts := 0
+	return t.http.retry(req.Context(), maxRetries, func() (*http.Request, error) {
+		attempt := req.Clone(req.Context())
+		if attempts++; attempts > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+		t.http.setAuthHeader(attempt)
+		return attempt, nil
+	}, transport.RoundTrip)
+}
+
+// NewHTTPTransport returns a transport sending requests through proxyURL, or the proxy named by
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY when it's empty, and trusting the certificates of the PEM
+// file caBundle on top of the system ones
+func NewHTTPTransport(proxyURL, caBundle string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || proxy.Host == "" || (proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5") {
+			return nil, fmt.Errorf("proxy '%s' must be an http://, https:// or socks5:// URL with a host", proxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle '%s' contains no PEM certificates", caBundle)
+		}
+		transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return transport, nil
+}
+
+// versionFor returns the Notion-Version to send for the given URL path
+func (n *NotionHTTP) versionFor(path string) string {
+	version, matched := n.Version, ""
+	for prefix, v := range n.EndpointVersions {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			version, matched = v, prefix
+		}
+	}
+	return version
+}
+
+func (n *NotionHTTP) Post(ctx context.Context, url string, body []byte, contentType string) (*http.Response, error) {
+	return n.do(ctx, "POST", url, body, contentType)
+}
+
+func (n *NotionHTTP) Patch(ctx context.Context, url string, body []byte, contentType string) (*http.Response, error) {
+	return n.do(ctx, "PATCH", url, body, contentType)
+}
+
+func (n *NotionHTTP) Put(ctx context.Context, url string, body []byte, contentType string) (*http.Response, error) {
+	return n.do(ctx, "PUT", url, body, contentType)
+}
+
+func (n *NotionHTTP) Get(ctx context.Context, url string) (*http.Response, error) {
+	return n.do(ctx, "GET", url, nil, "")
+}
+
+// do sends the request, retrying up to MaxRetries times while Notion answers 429 or a 5xx
+// status. The request is rebuilt for every attempt so the body is sent in full each time.
+// Cancelling ctx aborts the request in flight and any wait before the next attempt.
+func (n *NotionHTTP) do(ctx context.Context, method, url string, body []byte, contentType string) (*http.Response, error) {
+	return n.retry(ctx, n.MaxRetries, func() (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+		n.setHeaders(req)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req, nil
+	}, n.Client.Do)
+}
+
+// retry sends the request newRequest builds for every attempt, retrying up to maxRetries times
+// while Notion answers 429 or a 5xx status and waiting as long as Retry-After asks in between.
+// Every attempt goes through the rate limiter.
+func (n *NotionHTTP) retry(ctx context.Context, maxRetries int, newRequest func() (*http.Request, error), send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if err := n.RateLimiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := send(req)
+		if err != nil || attempt >= maxRetries || !isRetryableStatus(resp.StatusCode) {
+			return resp, err
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		fmt.Fprintf(output(ctx), "⏳ Notion answered %d to %s %s, retrying in %s (attempt %d of %d)\n", resp.StatusCode, req.Method, req.URL.Path, wait, attempt+2, maxRetries+1)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d, returning the context's error instead if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRetryableStatus reports whether a response status is worth retrying: rate limiting and server errors
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// defaultRetryAfter is how long to wait before retrying when the response doesn't say
+const defaultRetryAfter = time.Second
+
+// retryAfter returns how long a Retry-After header value asks to wait, given in seconds or
+// as an HTTP date, falling back to defaultRetryAfter
+func retryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return defaultRetryAfter
+}
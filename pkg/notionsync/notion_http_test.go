@@ -0,0 +1,249 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestNewHTTPTransportProxy(t *testing.T) {
+	// The proxy refuses every tunnel, it only records where they were meant to go
+	var mu sync.Mutex
+	var tunnels []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tunnels = append(tunnels, r.Method+" "+r.Host)
+		mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	transport, err := NewHTTPTransport(proxy.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.notion.com/v1/users/me", nil)
+	if got, err := transport.Proxy(req); err != nil || got == nil || got.String() != proxy.URL {
+		t.Fatalf("proxy for %s = %v, %v, want %s", req.URL, got, err, proxy.URL)
+	}
+
+	// Both the raw requests and the go-notion client go through the proxy
+	client := NewNotionClient("token", DefaultNotionVersion)
+	client.NotionHTTP.Client.Transport = transport
+	ctx := NewContext(context.Background(), testOptions())
+	if _, err := client.NotionHTTP.Get(ctx, "https://api.notion.com/v1/users/me"); err == nil {
+		t.Error("raw request succeeded, want the proxy's refusal")
+	}
+	if _, err := client.GetProperty(ctx, "page", "Hash"); err == nil {
+		t.Error("go-notion request succeeded, want the proxy's refusal")
+	}
+	if len(tunnels) < 2 || slices.ContainsFunc(tunnels, func(tunnel string) bool { return tunnel != "CONNECT api.notion.com:443" }) {
+		t.Errorf("proxy saw %q, want a tunnel to api.notion.com for each request", tunnels)
+	}
+}
+
+func TestNewHTTPTransportCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// Without the bundle the test server's certificate isn't trusted
+	transport, err := NewHTTPTransport("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("err = %v, want a certificate error", err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if transport, err = NewHTTPTransport("", bundle); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("err = %v, want the bundle's certificate trusted", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewHTTPTransportErrors(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		proxy    string
+		caBundle string
+		wantErr  string
+	}{
+		{name: "proxy without scheme", proxy: "proxy.example.com:8080", wantErr: "must be an http://"},
+		{name: "unsupported proxy scheme", proxy: "ftp://proxy.example.com", wantErr: "must be an http://"},
+		{name: "missing bundle", caBundle: filepath.Join(t.TempDir(), "missing.pem"), wantErr: "failed to read CA bundle"},
+		{name: "bundle without certificates", caBundle: notPEM, wantErr: "contains no PEM certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPTransport(tt.proxy, tt.caBundle); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to say %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// retryServer answers with the statuses in order, the last one from then on, and records the
+// bodies it receives. Retryable answers ask for a retry after retryAfter.
+type retryServer struct {
+	statuses   []int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *retryServer) respond(body []byte) (int, http.Header) {
+	s.bodies = append(s.bodies, string(body))
+	status := s.statuses[min(len(s.bodies), len(s.statuses))-1]
+	header := http.Header{"Content-Type": {"application/json"}}
+	if isRetryableStatus(status) {
+		header.Set("Retry-After", s.retryAfter)
+	}
+	return status, header
+}
+
+func TestNotionHTTPRetries(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name         string
+		statuses     []int
+		retryAfter   string
+		maxRetries   int
+		wantRequests int
+		wantStatus   int
+	}{
+		{name: "429 with Retry-After in seconds", statuses: []int{429, 200}, retryAfter: "0", maxRetries: 3, wantRequests: 2, wantStatus: 200},
+		{name: "429 with Retry-After as a date", statuses: []int{429, 200}, retryAfter: past, maxRetries: 3, wantRequests: 2, wantStatus: 200},
+		{name: "503", statuses: []int{503, 503, 200}, retryAfter: "0", maxRetries: 3, wantRequests: 3, wantStatus: 200},
+		{name: "retries exhausted", statuses: []int{500}, retryAfter: "0", maxRetries: 2, wantRequests: 3, wantStatus: 500},
+		{name: "retries disabled", statuses: []int{429, 200}, retryAfter: "0", wantRequests: 1, wantStatus: 429},
+		{name: "client error", statuses: []int{400, 200}, retryAfter: "0", maxRetries: 3, wantRequests: 1, wantStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &retryServer{statuses: tt.statuses, retryAfter: tt.retryAfter}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				status, header := rs.respond(body)
+				maps.Copy(w.Header(), header)
+				w.WriteHeader(status)
+				w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+
+			n := NewNotionHTTP("token", DefaultNotionVersion)
+			n.MaxRetries = tt.maxRetries
+			const body = `{"children":[{"paragraph":{"rich_text":[]}}]}`
+			resp, err := n.Patch(NewContext(context.Background(), testOptions()), server.URL+"/v1/blocks/page/children", []byte(body), "application/json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(rs.bodies) != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", len(rs.bodies), tt.wantRequests)
+			}
+			for i, got := range rs.bodies {
+				if got != body {
+					t.Errorf("attempt %d sent %q, want the full body", i+1, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"7", 7 * time.Second},
+		{" 0 ", 0},
+		{now.Add(3 * time.Second).Format(http.TimeFormat), 3 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"", defaultRetryAfter},
+		{"-1", defaultRetryAfter},
+		{"soon", defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.value, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNotionTransportRetries(t *testing.T) {
+	rs := &retryServer{statuses: []int{429, 502, 200}, retryAfter: "0"}
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.MaxRetries = 3
+	c.NotionHTTP.Client.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		status, header := rs.respond(body)
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("attempt %d has no token", len(rs.bodies))
+		}
+		resp := `{"object":"page","id":"page","parent":{"type":"workspace","workspace":true},"properties":{}}`
+		if req.Method == http.MethodDelete {
+			resp = `{"object":"block","id":"block","type":"divider","divider":{},"archived":true}`
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(resp))}
+	})
+	ctx := NewContext(context.Background(), testOptions())
+
+	// The page lookup before a title update
+	if err := c.UpdatePageTitle(ctx, "page", notion.Heading1Block{RichText: plainRichText("Title")}); err == nil || !strings.Contains(err.Error(), "not in a database") {
+		t.Fatalf("err = %v, want the page lookup to succeed after its retries", err)
+	}
+	if len(rs.bodies) != 3 {
+		t.Errorf("page lookup sent %d requests, want 3", len(rs.bodies))
+	}
+
+	// A request with a body is sent in full on every attempt
+	rs.statuses, rs.bodies = []int{503, 200}, nil
+	params := notion.UpdatePageParams{DatabasePageProperties: notion.DatabasePageProperties{"Name": {Title: plainRichText("Title")}}}
+	if _, err := c.NotionClient.UpdatePage(ctx, "page", params); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.bodies) != 2 || rs.bodies[0] == "" || rs.bodies[1] != rs.bodies[0] {
+		t.Errorf("page update sent %q, want the full body twice", rs.bodies)
+	}
+
+	// Deleting the blocks of a page for --replace goes through the go-notion client too
+	rs.statuses, rs.bodies = []int{429, 200}, nil
+	if _, err := c.NotionClient.DeleteBlock(ctx, "block"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.bodies) != 2 {
+		t.Errorf("block delete sent %d requests, want 2", len(rs.bodies))
+	}
+}
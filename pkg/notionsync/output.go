@@ -0,0 +1,65 @@
+package notionsync
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// session is the state of one sync: where its status messages go, whether debug messages are
+// printed, how long input files are waited for, the warnings counted so validation can fail on
+// them and the run report it is recorded in. It travels in the context, so syncs running side
+// by side in one program keep apart.
+type session struct {
+	out       io.Writer
+	debug     bool
+	inputWait time.Duration
+	report    *Report
+
+	// mu guards warnings, counted while images are uploaded concurrently
+	mu       sync.Mutex
+	warnings int
+}
+
+// sessionKey is the context key of the session
+type sessionKey struct{}
+
+// NewContext returns ctx set up to print, count warnings and record the report the way a sync
+// with opts does, for calling helpers such as ClearPage or LoadUserMap outside SyncFile.
+// SyncFile and the other syncs start a fresh one for every document from their own options.
+func NewContext(ctx context.Context, opts SyncOptions) context.Context {
+	s := &session{out: opts.StatusOutput, debug: opts.Debug, inputWait: opts.InputWait, report: opts.Report}
+	if s.out == nil {
+		s.out = os.Stdout
+	}
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// sessionFrom returns the session ctx carries. Outside a sync, e.g. when a program calls one
+// of the helpers directly, messages go to os.Stdout and warnings aren't counted anywhere.
+func sessionFrom(ctx context.Context) *session {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return s
+	}
+	return &session{out: os.Stdout}
+}
+
+// output returns the writer receiving the status messages of the sync ctx belongs to
+func output(ctx context.Context) io.Writer {
+	return sessionFrom(ctx).out
+}
+
+// reportFrom returns the run report the sync ctx belongs to is recorded in, nil if none
+func reportFrom(ctx context.Context) *Report {
+	return sessionFrom(ctx).report
+}
+
+// warningCount returns the number of warnings printed so far by the sync ctx belongs to
+func warningCount(ctx context.Context) int {
+	s := sessionFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.warnings
+}
@@ -0,0 +1,48 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSessionsKeepWarningsApart(t *testing.T) {
+	var wg sync.WaitGroup
+	outs := make([]bytes.Buffer, 4)
+	counts := make([]int, len(outs))
+	for i := range outs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := NewContext(context.Background(), SyncOptions{StatusOutput: &outs[i]})
+			for range i {
+				warnf(ctx, "warning\n")
+			}
+			counts[i] = warningCount(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range outs {
+		if counts[i] != i {
+			t.Errorf("session %d counted %d warnings, want %d", i, counts[i], i)
+		}
+		if got := strings.Count(outs[i].String(), "warning"); got != i {
+			t.Errorf("session %d printed %d warnings, want %d", i, got, i)
+		}
+	}
+}
+
+func TestDebugfOnlyWithDebug(t *testing.T) {
+	var quiet, loud bytes.Buffer
+	debugf(NewContext(context.Background(), SyncOptions{StatusOutput: &quiet}), "hidden\n")
+	debugf(NewContext(context.Background(), SyncOptions{StatusOutput: &loud, Debug: true}), "shown\n")
+	if quiet.Len() != 0 {
+		t.Errorf("debug message printed without Debug: %q", quiet.String())
+	}
+	if !strings.Contains(loud.String(), "shown") {
+		t.Errorf("debug message missing with Debug: %q", loud.String())
+	}
+}
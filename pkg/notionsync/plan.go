@@ -0,0 +1,128 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// syncPlan describes the block changes a sync would make to a live page
+type syncPlan struct {
+	Operation string       `json:"operation"`
+	PageID    string       `json:"page_id"`
+	Add       int          `json:"add"`
+	Remove    int          `json:"remove"`
+	Unchanged int          `json:"unchanged"`
+	Changes   []planChange `json:"changes"`
+}
+
+// planChange is a single planned block change
+type planChange struct {
+	Op   string `json:"op"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// blockTypeName returns the Notion API type of a block, e.g. "paragraph"
+func blockTypeName(block notion.Block) string {
+	data, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Sprintf("%T", block)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Sprintf("%T", block)
+	}
+	for name := range fields {
+		return name
+	}
+	return fmt.Sprintf("%T", block)
+}
+
+// blockSignature renders a block as a single comparable line: its type and plain text
+func blockSignature(block notion.Block) string {
+	text := strings.ReplaceAll(richTextPlainText(blockRichText(block)), "\n", "\\n")
+	if children := blockChildren(block); len(children) > 0 {
+		text += fmt.Sprintf(" (+%d children)", len(children))
+	}
+	return "[" + blockTypeName(block) + "] " + text
+}
+
+// buildSyncPlan diffs the live page blocks against the new blocks. Appending never removes
+// anything, replacing is planned as the minimal set of removals and additions.
+func buildSyncPlan(pageID string, replace bool, live, blocks []notion.Block) syncPlan {
+	plan := syncPlan{Operation: "append", PageID: pageID, Changes: []planChange{}}
+	newSigs := make([]string, len(blocks))
+	for i, block := range blocks {
+		newSigs[i] = blockSignature(block)
+	}
+	var oldSigs []string
+	if replace {
+		plan.Operation = "replace"
+		for _, block := range live {
+			oldSigs = append(oldSigs, blockSignature(block))
+		}
+	} else {
+		plan.Unchanged = len(live)
+	}
+
+	for _, op := range diffStrings(oldSigs, newSigs) {
+		typeName, text, _ := strings.Cut(strings.TrimPrefix(op.Text, "["), "] ")
+		switch op.Kind {
+		case '+':
+			plan.Add++
+			plan.Changes = append(plan.Changes, planChange{Op: "add", Type: typeName, Text: text})
+		case '-':
+			plan.Remove++
+			plan.Changes = append(plan.Changes, planChange{Op: "remove", Type: typeName, Text: text})
+		default:
+			plan.Unchanged++
+		}
+	}
+	return plan
+}
+
+// printSyncPlan prints the plan as human readable text or JSON
+func printSyncPlan(ctx context.Context, plan syncPlan, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(output(ctx), string(data))
+		return nil
+	}
+	fmt.Fprintf(output(ctx), "Planned changes (%s): %d to add, %d to remove, %d unchanged\n", plan.Operation, plan.Add, plan.Remove, plan.Unchanged)
+	for _, change := range plan.Changes {
+		marker := "+"
+		if change.Op == "remove" {
+			marker = "-"
+		}
+		fmt.Fprintf(output(ctx), "  %s [%s] %s\n", marker, change.Type, change.Text)
+	}
+	return nil
+}
+
+// DiffOutputs are the accepted --diff-output values: the block level plan, or a unified diff
+// of the page rendered as markdown before and after the sync
+var DiffOutputs = []string{"plan", "unified"}
+
+// unifiedSyncDiff renders the live page and the page as the sync would leave it as markdown
+// and returns their unified diff, "" if the sync changes nothing
+func unifiedSyncDiff(pageID, mdPath string, replace bool, live, blocks []notion.Block) string {
+	after := blocks
+	if !replace {
+		after = append(slices.Clip(live), blocks...)
+	}
+	lines := func(blocks []notion.Block) []string {
+		if len(blocks) == 0 {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(renderMarkdown(blocks), "\n"), "\n")
+	}
+	return unifiedDiff("notion page "+pageID, mdPath, lines(live), lines(after), 3)
+}
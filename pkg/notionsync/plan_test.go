@@ -0,0 +1,105 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// syncedClient returns a fake client whose page holds markdown, synced with replace
+func syncedClient(t *testing.T, markdown string) *fakeNotionClient {
+	t.Helper()
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.Replace = true
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	client.calls = nil
+	return client
+}
+
+func TestDryRunDiffPlan(t *testing.T) {
+	client := syncedClient(t, "# Title\n\nkept\n\nremoved\n\n- item\n")
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.DryRun, opts.DryRunDiff, opts.Replace, opts.Output = true, true, true, "json"
+	opts.StatusOutput = &out
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\nkept\n\nadded\n\n- item\n"), "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callNames(); slices.Contains(got, "AddPageContent") || slices.Contains(got, "ClearPageContent") {
+		t.Errorf("calls = %v, want the page left alone", got)
+	}
+	start := bytes.IndexByte(out.Bytes(), '{')
+	if start < 0 {
+		t.Fatalf("no JSON plan in %q", out.String())
+	}
+	var plan syncPlan
+	if err := json.NewDecoder(bytes.NewReader(out.Bytes()[start:])).Decode(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Operation != "replace" || plan.Add != 1 || plan.Remove != 1 || plan.Unchanged != 2 {
+		t.Errorf("plan = %s %d added, %d removed, %d unchanged, want replace 1, 1, 2", plan.Operation, plan.Add, plan.Remove, plan.Unchanged)
+	}
+	want := []planChange{{Op: "remove", Type: "paragraph", Text: "removed"}, {Op: "add", Type: "paragraph", Text: "added"}}
+	if !slices.Equal(plan.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", plan.Changes, want)
+	}
+}
+
+func TestBuildSyncPlanAppend(t *testing.T) {
+	live := convert(t, "existing\n")
+	plan := buildSyncPlan("page", false, live, convert(t, "one\n\ntwo\n"))
+	want := []planChange{{Op: "add", Type: "paragraph", Text: "one"}, {Op: "add", Type: "paragraph", Text: "two"}}
+	if plan.Operation != "append" || plan.Remove != 0 || plan.Unchanged != 1 || !slices.Equal(plan.Changes, want) {
+		t.Errorf("plan = %+v, want the two paragraphs added after the existing one", plan)
+	}
+}
+
+func TestPrintSyncPlan(t *testing.T) {
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.StatusOutput = &out
+	plan := buildSyncPlan("page", true, convert(t, "old\n"), convert(t, "new\n"))
+	if err := printSyncPlan(NewContext(context.Background(), opts), plan, "text"); err != nil {
+		t.Fatal(err)
+	}
+	const want = "Planned changes (replace): 1 to add, 1 to remove, 0 unchanged\n  - [paragraph] old\n  + [paragraph] new\n"
+	if out.String() != want {
+		t.Errorf("plan =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestDryRunDiffUnified(t *testing.T) {
+	client := syncedClient(t, "# Title\n\nkept\n\nremoved\n\n- item\n")
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.DryRun, opts.DryRunDiff, opts.Replace, opts.DiffOutput = true, true, true, "unified"
+	opts.StatusOutput = &out
+	mdPath := writeMarkdown(t, "# Title\n\nkept\n\nadded\n\n- item\n")
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callNames(); slices.Contains(got, "AddPageContent") || slices.Contains(got, "ClearPageContent") {
+		t.Errorf("calls = %v, want the page left alone", got)
+	}
+	want := "--- notion page page\n+++ " + mdPath + "\n@@ -1,5 +1,5 @@\n kept\n \n-removed\n+added\n \n - item\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output =\n%s\nwant it to contain\n%s", out.String(), want)
+	}
+}
+
+func TestUnifiedSyncDiffAppend(t *testing.T) {
+	live := convert(t, "existing\n")
+	got := unifiedSyncDiff("page", "doc.md", false, live, convert(t, "one\n"))
+	if want := "--- notion page page\n+++ doc.md\n@@ -1 +1,3 @@\n existing\n+\n+one\n"; got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedSyncDiff("page", "doc.md", true, live, convert(t, "existing\n")); got != "" {
+		t.Errorf("diff of an unchanged replace = %q, want none", got)
+	}
+}
@@ -0,0 +1,130 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// imagePreview describes what syncing would do with one image reference
+type imagePreview struct {
+	Reference   string `json:"reference"`
+	Path        string `json:"path"`
+	Source      string `json:"source"`
+	Exists      bool   `json:"exists"`
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// previewImages resolves the images ProcessImageBlocks would handle in blocks, without uploading
+// anything. Local files are stat'ed and their content type detected as the upload would.
+func previewImages(ctx context.Context, blocks []notion.Block, basePath string, opts ImageOptions) []imagePreview {
+	var previews []imagePreview
+	for _, block := range blocks {
+		if _, ok := block.(inlineSVGBlock); ok {
+			previews = append(previews, imagePreview{Reference: "<svg>", Source: "inline", Exists: true, ContentType: "image/svg+xml"})
+			continue
+		}
+		paragraphBlock, ok := block.(*notion.ParagraphBlock)
+		if !ok || paragraphBlock == nil {
+			previews = append(previews, previewImages(ctx, blockChildren(block), basePath, opts)...)
+			continue
+		}
+		refs := FindImageReferences(richTextPlainText(paragraphBlock.RichText))
+		if len(refs) == 0 {
+			continue
+		}
+		previews = append(previews, previewImage(ctx, refs[0], basePath, opts))
+	}
+	return previews
+}
+
+// previewImage resolves a single image reference the way processImageInParagraph does
+func previewImage(ctx context.Context, ref ImageReference, basePath string, opts ImageOptions) imagePreview {
+	preview := imagePreview{Reference: ref.Path, Path: ref.Path}
+	if isDataURI(ref.Path) {
+		// Data URIs are decoded as for the upload, the temporary file is described instead
+		preview.Reference, _, _ = strings.Cut(ref.Path, ",")
+		preview.Source = "data-uri"
+		imagePath, err := writeDataURIImage(ref.Path)
+		if err != nil {
+			preview.Path, preview.Error = "", err.Error()
+			return preview
+		}
+		defer os.Remove(imagePath)
+		if info, err := os.Stat(imagePath); err == nil {
+			preview.Exists, preview.Size = true, info.Size()
+		}
+		preview.Path, preview.ContentType = "", getFileContentType(imagePath)
+		return preview
+	}
+	if len(opts.PathRewrites) > 0 {
+		preview.Path = rewriteImagePath(ctx, ref.Path, opts.PathRewrites)
+		ref.IsLocal = !strings.HasPrefix(preview.Path, "http://") && !strings.HasPrefix(preview.Path, "https://")
+	}
+	if !ref.IsLocal {
+		preview.Source = "remote"
+		return preview
+	}
+
+	preview.Source = "local"
+	if !filepath.IsAbs(preview.Path) {
+		preview.Path = filepath.Join(filepath.Dir(basePath), preview.Path)
+	}
+	info, err := os.Stat(preview.Path)
+	if err != nil || info.IsDir() {
+		preview.Error = "local image file not found"
+		return preview
+	}
+	preview.Exists = true
+	preview.Size = info.Size()
+	preview.ContentType = getFileContentType(preview.Path)
+	return preview
+}
+
+// printImagePreview prints the image previews as a table or, with format "json", as JSON.
+// It returns the number of local image files that don't exist.
+func printImagePreview(ctx context.Context, previews []imagePreview, format string) (int, error) {
+	missing := 0
+	for _, preview := range previews {
+		if preview.Source == "local" && !preview.Exists {
+			missing++
+		}
+	}
+	if format == "json" {
+		if previews == nil {
+			previews = []imagePreview{}
+		}
+		data, err := json.MarshalIndent(previews, "", "  ")
+		if err != nil {
+			return missing, err
+		}
+		fmt.Fprintln(output(ctx), string(data))
+		return missing, nil
+	}
+
+	fmt.Fprintf(output(ctx), "Images (%d, none uploaded):\n", len(previews))
+	for _, preview := range previews {
+		switch {
+		case preview.Source == "remote":
+			fmt.Fprintf(output(ctx), "  [remote]   %s (embedded by URL, not uploaded)\n", preview.Path)
+		case preview.Source == "inline":
+			fmt.Fprintf(output(ctx), "  [inline]   %s (%s)\n", preview.Reference, preview.ContentType)
+		case preview.Source == "data-uri" && !preview.Exists:
+			fmt.Fprintf(output(ctx), "  [data-uri] %s ❌ %s\n", preview.Reference, preview.Error)
+		case preview.Source == "data-uri":
+			fmt.Fprintf(output(ctx), "  [data-uri] %s (%d bytes, %s)\n", preview.Reference, preview.Size, preview.ContentType)
+		case !preview.Exists:
+			fmt.Fprintf(output(ctx), "  [local]    %s ❌ not found\n", preview.Path)
+		default:
+			fmt.Fprintf(output(ctx), "  [local]    %s (%d bytes, %s)\n", preview.Path, preview.Size, preview.ContentType)
+		}
+	}
+	return missing, nil
+}
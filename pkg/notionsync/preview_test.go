@@ -0,0 +1,101 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncFilePreviewImages(t *testing.T) {
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(onePixelPNG)
+	markdown := "# Title\n\n" +
+		"![Logo](logo.png)\n\n" +
+		"![Sniffed](images/noext)\n\n" +
+		"![Missing](missing.png)\n\n" +
+		"![Remote](https://example.com/chart.png)\n\n" +
+		"![Pixel](" + dataURI + ")\n\n" +
+		"> Quoted\n>\n> ![Nested](logo.png)\n"
+	mdPath := writeMarkdown(t, markdown)
+	writeImage(t, mdPath, "logo.png")
+	dir := filepath.Dir(mdPath)
+	if err := os.Mkdir(filepath.Join(dir, "images"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "images", "noext"), onePixelPNG, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.PreviewImages, opts.Output, opts.StatusOutput = true, "json", &out
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("err = %v, want ErrValidationFailed for the missing image", err)
+	}
+	if len(client.calls) != 0 || len(client.uploads) != 0 {
+		t.Errorf("preview called Notion: %v", client.calls)
+	}
+
+	start := strings.Index(out.String(), "[")
+	if start < 0 {
+		t.Fatalf("no JSON in output:\n%s", out.String())
+	}
+	var previews []imagePreview
+	if err := json.NewDecoder(strings.NewReader(out.String()[start:])).Decode(&previews); err != nil {
+		t.Fatalf("%v in output:\n%s", err, out.String())
+	}
+	want := []imagePreview{
+		{Reference: "logo.png", Path: filepath.Join(dir, "logo.png"), Source: "local", Exists: true, Size: 16, ContentType: "image/png"},
+		{Reference: "images/noext", Path: filepath.Join(dir, "images", "noext"), Source: "local", Exists: true, Size: int64(len(onePixelPNG)), ContentType: "image/png"},
+		{Reference: "missing.png", Path: filepath.Join(dir, "missing.png"), Source: "local", Error: "local image file not found"},
+		{Reference: "https://example.com/chart.png", Path: "https://example.com/chart.png", Source: "remote"},
+		{Reference: "data:image/png;base64", Source: "data-uri", Exists: true, Size: int64(len(onePixelPNG)), ContentType: "image/png"},
+		{Reference: "logo.png", Path: filepath.Join(dir, "logo.png"), Source: "local", Exists: true, Size: 16, ContentType: "image/png"},
+	}
+	if len(previews) != len(want) {
+		t.Fatalf("got %d previews, want %d: %+v", len(previews), len(want), previews)
+	}
+	for i := range want {
+		if previews[i] != want[i] {
+			t.Errorf("preview %d = %+v, want %+v", i, previews[i], want[i])
+		}
+	}
+}
+
+func TestPrintImagePreviewTable(t *testing.T) {
+	previews := []imagePreview{
+		{Reference: "logo.png", Path: "/docs/logo.png", Source: "local", Exists: true, Size: 16, ContentType: "image/png"},
+		{Reference: "missing.png", Path: "/docs/missing.png", Source: "local", Error: "local image file not found"},
+		{Reference: "https://example.com/a.png", Path: "https://example.com/a.png", Source: "remote"},
+		{Reference: "<svg>", Source: "inline", Exists: true, ContentType: "image/svg+xml"},
+		{Reference: "data:image/gif;base64", Source: "data-uri", Error: "unsupported data URI type"},
+	}
+	var out bytes.Buffer
+	opts := testOptions()
+	opts.StatusOutput = &out
+	missing, err := printImagePreview(NewContext(context.Background(), opts), previews, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != 1 {
+		t.Errorf("missing = %d, want 1", missing)
+	}
+	for _, want := range []string{
+		"Images (5, none uploaded):",
+		"[local]    /docs/logo.png (16 bytes, image/png)",
+		"[local]    /docs/missing.png ❌ not found",
+		"[remote]   https://example.com/a.png (embedded by URL, not uploaded)",
+		"[inline]   <svg> (image/svg+xml)",
+		"[data-uri] data:image/gif;base64 ❌ unsupported data URI type",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// propertyValue builds the value of a page property of the given type from its text form.
+// Lists for multi_select are written "[a, b]" or "a, b", checkboxes true/false or yes/no,
+// dates as YYYY-MM-DD or RFC 3339 and people as a list of user IDs.
+func propertyValue(propType notion.DatabasePropertyType, value string) (notion.DatabasePageProperty, error) {
+	property := notion.DatabasePageProperty{Type: propType}
+	switch propType {
+	case notion.DBPropTypeRichText:
+		property.RichText = []notion.RichText{{Text: &notion.Text{Content: value}}}
+	case notion.DBPropTypeSelect:
+		property.Select = &notion.SelectOptions{Name: value}
+	case notion.DBPropTypeStatus:
+		property.Status = &notion.SelectOptions{Name: value}
+	case notion.DBPropTypeMultiSelect:
+		property.MultiSelect = []notion.SelectOptions{}
+		for _, name := range frontmatterList(value) {
+			property.MultiSelect = append(property.MultiSelect, notion.SelectOptions{Name: name})
+		}
+	case notion.DBPropTypeCheckbox:
+		var checked bool
+		switch strings.ToLower(value) {
+		case "true", "yes", "on", "1":
+			checked = true
+		case "false", "no", "off", "0", "":
+		default:
+			return property, fmt.Errorf("'%s' is not a checkbox value, use true or false", value)
+		}
+		property.Checkbox = &checked
+	case notion.DBPropTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return property, fmt.Errorf("'%s' is not a number", value)
+		}
+		property.Number = &number
+	case notion.DBPropTypeDate:
+		start, err := notion.ParseDateTime(value)
+		if t, rfcErr := time.Parse(time.RFC3339, value); err != nil && rfcErr == nil {
+			start, err = notion.NewDateTime(t, true), nil
+		}
+		if err != nil {
+			return property, fmt.Errorf("'%s' is not a date, use YYYY-MM-DD or RFC 3339", value)
+		}
+		property.Date = &notion.Date{Start: start}
+	case notion.DBPropTypePeople:
+		property.People = []notion.User{}
+		for _, id := range frontmatterList(value) {
+			property.People = append(property.People, notion.User{BaseUser: notion.BaseUser{ID: id}, Type: notion.UserTypePerson})
+		}
+	case notion.DBPropTypeURL:
+		property.URL = &value
+	case notion.DBPropTypeEmail:
+		property.Email = &value
+	case notion.DBPropTypePhoneNumber:
+		property.PhoneNumber = &value
+	default:
+		return property, fmt.Errorf("properties of type %s can't be set", propType)
+	}
+	return property, nil
+}
+
+// SetProperties sets page properties from their text form, converting each value to the type
+// the property has in the page's database schema. Keys the database has no property for and
+// values that don't fit the property's type are skipped with a warning.
+func (c *NotionClient) SetProperties(ctx context.Context, pageID string, values map[string]string) error {
+	existing, err := c.pageSchema(ctx, pageID)
+	if errors.Is(err, errNotDatabasePage) {
+		existing, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	properties := schemaProperties(ctx, existing, values)
+	if len(properties) == 0 {
+		return nil
+	}
+	_, err = c.NotionClient.UpdatePage(ctx, pageID, notion.UpdatePageParams{DatabasePageProperties: properties})
+	c.invalidatePage(pageID)
+	return err
+}
+
+// schemaProperties converts values from their text form to the types their properties have in
+// schema, skipping with a warning the keys schema has no property for and the values that don't
+// fit the property's type
+func schemaProperties(ctx context.Context, schema notion.DatabaseProperties, values map[string]string) notion.DatabasePageProperties {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	properties := notion.DatabasePageProperties{}
+	for _, key := range keys {
+		current, ok := schema[key]
+		if !ok {
+			warnf(ctx, "Skipping property '%s': the database has no property of that name\n", key)
+			continue
+		}
+		property, err := propertyValue(current.Type, values[key])
+		if err != nil {
+			warnf(ctx, "Skipping property '%s': %s\n", key, err)
+			continue
+		}
+		properties[key] = property
+	}
+	return properties
+}
@@ -0,0 +1,122 @@
+package notionsync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find a blockquote line: > text
+var quoteLineRegex = regexp.MustCompile(`^ {0,3}> ?`)
+
+// Regular expression to find a color directive on a line of its own: {color=blue_background}
+var colorDirectiveRegex = regexp.MustCompile(`^\{\s*color\s*=\s*"?([a-z_]+)"?\s*\}$`)
+
+// notionColors are the colors Notion accepts for blocks
+var notionColors = []notion.Color{
+	notion.ColorDefault, notion.ColorGray, notion.ColorBrown, notion.ColorOrange, notion.ColorYellow,
+	notion.ColorGreen, notion.ColorBlue, notion.ColorPurple, notion.ColorPink, notion.ColorRed,
+	notion.ColorGrayBg, notion.ColorBrownBg, notion.ColorOrangeBg, notion.ColorYellowBg, notion.ColorGreenBg,
+	notion.ColorBlueBg, notion.ColorPurpleBg, notion.ColorPinkBg, notion.ColorRedBg,
+}
+
+// parseColorDirective returns the color of a "{color=...}" directive line. ok is false if the
+// line isn't a directive, unknown colors are reported with a warning and ignored.
+func parseColorDirective(ctx context.Context, line string) (color notion.Color, ok bool) {
+	match := colorDirectiveRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return "", false
+	}
+	for _, known := range notionColors {
+		if string(known) == match[1] {
+			return known, true
+		}
+	}
+	warnf(ctx, "Unknown color '%s' in %s, ignoring it\n", match[1], strings.TrimSpace(line))
+	return "", true
+}
+
+// quoteEnd returns the index of the line after the blockquote starting at lines[start].
+// Lines without ">" continue the quote as long as they continue its paragraph text.
+func quoteEnd(lines []string, start int) int {
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if quoteLineRegex.MatchString(lines[i]) {
+			continue
+		}
+		previous := strings.TrimSpace(quoteLineRegex.ReplaceAllString(lines[i-1], ""))
+		if strings.TrimSpace(lines[i]) == "" || previous == "" || nonParagraphLineRegex.MatchString(lines[i]) ||
+			fenceOpening(lines[i]) != "" || thematicBreakRegex.MatchString(lines[i]) {
+			break
+		}
+	}
+	return i
+}
+
+// convertQuote converts the blockquote spanning lines[start:end] into a single quote block.
+// Its first paragraph becomes the quote text and everything after it the quote's children, so
+// multi-paragraph quotes, lists and code inside a quote keep their structure. A first line
+// holding a color directive ("> {color=blue}") colors the quote. Quotes opening with a GitHub
+// alert marker ("> [!NOTE]") or an emoji ("> 💡 Tip") become callouts instead.
+func (c *markdownConverter) convertQuote(ctx context.Context, lines []string, start, end int) (notion.Block, error) {
+	inner := make([]string, 0, end-start)
+	for _, line := range lines[start:end] {
+		inner = append(inner, quoteLineRegex.ReplaceAllString(line, ""))
+	}
+
+	if callout, ok, err := c.convertAlert(ctx, inner); ok || err != nil {
+		return callout, err
+	}
+
+	quote := &notion.QuoteBlock{RichText: []notion.RichText{}}
+	if color, ok := parseColorDirective(ctx, inner[0]); ok {
+		quote.Color = color
+		inner = inner[1:]
+	}
+
+	blocks, err := c.convert(ctx, strings.Join(inner, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 {
+		if paragraph, ok := blocks[0].(*notion.ParagraphBlock); ok && len(paragraph.Children) == 0 {
+			quote.RichText = paragraph.RichText
+			blocks = blocks[1:]
+		}
+	}
+	if len(blocks) > 0 {
+		quote.Children = blocks
+	}
+	if callout, ok := calloutFromQuote(quote); ok {
+		return callout, nil
+	}
+	return quote, nil
+}
+
+// calloutFromQuote turns a quote whose text starts with an emoji ("> 💡 Remember to save") into
+// a callout with the emoji as its icon. ok is false if the quote doesn't start with one.
+func calloutFromQuote(quote *notion.QuoteBlock) (*notion.CalloutBlock, bool) {
+	if len(quote.RichText) == 0 || !isPlainTextRun(quote.RichText[0]) {
+		return nil, false
+	}
+	// The emoji and the space after it are in the first run, the text may go on in formatted runs
+	first := quote.RichText[0]
+	text := richTextPlainText(quote.RichText)
+	emoji, rest := leadingEmoji(text)
+	cut := len(text) - len(rest)
+	if emoji == "" || cut > len(first.Text.Content) {
+		return nil, false
+	}
+	richText := quote.RichText[1:]
+	if content := first.Text.Content[cut:]; content != "" {
+		richText = append([]notion.RichText{textRun(content, first.Annotations)}, richText...)
+	}
+	return &notion.CalloutBlock{
+		RichText: richText,
+		Icon:     &notion.Icon{Type: notion.IconTypeEmoji, Emoji: &emoji},
+		Color:    quote.Color,
+		Children: quote.Children,
+	}, true
+}
@@ -0,0 +1,162 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestConvertColoredMultiParagraphQuote(t *testing.T) {
+	blocks := convert(t, "> {color=blue_background}\n> First paragraph\n> continues here.\n>\n> Second paragraph.\n>\n> - a point\n\nAfter.\n")
+	if got := blockTypes(blocks); !slices.Equal(got, []string{"notion.QuoteBlock", "notion.ParagraphBlock"}) {
+		t.Fatalf("blocks = %v, want one quote and the paragraph after it", got)
+	}
+	quote := blocks[0].(*notion.QuoteBlock)
+	if quote.Color != notion.ColorBlueBg {
+		t.Errorf("color = %q, want %q", quote.Color, notion.ColorBlueBg)
+	}
+	if got := ownText(quote); got != "First paragraph\ncontinues here." {
+		t.Errorf("quote text = %q, want the first paragraph", got)
+	}
+	children := quote.Children
+	if got := blockTypes(children); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.BulletedListItemBlock"}) {
+		t.Fatalf("quote children = %v, want the second paragraph and the list", got)
+	}
+	if got := ownText(children[0]); got != "Second paragraph." {
+		t.Errorf("second paragraph = %q", got)
+	}
+}
+
+func TestConvertQuoteColors(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		wantColor notion.Color
+		wantText  string
+		warnings  int
+	}{
+		{name: "plain quote", markdown: "> Quoted.\n", wantText: "Quoted."},
+		{name: "text color", markdown: "> {color=red}\n> Quoted.\n", wantColor: notion.ColorRed, wantText: "Quoted."},
+		{name: "quoted value", markdown: "> { color = \"gray\" }\n> Quoted.\n", wantColor: notion.ColorGray, wantText: "Quoted."},
+		{name: "unknown color warns", markdown: "> {color=teal}\n> Quoted.\n", wantText: "Quoted.", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			blocks, err := convertMarkdown(ctx, tt.markdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one quote", blockTypes(blocks))
+			}
+			quote, ok := blocks[0].(*notion.QuoteBlock)
+			if !ok {
+				t.Fatalf("block = %T, want a quote", blocks[0])
+			}
+			if quote.Color != tt.wantColor || ownText(quote) != tt.wantText {
+				t.Errorf("quote = %q colored %q, want %q colored %q", ownText(quote), quote.Color, tt.wantText, tt.wantColor)
+			}
+			if n := warningCount(ctx); n != tt.warnings {
+				t.Errorf("got %d warnings, want %d", n, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestSyncFileColoredQuote(t *testing.T) {
+	client := newFakeNotionClient()
+	markdown := "# Title\n\n> {color=yellow_background}\n> One.\n>\n> Two.\n"
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if len(content) != 1 {
+		t.Fatalf("content = %v, want one quote", blockTypes(content))
+	}
+	quote, ok := content[0].(*notion.QuoteBlock)
+	if !ok || quote.Color != notion.ColorYellowBg || ownText(quote) != "One." {
+		t.Fatalf("content = %#v, want the colored quote", content[0])
+	}
+	if children := blockChildren(quote); len(children) != 1 || ownText(children[0]) != "Two." {
+		t.Errorf("quote children = %v, want the second paragraph", blockTypes(children))
+	}
+}
+
+func TestConvertEmojiCallouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		markdown     string
+		wantIcon     string
+		wantRuns     []string
+		wantColor    notion.Color
+		wantChildren []string
+	}{
+		{
+			name:     "emoji",
+			markdown: "> 💡 Remember to save your work\n",
+			wantIcon: "💡",
+			wantRuns: []string{"Remember to save your work"},
+		},
+		{
+			name:     "emoji before formatted text",
+			markdown: "> ⚠️ **Careful** with this\n",
+			wantIcon: "⚠️",
+			wantRuns: []string{"Careful[b]", " with this"},
+		},
+		{
+			name:         "multi-line",
+			markdown:     "> 📝 First line\n> second line.\n>\n> More detail.\n>\n> - a point\n",
+			wantIcon:     "📝",
+			wantRuns:     []string{"First line\nsecond line."},
+			wantChildren: []string{"More detail.", "a point"},
+		},
+		{
+			name:      "colored",
+			markdown:  "> {color=yellow_background}\n> 🚧 Under construction\n",
+			wantIcon:  "🚧",
+			wantRuns:  []string{"Under construction"},
+			wantColor: notion.ColorYellowBg,
+		},
+		{name: "no emoji", markdown: "> Remember to save your work\n", wantRuns: []string{"Remember to save your work"}},
+		{name: "emoji later in the text", markdown: "> Save often 💡\n", wantRuns: []string{"Save often 💡"}},
+		{name: "emoji without text after it", markdown: "> 💡\n", wantRuns: []string{"💡"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			if len(blocks) != 1 {
+				t.Fatalf("blocks = %v, want one block", blockTypes(blocks))
+			}
+			var color notion.Color
+			switch b := blocks[0].(type) {
+			case *notion.CalloutBlock:
+				if tt.wantIcon == "" {
+					t.Fatalf("got a callout, want a quote")
+				}
+				if b.Icon == nil || b.Icon.Emoji == nil || *b.Icon.Emoji != tt.wantIcon {
+					t.Errorf("icon = %+v, want %q", b.Icon, tt.wantIcon)
+				}
+				color = b.Color
+			case *notion.QuoteBlock:
+				if tt.wantIcon != "" {
+					t.Fatalf("got a quote, want a callout with %q", tt.wantIcon)
+				}
+				color = b.Color
+			default:
+				t.Fatalf("block = %T, want a callout or a quote", blocks[0])
+			}
+			if got := annotatedRuns(blockRichText(blocks[0])); !slices.Equal(got, tt.wantRuns) {
+				t.Errorf("text = %q, want %q", got, tt.wantRuns)
+			}
+			if color != tt.wantColor {
+				t.Errorf("color = %q, want %q", color, tt.wantColor)
+			}
+			if got := pageTexts(blockChildren(blocks[0])); !slices.Equal(got, tt.wantChildren) {
+				t.Errorf("children = %q, want %q", got, tt.wantChildren)
+			}
+		})
+	}
+}
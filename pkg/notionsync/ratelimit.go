@@ -0,0 +1,45 @@
+package notionsync
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every request to Notion, so concurrent requests
+// together stay under the configured rate. A nil RateLimiter doesn't limit.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perSecond requests per second on average, with
+// bursts of at most one second's worth. A rate of zero or less returns nil, disabling limiting.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{rate: perSecond, tokens: max(perSecond, 1), last: time.Now()}
+}
+
+// wait blocks until the caller may send a request. Each call takes a token, refilled at the
+// configured rate; callers arriving when the bucket is empty queue up behind each other.
+// Fails with the context's error if ctx is done before the wait is over.
+func (l *RateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, max(l.rate, 1))
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	return sleep(ctx, delay)
+}
@@ -0,0 +1,102 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBoundsConcurrentRequests(t *testing.T) {
+	const (
+		rate       = 40.0
+		goroutines = 6
+		perRoutine = 10
+	)
+	var mu sync.Mutex
+	var sent []time.Time
+	n := NewNotionHTTP("token", DefaultNotionVersion)
+	n.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		mu.Lock()
+		sent = append(sent, time.Now())
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}}
+	})}
+	n.RateLimiter = NewRateLimiter(rate)
+
+	// Every goroutine makes its own requests, only the shared limiter holds them back together
+	ctx := NewContext(context.Background(), testOptions())
+	started := time.Now()
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perRoutine {
+				resp, err := n.Get(ctx, "https://api.notion.com/v1/users/me")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(sent) != goroutines*perRoutine {
+		t.Fatalf("sent %d requests, want %d", len(sent), goroutines*perRoutine)
+	}
+	slices.SortFunc(sent, func(a, b time.Time) int { return a.Compare(b) })
+	// A burst of one second's worth goes out at once, the rest at the configured rate
+	const slack = 20 * time.Millisecond
+	for i, at := range sent {
+		earliest := time.Duration(float64(i+1-int(rate)) / rate * float64(time.Second))
+		if elapsed := at.Sub(started); elapsed < earliest-slack {
+			t.Errorf("request %d went out after %s, want no sooner than %s", i+1, elapsed, earliest)
+		}
+	}
+	// Over the whole run the rate stays within the bound
+	if elapsed := sent[len(sent)-1].Sub(started).Seconds(); float64(len(sent))-rate > elapsed*rate+1 {
+		t.Errorf("sent %d requests in %.2fs, over %v per second after the burst", len(sent), elapsed, rate)
+	}
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("first wait = %v, want the burst token", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want the context's deadline", err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("wait took %s, want it to stop with the context", elapsed)
+	}
+}
+
+func TestNewRateLimiterDisabled(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		limiter := NewRateLimiter(rate)
+		if limiter != nil {
+			t.Errorf("NewRateLimiter(%v) = %+v, want nil", rate, limiter)
+		}
+		started := time.Now()
+		for range 100 {
+			if err := limiter.wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if elapsed := time.Since(started); elapsed > 50*time.Millisecond {
+			t.Errorf("a nil limiter waited %s", elapsed)
+		}
+	}
+}
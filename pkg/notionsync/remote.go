@@ -0,0 +1,91 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// downloadRemoteImage downloads the image at url into a temporary directory, following
+// redirects, and returns the file's path and a function removing it. The file is named after
+// the URL, with the extension of the served Content-Type when the URL has none, so the upload
+// gets a sensible name and content type. Responses that aren't images fail.
+func downloadRemoteImage(ctx context.Context, client *http.Client, url string) (string, func(), error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("download error %d for %s", resp.StatusCode, url)
+	}
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType != "" && !strings.HasPrefix(mimeType, "image/") {
+		return "", nil, fmt.Errorf("%s is served as %s, not an image", url, mimeType)
+	}
+
+	// The final URL after redirects names the file better than a redirecting short link
+	name := path.Base(resp.Request.URL.Path)
+	if name == "/" || name == "." {
+		name = "image"
+	}
+	if path.Ext(name) == "" {
+		name += dataURIImageTypes[mimeType]
+	}
+	dir, err := os.MkdirTemp("", "notionmd-remote-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return file.Name(), cleanup, nil
+}
+
+// uploadRemoteImage uploads the image at url as a Notion file upload, downloading it through
+// the image cache when there is one. The ID is empty when the download failed and the image
+// should stay an external URL.
+func uploadRemoteImage(ctx context.Context, url string, notionClient NotionClientInterface, opts ImageOptions) (string, error) {
+	var (
+		imagePath string
+		err       error
+	)
+	if opts.Cache != nil {
+		imagePath, _, err = opts.Cache.Fetch(ctx, url)
+	} else {
+		var cleanup func()
+		imagePath, cleanup, err = downloadRemoteImage(ctx, opts.HTTPClient, url)
+		if err == nil {
+			defer cleanup()
+		}
+	}
+	if err != nil {
+		warnf(ctx, "Failed to download %s, keeping it as an external image: %s\n", url, err)
+		return "", nil
+	}
+	return notionClient.UploadFile(ctx, imagePath)
+}
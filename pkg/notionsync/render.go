@@ -0,0 +1,242 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// renderMarkdown renders blocks back into markdown, used to check what conversion keeps
+func renderMarkdown(blocks []notion.Block) string {
+	var sb strings.Builder
+	renderBlocks(&sb, blocks, "")
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// renderBlocks writes blocks at the given indentation. Blocks are separated by a blank
+// line, except consecutive items of the same list.
+func renderBlocks(sb *strings.Builder, blocks []notion.Block, indent string) {
+	number := 0
+	previous := ""
+	for i, block := range blocks {
+		kind := blockTypeName(block)
+		if kind == "numbered_list_item" {
+			number++
+		} else {
+			number = 0
+		}
+		if i > 0 && !(kind == previous && isListKind(kind)) {
+			sb.WriteString("\n")
+		}
+		previous = kind
+
+		childIndent := indent + "  "
+		text := renderRichText(blockRichText(block))
+		switch kind {
+		case "heading_1", "heading_2", "heading_3":
+			writeLines(sb, indent, strings.Repeat("#", headingLevel(block))+" "+text)
+		case "paragraph":
+			writeLines(sb, indent, text)
+		case "bulleted_list_item":
+			writeLines(sb, indent, "- "+text)
+		case "numbered_list_item":
+			marker := fmt.Sprintf("%d. ", number)
+			writeLines(sb, indent, marker+text)
+			childIndent = indent + strings.Repeat(" ", len(marker))
+		case "to_do":
+			checkbox := "[ ] "
+			if isChecked(block) {
+				checkbox = "[x] "
+			}
+			writeLines(sb, indent, "- "+checkbox+text)
+		case "quote", "callout":
+			// Children of a quote stay inside it
+			var quoted strings.Builder
+			quoted.WriteString(text + "\n")
+			if children := blockChildren(block); len(children) > 0 {
+				quoted.WriteString("\n")
+				renderBlocks(&quoted, children, "")
+			}
+			quotedLines := strings.Split(strings.TrimRight(quoted.String(), "\n"), "\n")
+			for j, line := range quotedLines {
+				quotedLines[j] = strings.TrimRight("> "+line, " ")
+			}
+			writeLines(sb, indent, strings.Join(quotedLines, "\n"))
+			continue
+		case "code":
+			code := richTextPlainText(blockRichText(block))
+			fence := codeFence(code)
+			writeLines(sb, indent, fence+renderCodeLanguage(block)+"\n"+code+"\n"+fence)
+		case "toggle":
+			writeLines(sb, indent, "<details>\n<summary>"+text+"</summary>\n")
+			renderBlocks(sb, blockChildren(block), indent)
+			writeLines(sb, indent, "\n</details>")
+			continue
+		case "table":
+			writeLines(sb, indent, renderTable(block))
+		case "divider":
+			writeLines(sb, indent, "---")
+		case "bookmark":
+			if bookmark, ok := block.(*notion.BookmarkBlock); ok {
+				writeLines(sb, indent, bookmark.URL)
+			}
+		default:
+			writeLines(sb, indent, "<!-- "+kind+" block -->")
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			// A list nested in a list item keeps the list tight
+			if !isListKind(kind) || !isListKind(blockTypeName(children[0])) {
+				sb.WriteString("\n")
+			}
+			renderBlocks(sb, children, childIndent)
+		}
+	}
+}
+
+// renderTable renders a table block as a GFM table, its first row being the header
+func renderTable(block notion.Block) string {
+	table, ok := block.(*notion.TableBlock)
+	if !ok || len(table.Children) == 0 {
+		return ""
+	}
+	var rows []string
+	for i, child := range table.Children {
+		row, ok := child.(*notion.TableRowBlock)
+		if !ok {
+			continue
+		}
+		cells := make([]string, len(row.Cells))
+		for j, cell := range row.Cells {
+			cells[j] = strings.ReplaceAll(renderRichText(cell), "|", `\|`)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// isListKind reports whether blocks of the given type form a list
+func isListKind(kind string) bool {
+	return kind == "bulleted_list_item" || kind == "numbered_list_item" || kind == "to_do"
+}
+
+// isChecked reports whether a to-do block is checked
+func isChecked(block notion.Block) bool {
+	switch b := block.(type) {
+	case *notion.ToDoBlock:
+		return b.Checked != nil && *b.Checked
+	case notion.ToDoBlock:
+		return b.Checked != nil && *b.Checked
+	}
+	return false
+}
+
+// renderCodeLanguage returns the fence info string for a code block's language
+func renderCodeLanguage(block notion.Block) string {
+	var language *string
+	switch b := block.(type) {
+	case *notion.CodeBlock:
+		language = b.Language
+	case notion.CodeBlock:
+		language = b.Language
+	}
+	if language == nil || *language == "plain text" {
+		return ""
+	}
+	return *language
+}
+
+// codeFence returns a backtick fence longer than any backtick run in code, or a tilde fence
+// when that keeps the fence at three characters, so the code can't close its own fence
+func codeFence(code string) string {
+	longest := func(ch rune) int {
+		longest, n := 0, 0
+		for _, r := range code {
+			if r == ch {
+				n++
+				longest = max(longest, n)
+			} else {
+				n = 0
+			}
+		}
+		return longest
+	}
+	backticks := longest('`')
+	if backticks < 3 {
+		return "```"
+	}
+	if longest('~') < 3 {
+		return "~~~"
+	}
+	return strings.Repeat("`", backticks+1)
+}
+
+// writeLines writes text with every line indented, followed by a newline
+func writeLines(sb *strings.Builder, indent, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		if line != "" {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
+
+// renderRichText renders rich text runs as inline markdown
+func renderRichText(richText []notion.RichText) string {
+	var sb strings.Builder
+	for _, rt := range richText {
+		if rt.Text == nil {
+			sb.WriteString(rt.PlainText)
+			continue
+		}
+		text := rt.Text.Content
+		if ann := rt.Annotations; ann != nil {
+			if ann.Code {
+				text = "`" + text + "`"
+			}
+			if ann.Strikethrough {
+				text = "~~" + text + "~~"
+			}
+			if ann.Italic {
+				text = "_" + text + "_"
+			}
+			if ann.Bold {
+				text = "**" + text + "**"
+			}
+		}
+		if rt.Text.Link != nil {
+			text = "[" + text + "](" + rt.Text.Link.URL + ")"
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// printRoundtrip prints the line diff between the markdown input and the markdown rendered
+// from its converted blocks, showing where conversion loses fidelity
+func printRoundtrip(ctx context.Context, input, rendered string) {
+	inputLines := strings.Split(strings.TrimRight(input, "\n"), "\n")
+	renderedLines := strings.Split(strings.TrimRight(rendered, "\n"), "\n")
+	lost, added := 0, 0
+	for _, op := range diffStrings(inputLines, renderedLines) {
+		switch op.Kind {
+		case '-':
+			lost++
+		case '+':
+			added++
+		default:
+			continue
+		}
+		fmt.Fprintf(output(ctx), "%c %s\n", op.Kind, op.Text)
+	}
+	if lost == 0 && added == 0 {
+		fmt.Fprintln(output(ctx), "✅ Round trip is stable: the rendered markdown matches the input.")
+		return
+	}
+	fmt.Fprintf(output(ctx), "Round trip: %d input lines lost or changed, %d lines differ in the output\n", lost, added)
+}
@@ -0,0 +1,60 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{"headings", "# One\n\n## Two\n\n### Three\n"},
+		{"paragraphs", "First paragraph with **bold**, _italic_ text.\n\nSecond with a [link](https://go.dev).\n"},
+		{"bulleted list", "- one\n- two\n  - nested\n- three\n"},
+		{"numbered list", "1. one\n2. two\n   1. nested\n3. three\n"},
+		{"code", "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n"},
+		{"mixed", "# Title\n\nIntro.\n\n- item\n\n```yaml\nkey: value\n```\n\nOutro.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(convert(t, tt.markdown)); got != tt.markdown {
+				t.Errorf("rendered =\n%s\nwant\n%s", got, tt.markdown)
+			}
+		})
+	}
+}
+
+func TestSyncFileRoundtrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{"stable", "# Title\n\ntext\n", []string{"✅ Round trip is stable"}},
+		{"setext heading", "Title\n=====\n\ntext\n", []string{"- Title", "- =====", "+ # Title", "Round trip: 2 input lines lost or changed, 1 lines differ in the output"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			var out bytes.Buffer
+			opts := testOptions()
+			opts.Roundtrip, opts.TitleLevel = true, 0
+			opts.StatusOutput = &out
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, tt.markdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			if len(client.calls) != 0 {
+				t.Errorf("calls = %v, want the page left alone", client.calls)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output is missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Report is the JSON report of a whole run, written with --report-file or printed by
+// --output json. Syncs given one in SyncOptions.Report record their documents in it, one
+// after another. All its methods are no-ops on a nil report.
+type Report struct {
+	path string
+	// result receives the report as JSON when the run finishes, nil when only path gets it
+	result io.Writer
+	// mu guards the records made while images are uploaded concurrently
+	mu sync.Mutex
+
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Args       []string        `json:"args"`
+	Status     string          `json:"status"`
+	ExitCode   int             `json:"exit_code"`
+	Error      string          `json:"error,omitempty"`
+	Files      []*fileReport   `json:"files"`
+	APICalls   []apiCallReport `json:"api_calls"`
+	Warnings   []string        `json:"warnings"`
+}
+
+// fileReport is the part of the report about syncing one markdown document
+type fileReport struct {
+	File           string         `json:"file"`
+	PageID         string         `json:"page_id,omitempty"`
+	Operation      string         `json:"operation"`
+	Status         string         `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	HashCheck      *hashReport    `json:"hash_check,omitempty"`
+	BlocksSent     int            `json:"blocks_sent"`
+	BlockTypes     map[string]int `json:"block_types,omitempty"`
+	ImagesUploaded []uploadReport `json:"images_uploaded,omitempty"`
+	Warnings       []string       `json:"warnings,omitempty"`
+}
+
+// hashReport records how a change check compared the content with what was synced before
+type hashReport struct {
+	Method    string `json:"method"`
+	Stored    string `json:"stored"`
+	Content   string `json:"content"`
+	Unchanged bool   `json:"unchanged"`
+}
+
+// uploadReport records one uploaded file
+type uploadReport struct {
+	Path         string `json:"path"`
+	FileUploadID string `json:"file_upload_id"`
+}
+
+// apiCallReport records the timing of one call to Notion
+type apiCallReport struct {
+	Method     string  `json:"method"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// NewReport starts a run report, written when the run finishes to path unless it is empty and
+// to result unless it is nil. The arguments are recorded without the token.
+func NewReport(path string, args []string, result io.Writer) *Report {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && args[i-1] == "--token":
+			redacted[i] = "***"
+		case strings.HasPrefix(arg, "--token="):
+			redacted[i] = "--token=***"
+		default:
+			redacted[i] = arg
+		}
+	}
+	return &Report{path: path, result: result, StartedAt: time.Now().UTC(), Args: redacted}
+}
+
+// current returns the report of the document being synced, nil if there is none
+func (r *Report) current() *fileReport {
+	if r == nil || len(r.Files) == 0 {
+		return nil
+	}
+	return r.Files[len(r.Files)-1]
+}
+
+// beginFile starts the report of a markdown document synced with the given operation
+func (r *Report) beginFile(mdPath, pageID, operation string) {
+	if r != nil {
+		r.Files = append(r.Files, &fileReport{File: mdPath, PageID: pageID, Operation: operation, Status: "started"})
+	}
+}
+
+// endFile records the outcome of syncing the current document
+func (r *Report) endFile(err error) {
+	file := r.current()
+	if file == nil {
+		return
+	}
+	file.Status = syncStatus(err)
+	if file.Status == "failed" {
+		file.Error = err.Error()
+	}
+}
+
+// setPageID records the page the current document syncs to once it is known
+func (r *Report) setPageID(pageID string) {
+	if file := r.current(); file != nil {
+		file.PageID = pageID
+	}
+}
+
+// hashCheck records the change check of the current document
+func (r *Report) hashCheck(method, stored, content string) {
+	if file := r.current(); file != nil {
+		file.HashCheck = &hashReport{Method: method, Stored: stored, Content: content, Unchanged: stored == content}
+	}
+}
+
+// warning records a warning, for the run and the current document
+func (r *Report) warning(message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, message)
+	if file := r.current(); file != nil {
+		file.Warnings = append(file.Warnings, message)
+	}
+}
+
+// apiCall records the timing of a call to Notion
+func (r *Report) apiCall(method string, started time.Time, err error) {
+	if r == nil {
+		return
+	}
+	call := apiCallReport{Method: method, DurationMS: float64(time.Since(started).Microseconds()) / 1000}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.APICalls = append(r.APICalls, call)
+}
+
+// blocksSent records blocks sent to Notion for the current document
+func (r *Report) blocksSent(blocks []notion.Block) {
+	file := r.current()
+	if file == nil {
+		return
+	}
+	if file.BlockTypes == nil {
+		file.BlockTypes = make(map[string]int)
+	}
+	file.BlocksSent += len(blocks)
+	for _, block := range blocks {
+		file.BlockTypes[blockTypeName(block)]++
+	}
+}
+
+// upload records an uploaded file of the current document
+func (r *Report) upload(path, fileUploadID string) {
+	if file := r.current(); file != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		file.ImagesUploaded = append(file.ImagesUploaded, uploadReport{Path: path, FileUploadID: fileUploadID})
+	}
+}
+
+// Fail records why the run failed, for failures outside the sync of a document. The first
+// error recorded is kept.
+func (r *Report) Fail(err error) {
+	if r != nil && r.Error == "" {
+		r.Error = err.Error()
+	}
+}
+
+// Finish writes the report with the run's exit code to its file and result writer
+func (r *Report) Finish(exitCode int) error {
+	if r == nil {
+		return nil
+	}
+	r.FinishedAt = time.Now().UTC()
+	r.ExitCode = exitCode
+	r.Status = "success"
+	if exitCode != 0 {
+		r.Status = "failed"
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the report: %w", err)
+	}
+	data = append(data, '\n')
+	if r.path != "" {
+		if err := os.WriteFile(r.path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write report file '%s': %w", r.path, err)
+		}
+	}
+	if r.result != nil {
+		r.result.Write(data)
+	}
+	return nil
+}
+
+// WithReport wraps client so every call is recorded in report, client is returned as it is
+// when report is nil
+func WithReport(client NotionClientInterface, report *Report) NotionClientInterface {
+	if report == nil {
+		return client
+	}
+	return reportingClient{client: client, report: report}
+}
+
+// reportingClient wraps a Notion client, recording every call in the run report
+type reportingClient struct {
+	client NotionClientInterface
+	report *Report
+}
+
+func (c reportingClient) UploadFile(ctx context.Context, filePath string) (string, error) {
+	started := time.Now()
+	fileID, err := c.client.UploadFile(ctx, filePath)
+	c.report.apiCall("UploadFile", started, err)
+	if err == nil {
+		c.report.upload(filePath, fileID)
+	}
+	return fileID, err
+}
+
+func (c reportingClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	started := time.Now()
+	blockIDs, err := c.client.AddPageContent(ctx, pageID, blocks)
+	c.report.apiCall("AddPageContent", started, err)
+	if err == nil {
+		c.report.blocksSent(blocks)
+	}
+	return blockIDs, err
+}
+
+func (c reportingClient) ReplaceSection(ctx context.Context, pageID, afterID string, oldIDs []string, blocks []notion.Block) ([]string, error) {
+	started := time.Now()
+	blockIDs, err := c.client.ReplaceSection(ctx, pageID, afterID, oldIDs, blocks)
+	c.report.apiCall("ReplaceSection", started, err)
+	if err == nil {
+		c.report.blocksSent(blocks)
+	}
+	return blockIDs, err
+}
+
+func (c reportingClient) ClearPageContent(ctx context.Context, pageID string) error {
+	started := time.Now()
+	err := c.client.ClearPageContent(ctx, pageID)
+	c.report.apiCall("ClearPageContent", started, err)
+	return err
+}
+
+func (c reportingClient) ClearPageContentAfter(ctx context.Context, pageID string, keep int) error {
+	started := time.Now()
+	err := c.client.ClearPageContentAfter(ctx, pageID, keep)
+	c.report.apiCall("ClearPageContentAfter", started, err)
+	return err
+}
+
+func (c reportingClient) UpdatePageTitle(ctx context.Context, pageID string, titleBlock notion.Block) error {
+	started := time.Now()
+	err := c.client.UpdatePageTitle(ctx, pageID, titleBlock)
+	c.report.apiCall("UpdatePageTitle", started, err)
+	return err
+}
+
+func (c reportingClient) SetPageIconAndCover(ctx context.Context, pageID, icon, cover string) error {
+	started := time.Now()
+	err := c.client.SetPageIconAndCover(ctx, pageID, icon, cover)
+	c.report.apiCall("SetPageIconAndCover", started, err)
+	return err
+}
+
+func (c reportingClient) GetProperty(ctx context.Context, pageID, propName string) (string, error) {
+	started := time.Now()
+	value, err := c.client.GetProperty(ctx, pageID, propName)
+	c.report.apiCall("GetProperty", started, err)
+	return value, err
+}
+
+func (c reportingClient) SetProperty(ctx context.Context, pageID, propName, value string) error {
+	started := time.Now()
+	err := c.client.SetProperty(ctx, pageID, propName, value)
+	c.report.apiCall("SetProperty", started, err)
+	return err
+}
+
+func (c reportingClient) GetLastEdit(ctx context.Context, pageID string) (PageEdit, error) {
+	started := time.Now()
+	edit, err := c.client.GetLastEdit(ctx, pageID)
+	c.report.apiCall("GetLastEdit", started, err)
+	return edit, err
+}
+
+func (c reportingClient) SetProperties(ctx context.Context, pageID string, values map[string]string) error {
+	started := time.Now()
+	err := c.client.SetProperties(ctx, pageID, values)
+	c.report.apiCall("SetProperties", started, err)
+	return err
+}
+
+func (c reportingClient) GetPageContent(ctx context.Context, pageID string) ([]notion.Block, error) {
+	started := time.Now()
+	blocks, err := c.client.GetPageContent(ctx, pageID)
+	c.report.apiCall("GetPageContent", started, err)
+	return blocks, err
+}
+
+func (c reportingClient) VerifyPage(ctx context.Context, pageID string) error {
+	started := time.Now()
+	err := c.client.VerifyPage(ctx, pageID)
+	c.report.apiCall("VerifyPage", started, err)
+	return err
+}
+
+func (c reportingClient) GetStoredHash(ctx context.Context, pageID, storage string) (string, error) {
+	started := time.Now()
+	hash, err := c.client.GetStoredHash(ctx, pageID, storage)
+	c.report.apiCall("GetStoredHash", started, err)
+	return hash, err
+}
+
+func (c reportingClient) SetStoredHash(ctx context.Context, pageID, storage, hash string) error {
+	started := time.Now()
+	err := c.client.SetStoredHash(ctx, pageID, storage, hash)
+	c.report.apiCall("SetStoredHash", started, err)
+	return err
+}
+
+func (c reportingClient) CreateChildPage(ctx context.Context, parentID, title, icon string, blocks []notion.Block) (string, error) {
+	started := time.Now()
+	childID, err := c.client.CreateChildPage(ctx, parentID, title, icon, blocks)
+	c.report.apiCall("CreateChildPage", started, err)
+	if err == nil {
+		c.report.blocksSent(blocks)
+	}
+	return childID, err
+}
+
+func (c reportingClient) CreateDatabasePage(ctx context.Context, databaseID string, title []notion.RichText, values map[string]string, blocks []notion.Block) (string, error) {
+	started := time.Now()
+	pageID, err := c.client.CreateDatabasePage(ctx, databaseID, title, values, blocks)
+	c.report.apiCall("CreateDatabasePage", started, err)
+	if err == nil {
+		c.report.blocksSent(blocks)
+	}
+	return pageID, err
+}
+
+func (c reportingClient) GetChildPages(ctx context.Context, parentID string) (map[string]string, error) {
+	started := time.Now()
+	pages, err := c.client.GetChildPages(ctx, parentID)
+	c.report.apiCall("GetChildPages", started, err)
+	return pages, err
+}
+
+func (c reportingClient) AddBlockComment(ctx context.Context, blockID string, richText []notion.RichText) error {
+	started := time.Now()
+	err := c.client.AddBlockComment(ctx, blockID, richText)
+	c.report.apiCall("AddBlockComment", started, err)
+	return err
+}
+
+func (c reportingClient) AddComment(ctx context.Context, pageID, text string) error {
+	started := time.Now()
+	err := c.client.AddComment(ctx, pageID, text)
+	c.report.apiCall("AddComment", started, err)
+	return err
+}
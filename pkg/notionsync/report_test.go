@@ -0,0 +1,135 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReportFields(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	report := NewReport(reportPath, []string{"--md", "doc.md", "--token", "secret", "--token=secret", "--use-hash"}, nil)
+
+	mdPath := writeMarkdown(t, "# Title\n\nSome text.\n\n![Logo](logo.png)\n\n> {color=teal}\n> Quoted.\n")
+	writeImage(t, mdPath, "logo.png")
+	opts := testOptions()
+	opts.UseHash = true
+	opts.Report = report
+	client := WithReport(newFakeNotionClient(), report)
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Finish(0); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report isn't JSON: %v\n%s", err, data)
+	}
+	if got.Status != "success" || got.ExitCode != 0 || got.StartedAt.IsZero() || got.FinishedAt.Before(got.StartedAt) {
+		t.Errorf("run = %s with exit code %d from %s to %s", got.Status, got.ExitCode, got.StartedAt, got.FinishedAt)
+	}
+	if want := []string{"--md", "doc.md", "--token", "***", "--token=***", "--use-hash"}; !slices.Equal(got.Args, want) {
+		t.Errorf("args = %v, want %v", got.Args, want)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("the report holds the token")
+	}
+	if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "teal") {
+		t.Errorf("warnings = %q, want the unknown color", got.Warnings)
+	}
+	var methods []string
+	for _, call := range got.APICalls {
+		methods = append(methods, call.Method)
+		if call.DurationMS < 0 {
+			t.Errorf("%s took %vms", call.Method, call.DurationMS)
+		}
+	}
+	for _, want := range []string{"GetProperty", "SetProperty", "UploadFile", "AddPageContent"} {
+		if !slices.Contains(methods, want) {
+			t.Errorf("api calls = %v, want %s among them", methods, want)
+		}
+	}
+
+	if len(got.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(got.Files))
+	}
+	file := got.Files[0]
+	if file.File != mdPath || file.PageID != "page" || file.Status != "synced" || file.Error != "" {
+		t.Errorf("file = %+v", file)
+	}
+	if file.HashCheck == nil || file.HashCheck.Method != "property" || file.HashCheck.Unchanged || file.HashCheck.Content == "" {
+		t.Errorf("hash check = %+v, want a changed property hash", file.HashCheck)
+	}
+	if file.BlocksSent != 3 || file.BlockTypes["paragraph"] != 1 || file.BlockTypes["image"] != 1 || file.BlockTypes["quote"] != 1 {
+		t.Errorf("blocks sent = %d %v, want the paragraph, image and quote", file.BlocksSent, file.BlockTypes)
+	}
+	if len(file.ImagesUploaded) != 1 || !strings.HasSuffix(file.ImagesUploaded[0].Path, "logo.png") || file.ImagesUploaded[0].FileUploadID == "" {
+		t.Errorf("uploads = %+v, want logo.png", file.ImagesUploaded)
+	}
+	if len(file.Warnings) != 1 {
+		t.Errorf("file warnings = %q, want 1", file.Warnings)
+	}
+}
+
+func TestReportFailure(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	report := NewReport(reportPath, nil, nil)
+	opts := testOptions()
+	opts.Report = report
+	client := WithReport(&failingAddClient{newFakeNotionClient()}, report)
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\ntext\n"), "page"); err == nil {
+		t.Fatal("sync with a failing client succeeded")
+	}
+	report.Fail(errors.New("first"))
+	report.Fail(errors.New("second"))
+	if err := report.Finish(1); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "failed" || got.ExitCode != 1 || got.Error != "first" {
+		t.Errorf("run = %s with exit code %d and error %q", got.Status, got.ExitCode, got.Error)
+	}
+	if len(got.Files) != 1 || got.Files[0].Status != "failed" || got.Files[0].Error == "" {
+		t.Fatalf("files = %+v, want the failed sync", got.Files)
+	}
+	var failed bool
+	for _, call := range got.APICalls {
+		failed = failed || (call.Method == "AddPageContent" && call.Error != "")
+	}
+	if !failed {
+		t.Errorf("api calls = %+v, want the failed AddPageContent", got.APICalls)
+	}
+}
+
+func TestNilReport(t *testing.T) {
+	var report *Report
+	report.beginFile("doc.md", "page", "append")
+	report.warning("ignored")
+	report.Fail(errors.New("ignored"))
+	if err := report.Finish(1); err != nil {
+		t.Errorf("Finish on a nil report = %v", err)
+	}
+	client := newFakeNotionClient()
+	if WithReport(client, nil) != NotionClientInterface(client) {
+		t.Error("WithReport wrapped the client without a report")
+	}
+}
@@ -0,0 +1,286 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RewriteModes are the accepted --rewrite-mode values: replace the mapping keys anywhere in
+// the markdown (text) or only in link and image destinations (links)
+var RewriteModes = []string{"text", "links"}
+
+// Regular expression to find the destination of a markdown link or image: [text](url "title")
+var linkDestinationRegex = regexp.MustCompile(`(\]\()(<[^>]*>|[^)\s]+)`)
+
+// Regular expression to find the destination of a link reference definition: [id]: url
+var linkReferenceRegex = regexp.MustCompile(`^( {0,3}\[[^\]]+\]:[ \t]*)(<[^>]*>|\S+)`)
+
+// Regular expression to find the href and src attributes of HTML tags
+var urlAttributeRegex = regexp.MustCompile(`(?i)(\s(?:href|src)\s*=\s*)("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+// rewriteTarget is the value of a rewrite-text mapping entry: either the replacement string,
+// or {"to": "...", "regex": true} to treat the key as a regular expression whose capture
+// groups the replacement can reference as $1, ${name}
+type rewriteTarget struct {
+	To    string `json:"to"`
+	Regex bool   `json:"regex"`
+}
+
+// UnmarshalJSON accepts a plain replacement string or a rule object. Objects without a "to"
+// field or with unknown fields are rejected, which tells a single-page mapping apart from a
+// multi-page one.
+func (t *rewriteTarget) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &t.To); err == nil {
+		return nil
+	}
+	var rule struct {
+		To    *string `json:"to"`
+		Regex bool    `json:"regex"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		return err
+	}
+	if rule.To == nil {
+		return errors.New(`rewrite rule is missing "to"`)
+	}
+	t.To, t.Regex = *rule.To, rule.Regex
+	return nil
+}
+
+// regexRewrite is a compiled rewrite-text mapping entry. Literal entries are quoted patterns
+// whose replacement is inserted as is.
+type regexRewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+	literal     bool
+}
+
+// rewriteContent applies rewrite-text mapping from a file to the markdown content, in the
+// given --rewrite-mode
+func rewriteContent(ctx context.Context, mdContent []byte, mdPath, rewriteLink, mode string) ([]byte, error) {
+	rewrite := RewriteTextMap
+	if mode == "links" {
+		rewrite = RewriteLinkMap
+	}
+
+	data, err := readInputFile(ctx, rewriteLink)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading rewrite-text mapping file: %w", err)
+	}
+
+	var singlePage map[string]rewriteTarget
+	if err := json.Unmarshal(data, &singlePage); err == nil {
+		debugf(ctx, "[DEBUG] Detected single-page rewrite mapping with %d links\n", len(singlePage))
+		return applyRewrites(ctx, mdContent, singlePage, rewrite, mode)
+	}
+
+	var multiPage map[string]map[string]rewriteTarget
+	if err := json.Unmarshal(data, &multiPage); err == nil {
+		debugf(ctx, "[DEBUG] Detected multi-page rewrite mapping. Searching for a matching page key in: %s\n", mdPath)
+		var (
+			matchedKey string
+			pageMap    map[string]rewriteTarget
+		)
+		for key, candidate := range multiPage {
+			if strings.Contains(mdPath, key) {
+				matchedKey = key
+				pageMap = candidate
+				break
+			}
+		}
+		if matchedKey != "" {
+			debugf(ctx, "[DEBUG] Found %d links for page key '%s' (matched in: %s)\n", len(pageMap), matchedKey, mdPath)
+			return applyRewrites(ctx, mdContent, pageMap, rewrite, mode)
+		}
+		debugf(ctx, "[DEBUG] No mapping found for any key in '%s'. No rewrite applied.\n", mdPath)
+		return mdContent, nil // no mapping for this page, return original content
+	}
+	debugf(ctx, "[DEBUG] Could not decode rewrite-text mapping file as single or multi-page mapping")
+	return nil, fmt.Errorf("Error decoding rewrite-text mapping file as single or multi-page mapping")
+}
+
+// applyRewrites runs a mapping of only literal entries through rewrite. A mapping with regex
+// entries is applied by rewriteRegexMap, literal and regex entries together in a single pass.
+// An invalid regex fails the whole mapping.
+func applyRewrites(ctx context.Context, mdContent []byte, mapping map[string]rewriteTarget, rewrite func(context.Context, string, map[string]string) string, mode string) ([]byte, error) {
+	literal := make(map[string]string)
+	keys := make([]string, 0, len(mapping))
+	for key, target := range mapping {
+		if !target.Regex {
+			literal[key] = target.To
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(literal) == len(mapping) {
+		return []byte(rewrite(ctx, string(mdContent), literal)), nil
+	}
+
+	sortLongestFirst(keys)
+	rewrites := make([]regexRewrite, 0, len(keys))
+	for _, key := range keys {
+		target := mapping[key]
+		expr := key
+		if !target.Regex {
+			expr = regexp.QuoteMeta(key)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex '%s' in rewrite-text mapping file: %w", key, err)
+		}
+		rewrites = append(rewrites, regexRewrite{pattern: pattern, replacement: target.To, literal: !target.Regex})
+	}
+	return []byte(rewriteRegexMap(ctx, string(mdContent), rewrites, mode)), nil
+}
+
+// rewriteRegexMap replaces the matches of all rewrites in a single pass, expanding capture
+// groups in the replacements of regex entries. Like RewriteTextMap it scans the content once:
+// where several entries match at the same position the first, i.e. the longest key, wins and
+// replaced text is never matched again. In links mode only link destinations are rewritten.
+func rewriteRegexMap(ctx context.Context, content string, rewrites []regexRewrite, mode string) string {
+	fmt.Fprintf(output(ctx), "Rewriting %d patterns:\n", len(rewrites))
+	for _, rewrite := range rewrites {
+		if rewrite.literal {
+			fmt.Fprintf(output(ctx), "Replacing:  '%s' -> '%s'\n", regexpLiteral(rewrite.pattern), rewrite.replacement)
+		} else {
+			fmt.Fprintf(output(ctx), "Replacing pattern:  '%s' -> '%s'\n", rewrite.pattern, rewrite.replacement)
+		}
+	}
+
+	// Each entry is a group of one combined pattern, followed by its own capture groups
+	alternatives := make([]string, len(rewrites))
+	groups := make([]int, len(rewrites))
+	group := 1
+	for i, rewrite := range rewrites {
+		alternatives[i] = "(" + rewrite.pattern.String() + ")"
+		groups[i] = group
+		group += 1 + rewrite.pattern.NumSubexp()
+	}
+	combined := regexp.MustCompile(strings.Join(alternatives, "|"))
+
+	replace := func(s string) string {
+		var sb strings.Builder
+		last := 0
+		for _, match := range combined.FindAllStringSubmatchIndex(s, -1) {
+			sb.WriteString(s[last:match[0]])
+			last = match[1]
+			for i, rewrite := range rewrites {
+				if match[2*groups[i]] < 0 {
+					continue
+				}
+				if rewrite.literal {
+					sb.WriteString(rewrite.replacement)
+				} else {
+					submatches := match[2*groups[i] : 2*(groups[i]+1+rewrite.pattern.NumSubexp())]
+					sb.Write(rewrite.pattern.ExpandString(nil, rewrite.replacement, s, submatches))
+				}
+				break
+			}
+		}
+		sb.WriteString(s[last:])
+		return sb.String()
+	}
+	if mode == "links" {
+		return rewriteLinkDestinations(content, replace)
+	}
+	return replace(content)
+}
+
+// regexpLiteral returns the text a pattern compiled from regexp.QuoteMeta matches
+func regexpLiteral(pattern *regexp.Regexp) string {
+	literal, _ := pattern.LiteralPrefix()
+	return literal
+}
+
+// RewriteTextMap replaces markdown links according to the mapping. The content is scanned
+// once: where several keys match at the same position the longest wins, and replaced text
+// is never matched again, so the result does not depend on the map's iteration order.
+func RewriteTextMap(ctx context.Context, content string, linkMap map[string]string) string {
+	fmt.Fprintf(output(ctx), "Rewriting %d links:\n", len(linkMap))
+	printRewrites(ctx, linkMap)
+	return rewriteReplacer(linkMap).Replace(content)
+}
+
+// RewriteLinkMap replaces the mapping keys in the destinations of markdown links, images and
+// link reference definitions and in HTML href and src attributes, leaving the link text and
+// fenced code alone. Keys match the same way as in RewriteTextMap.
+func RewriteLinkMap(ctx context.Context, content string, linkMap map[string]string) string {
+	fmt.Fprintf(output(ctx), "Rewriting %d link destinations:\n", len(linkMap))
+	printRewrites(ctx, linkMap)
+	return rewriteLinkDestinations(content, rewriteReplacer(linkMap).Replace)
+}
+
+// rewriteLinkDestinations passes every link destination and HTML href and src attribute
+// outside fenced code through replace. The quotes or angle brackets around a destination are
+// not passed, so anchored patterns match the URL itself.
+func rewriteLinkDestinations(content string, replace func(string) string) string {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		if marker := fenceOpening(lines[i]); marker != "" {
+			i = fenceEnd(lines, i, marker) - 1
+			continue
+		}
+		for _, re := range []*regexp.Regexp{linkDestinationRegex, linkReferenceRegex, urlAttributeRegex} {
+			lines[i] = re.ReplaceAllStringFunc(lines[i], func(match string) string {
+				parts := re.FindStringSubmatch(match)
+				destination, open, close := parts[2], "", ""
+				if len(destination) >= 2 && strings.ContainsAny(destination[:1], `"'<`) {
+					open, close = destination[:1], destination[len(destination)-1:]
+					destination = destination[1 : len(destination)-1]
+				}
+				return parts[1] + open + replace(destination) + close
+			})
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printRewrites lists the literal mapping entries, longest key first
+func printRewrites(ctx context.Context, rewrites map[string]string) {
+	for _, key := range sortedRewriteKeys(rewrites) {
+		fmt.Fprintf(output(ctx), "Replacing:  '%s' -> '%s'\n", key, rewrites[key])
+	}
+}
+
+// rewriteReplacer returns a replacer that rewrites every occurrence of a mapping key in a
+// single pass. Keys are tried longest first so a specific rewrite wins over a more general
+// one, and replacements are not rescanned, so "cat" -> "dog" never touches the "group"
+// written for "category". Empty keys are ignored.
+func rewriteReplacer(rewrites map[string]string) *strings.Replacer {
+	var oldnew []string
+	for _, key := range sortedRewriteKeys(rewrites) {
+		if key != "" {
+			oldnew = append(oldnew, key, rewrites[key])
+		}
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// sortedRewriteKeys returns the keys of a mapping longest first
+func sortedRewriteKeys(rewrites map[string]string) []string {
+	keys := make([]string, 0, len(rewrites))
+	for key := range rewrites {
+		keys = append(keys, key)
+	}
+	sortLongestFirst(keys)
+	return keys
+}
+
+// sortLongestFirst orders mapping keys longest first, alphabetically among equal lengths
+func sortLongestFirst(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+}
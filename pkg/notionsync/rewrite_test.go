@@ -0,0 +1,137 @@
+package notionsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		mode    string
+		content string
+		want    string
+	}{
+		{
+			name:    "longer literal wins over its prefix",
+			mapping: `{"cat": "dog", "category": "group"}`,
+			content: "cat category cat",
+			want:    "dog group dog",
+		},
+		{
+			name:    "replacements are not rescanned",
+			mapping: `{"cat": "category", "category": "cat"}`,
+			content: "cat category",
+			want:    "category cat",
+		},
+		{
+			name:    "regex and literal entries overlap",
+			mapping: `{"cat": "dog", "categor(y|ies)": {"to": "group$1", "regex": true}}`,
+			content: "cat category categories",
+			want:    "dog groupy groupies",
+		},
+		{
+			name:    "regex replacements are not rescanned",
+			mapping: `{"c[a]t": {"to": "category", "regex": true}, "categor[y]": {"to": "cat", "regex": true}}`,
+			content: "cat category",
+			want:    "category cat",
+		},
+		{
+			name:    "named capture groups",
+			mapping: `{"v(?P<major>\\d+)\\.(?P<minor>\\d+)": {"to": "version ${major} (minor ${minor})", "regex": true}}`,
+			content: "see v2.5",
+			want:    "see version 2 (minor 5)",
+		},
+		{
+			name:    "literal replacement keeps dollars",
+			mapping: `{"price": "$1", "x+": {"to": "y", "regex": true}}`,
+			content: "price xx",
+			want:    "$1 y",
+		},
+		{
+			name:    "links mode leaves the text alone",
+			mode:    "links",
+			mapping: `{"docs/(\\w+)\\.md": {"to": "https://example.com/$1", "regex": true}}`,
+			content: "docs/setup.md is [here](docs/setup.md)",
+			want:    "docs/setup.md is [here](https://example.com/setup)",
+		},
+		{
+			name:    "relative links made absolute",
+			mode:    "links",
+			mapping: `{"^/docs/(.*)": {"to": "https://example.com/docs/$1", "regex": true}}`,
+			content: "[Setup](/docs/setup.html) and <a href=\"/docs/faq\">FAQ</a>\n\n[ref]: /docs/ref.html\n\n```\n[code](/docs/kept)\n```\n[Other](/blog/docs/post) [Spaced](</docs/a b.html>)",
+			want:    "[Setup](https://example.com/docs/setup.html) and <a href=\"https://example.com/docs/faq\">FAQ</a>\n\n[ref]: https://example.com/docs/ref.html\n\n```\n[code](/docs/kept)\n```\n[Other](/blog/docs/post) [Spaced](<https://example.com/docs/a b.html>)",
+		},
+		{
+			name:    "regex false is literal",
+			mapping: `{"a.b": {"to": "X", "regex": false}, "z+": {"to": "z", "regex": true}}`,
+			content: "a.b axb zzz",
+			want:    "X axb z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappingPath := filepath.Join(t.TempDir(), "rewrite.json")
+			if err := os.WriteFile(mappingPath, []byte(tt.mapping), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			mode := tt.mode
+			if mode == "" {
+				mode = "text"
+			}
+			ctx := NewContext(context.Background(), testOptions())
+			got, err := rewriteContent(ctx, []byte(tt.content), "doc.md", mappingPath, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteContentInvalidMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		wantErr string
+	}{
+		{name: "invalid regex", mapping: `{"ok": "fine", "docs/(.*": {"to": "x", "regex": true}}`, wantErr: "Invalid regex 'docs/(.*' in rewrite-text mapping file"},
+		{name: "invalid regex in a page mapping", mapping: `{"doc": {"[a-": {"to": "x", "regex": true}}}`, wantErr: "Invalid regex '[a-'"},
+		{name: "rule without to", mapping: `{"old": {"regex": true}}`, wantErr: "as single or multi-page mapping"},
+		{name: "rule with unknown fields", mapping: `{"old": {"to": "new", "regexp": true}}`, wantErr: "as single or multi-page mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappingPath := filepath.Join(t.TempDir(), "rewrite.json")
+			if err := os.WriteFile(mappingPath, []byte(tt.mapping), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ctx := NewContext(context.Background(), testOptions())
+			if _, err := rewriteContent(ctx, []byte("text"), "doc.md", mappingPath, "text"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to say %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRewriteContentMultiPage(t *testing.T) {
+	mappingPath := filepath.Join(t.TempDir(), "rewrite.json")
+	mapping := `{"guide": {"old": "new"}, "other": {"old": "wrong"}}`
+	if err := os.WriteFile(mappingPath, []byte(mapping), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext(context.Background(), testOptions())
+	got, err := rewriteContent(ctx, []byte("old text"), "docs/guide.md", mappingPath, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new text" {
+		t.Errorf("got %q, want the guide's mapping applied", got)
+	}
+}
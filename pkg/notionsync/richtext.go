@@ -0,0 +1,244 @@
+package notionsync
+
+import (
+	"regexp"
+	"unicode/utf16"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// transformRichText applies fn to the rich text of every block, recursing into children.
+// Code blocks are skipped so their content is preserved exactly.
+func transformRichText(blocks []notion.Block, fn func([]notion.RichText) []notion.RichText) []notion.Block {
+	for i, block := range blocks {
+		if isCodeBlock(block) {
+			continue
+		}
+		if richText := blockRichText(block); len(richText) > 0 {
+			block = withRichText(block, fn(richText))
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, transformRichText(children, fn))
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// isPlainTextRun reports whether rt is ordinary text that transforms may rewrite:
+// not a mention or equation, not a link and not inline code
+func isPlainTextRun(rt notion.RichText) bool {
+	if rt.Text == nil || rt.Text.Link != nil {
+		return false
+	}
+	return rt.Annotations == nil || !rt.Annotations.Code
+}
+
+// textRun builds a text rich text element carrying the given annotations
+func textRun(content string, annotations *notion.Annotations) notion.RichText {
+	return notion.RichText{
+		Type:        notion.RichTextTypeText,
+		Text:        &notion.Text{Content: content},
+		PlainText:   content,
+		Annotations: annotations,
+	}
+}
+
+// emboldenRichText returns a copy of richText with every run bold
+func emboldenRichText(richText []notion.RichText) []notion.RichText {
+	bold := make([]notion.RichText, len(richText))
+	for i, rt := range richText {
+		annotations := notion.Annotations{}
+		if rt.Annotations != nil {
+			annotations = *rt.Annotations
+		}
+		annotations.Bold = true
+		rt.Annotations = &annotations
+		bold[i] = rt
+	}
+	return bold
+}
+
+// replaceInTextRuns splits plain text runs around every match of re, replacing each match with
+// the rich text fn returns. When fn returns nil the match is kept as text. Annotations of the
+// surrounding text are preserved.
+func replaceInTextRuns(richText []notion.RichText, re *regexp.Regexp, fn func(content string, loc []int) *notion.RichText) []notion.RichText {
+	var result []notion.RichText
+	for _, rt := range richText {
+		if !isPlainTextRun(rt) {
+			result = append(result, rt)
+			continue
+		}
+		content := rt.Text.Content
+		last := 0
+		for _, loc := range re.FindAllStringSubmatchIndex(content, -1) {
+			replacement := fn(content, loc)
+			if replacement == nil {
+				continue
+			}
+			if loc[0] > last {
+				result = append(result, textRun(content[last:loc[0]], rt.Annotations))
+			}
+			if replacement.Annotations == nil {
+				replacement.Annotations = rt.Annotations
+			}
+			result = append(result, *replacement)
+			last = loc[1]
+		}
+		if last == 0 {
+			result = append(result, rt)
+			continue
+		}
+		if last < len(content) {
+			result = append(result, textRun(content[last:], rt.Annotations))
+		}
+	}
+	return result
+}
+
+// precededByWordChar reports whether the match starting at start directly follows a letter or digit
+func precededByWordChar(content string, start int) bool {
+	if start == 0 {
+		return false
+	}
+	ch := content[start-1]
+	return ch == '_' || (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// sameFormatting reports whether two text runs carry identical annotations and link
+func sameFormatting(a, b notion.RichText) bool {
+	var annA, annB notion.Annotations
+	if a.Annotations != nil {
+		annA = *a.Annotations
+	}
+	if b.Annotations != nil {
+		annB = *b.Annotations
+	}
+	var linkA, linkB string
+	if a.Text.Link != nil {
+		linkA = a.Text.Link.URL
+	}
+	if b.Text.Link != nil {
+		linkB = b.Text.Link.URL
+	}
+	return annA == annB && linkA == linkB
+}
+
+// splitRichText keeps every text run within Notion's length limit. Adjacent runs with identical
+// formatting are merged first (undoing byte-based chunking that can cut characters in half), then
+// over-long runs are split at word boundaries. Each piece keeps the run's annotations and link, so
+// splits never cross a formatting, link or mention boundary.
+func splitRichText(richText []notion.RichText) []notion.RichText {
+	var merged []notion.RichText
+	for _, rt := range richText {
+		if n := len(merged); n > 0 && rt.Type == notion.RichTextTypeText && rt.Text != nil &&
+			merged[n-1].Type == notion.RichTextTypeText && merged[n-1].Text != nil && sameFormatting(merged[n-1], rt) {
+			prev := merged[n-1]
+			text := *prev.Text
+			text.Content += rt.Text.Content
+			prev.Text = &text
+			prev.PlainText = text.Content
+			merged[n-1] = prev
+			continue
+		}
+		merged = append(merged, rt)
+	}
+
+	var result []notion.RichText
+	for _, rt := range merged {
+		if rt.Text == nil || textLength(rt.Text.Content) <= maxRichTextLength {
+			result = append(result, rt)
+			continue
+		}
+		for _, piece := range splitAtWordBoundaries(rt.Text.Content, maxRichTextLength) {
+			part := rt
+			part.Text = &notion.Text{Content: piece, Link: rt.Text.Link}
+			part.PlainText = piece
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// splitAtWordBoundaries splits content into pieces of at most limit characters, breaking after
+// the last whitespace within the limit and only mid-word when a word is longer than the limit
+func splitAtWordBoundaries(content string, limit int) []string {
+	return splitAtBoundaries(content, limit, func(r rune) bool { return r == ' ' || r == '\n' || r == '\t' })
+}
+
+// textLength returns the length of content the way Notion counts it, in UTF-16 code units,
+// so characters outside the Basic Multilingual Plane such as most emoji count twice
+func textLength(content string) int {
+	n := 0
+	for _, r := range content {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// splitAtBoundaries splits content into pieces of at most limit characters as counted by
+// textLength, breaking after the last rune within the limit for which isBoundary is true and
+// only mid-run when there is none. Runes are never cut in half.
+func splitAtBoundaries(content string, limit int, isBoundary func(rune) bool) []string {
+	runes := []rune(content)
+	var pieces []string
+	for textLength(string(runes)) > limit {
+		fits, length := 0, 0
+		for fits < len(runes) && length+utf16.RuneLen(runes[fits]) <= limit {
+			length += utf16.RuneLen(runes[fits])
+			fits++
+		}
+		cut := max(fits, 1)
+		for i := fits; i > 0; i-- {
+			if isBoundary(runes[i-1]) {
+				cut = i
+				break
+			}
+		}
+		pieces = append(pieces, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+// codeRichText builds the rich text for code block content, split into runs within Notion's
+// length limit. Splits fall after a newline where possible so the content is kept exactly.
+func codeRichText(content string) []notion.RichText {
+	var result []notion.RichText
+	for _, piece := range splitAtLineBoundaries(content, maxRichTextLength) {
+		result = append(result, textRun(piece, nil))
+	}
+	if len(result) == 0 {
+		result = append(result, textRun("", nil))
+	}
+	return result
+}
+
+// splitAtLineBoundaries splits content into pieces of at most limit characters, breaking after
+// the last newline within the limit and only mid-line when a line is longer than the limit
+func splitAtLineBoundaries(content string, limit int) []string {
+	return splitAtBoundaries(content, limit, func(r rune) bool { return r == '\n' })
+}
+
+// splitCodeRichText splits the over-long runs of a code block's rich text at line boundaries.
+// Code blocks built from fences are already split, this catches the others, such as inline SVG
+// sources and code that notionmd converted itself.
+func splitCodeRichText(richText []notion.RichText) []notion.RichText {
+	var result []notion.RichText
+	for _, rt := range richText {
+		if rt.Text == nil || textLength(rt.Text.Content) <= maxRichTextLength {
+			result = append(result, rt)
+			continue
+		}
+		for _, piece := range splitAtLineBoundaries(rt.Text.Content, maxRichTextLength) {
+			part := rt
+			part.Text = &notion.Text{Content: piece, Link: rt.Text.Link}
+			part.PlainText = piece
+			result = append(result, part)
+		}
+	}
+	return result
+}
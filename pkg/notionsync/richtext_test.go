@@ -0,0 +1,162 @@
+package notionsync
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestSplitRichTextKeepsLinks(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum ", 300)
+	longLinkText := strings.Repeat("docs ", 500)
+	richText := []notion.RichText{
+		textRun(filler, nil),
+		{Type: notion.RichTextTypeText, Text: &notion.Text{Content: "Go", Link: &notion.Link{URL: "https://go.dev"}}, PlainText: "Go"},
+		textRun(filler, nil),
+		{Type: notion.RichTextTypeText, Text: &notion.Text{Content: longLinkText, Link: &notion.Link{URL: "https://example.com/docs"}}, PlainText: longLinkText},
+		textRun(" the end", nil),
+	}
+	split := splitRichText(richText)
+
+	var all strings.Builder
+	links := make(map[string]string)
+	for _, rt := range split {
+		if n := textLength(rt.Text.Content); n > maxRichTextLength {
+			t.Errorf("run of %d characters, want at most %d", n, maxRichTextLength)
+		}
+		all.WriteString(rt.Text.Content)
+		if rt.Text.Link != nil {
+			links[rt.Text.Link.URL] += rt.Text.Content
+		} else if strings.Contains(rt.Text.Content, "docs") || strings.Contains(rt.Text.Content, "Go") {
+			t.Errorf("unlinked run %.40q holds link text", rt.Text.Content)
+		}
+	}
+	if want := filler + "Go" + filler + longLinkText + " the end"; all.String() != want {
+		t.Errorf("splitting changed the text")
+	}
+	if links["https://go.dev"] != "Go" {
+		t.Errorf("go.dev link covers %q, want Go", links["https://go.dev"])
+	}
+	if links["https://example.com/docs"] != longLinkText {
+		t.Errorf("docs link covers %d characters, want all %d of its text", len(links["https://example.com/docs"]), len(longLinkText))
+	}
+	for i, rt := range split[:len(split)-1] {
+		if rt.Text.Link == nil && split[i+1].Text.Link == nil && !strings.HasSuffix(rt.Text.Content, " ") {
+			t.Errorf("plain run %d ends %.20q, want it split at a space", i, rt.Text.Content[len(rt.Text.Content)-20:])
+		}
+	}
+}
+
+func TestSplitRichTextKeepsMentions(t *testing.T) {
+	mention := notion.RichText{
+		Type:      notion.RichTextTypeMention,
+		PlainText: "@Ada",
+		Mention:   &notion.Mention{Type: notion.MentionTypeUser, User: &notion.User{BaseUser: notion.BaseUser{ID: "user-1"}}},
+	}
+	filler := strings.Repeat("word ", 500)
+	split := splitRichText([]notion.RichText{textRun(filler, nil), mention, textRun(filler, nil)})
+	mentions := 0
+	for _, rt := range split {
+		if rt.Type == notion.RichTextTypeMention {
+			mentions++
+			if rt.Mention.User.ID != "user-1" || rt.PlainText != "@Ada" {
+				t.Errorf("mention = %#v, want it unchanged", rt)
+			}
+		}
+	}
+	if mentions != 1 {
+		t.Errorf("got %d mentions, want 1", mentions)
+	}
+}
+
+func TestSyncFileKeepsCodeWhitespace(t *testing.T) {
+	const yaml = "services:\n  web:\n    image: \"nginx:1.25\"   \n    ports:\n      - \"80:80\"\n\n    # due @today, cc @alice\n    command: [\"a  b\", '<a href=\"x\">y</a>']"
+	const makefile = "build:\n\tgo build ./...\n\t@echo  done\n\ntest: build\n\tgo test ./...  # *not* emphasis"
+	markdown := "# Title\n\n```yaml\n" + yaml + "\n```\n\n```makefile\n" + makefile + "\n```\n"
+
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.DateMentions, opts.DatePrefix, opts.EscapeReserved = true, "@", true
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if got := blockTypes(content); !slices.Equal(got, []string{"notion.CodeBlock", "notion.CodeBlock"}) {
+		t.Fatalf("content = %v, want the two code blocks", got)
+	}
+	for i, want := range []string{yaml, makefile} {
+		if got := ownText(content[i]); got != want {
+			t.Errorf("code block %d =\n%q\nwant\n%q", i, got, want)
+		}
+	}
+}
+
+func TestSyncFileLongParagraphAndCode(t *testing.T) {
+	paragraph := strings.TrimSpace(strings.Repeat("lorem ipsum dolor ", 278))[:5000]
+	var code strings.Builder
+	for code.Len() < 3000 {
+		code.WriteString(strings.Repeat("x", 50) + " // line\n")
+	}
+	codeText := strings.TrimSuffix(code.String(), "\n")
+	heading := strings.Repeat("heading ", 300)
+	item := strings.Repeat("item ", 500)
+	markdown := "# Title\n\n" + paragraph + "\n\n```go\n" + codeText + "\n```\n\n## " + heading + "\n\n- " + item + "\n"
+
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), testOptions(), client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	content := client.content["page"]
+	if got := blockTypes(content); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.CodeBlock", "notion.Heading2Block", "notion.BulletedListItemBlock"}) {
+		t.Fatalf("content = %v", got)
+	}
+	for i, want := range []string{paragraph, codeText, strings.TrimSpace(heading), strings.TrimSpace(item)} {
+		richText := blockRichText(content[i])
+		if len(richText) < 2 {
+			t.Errorf("block %d holds %d run, want it split", i, len(richText))
+		}
+		for j, rt := range richText {
+			if n := textLength(rt.Text.Content); n > maxRichTextLength {
+				t.Errorf("block %d run %d has %d characters, want at most %d", i, j, n, maxRichTextLength)
+			}
+			boundary := " "
+			if i == 1 {
+				boundary = "\n"
+			}
+			if j < len(richText)-1 && !strings.HasSuffix(rt.Text.Content, boundary) {
+				t.Errorf("block %d run %d ends %q, want it split after %q", i, j, rt.Text.Content[len(rt.Text.Content)-10:], boundary)
+			}
+		}
+		if got := richTextPlainText(richText); got != want {
+			t.Errorf("block %d text changed by splitting: %d characters, want %d", i, len(got), len(want))
+		}
+	}
+}
+
+func TestSplitAtBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		want    []string
+	}{
+		{name: "fits", content: "one two", limit: 10, want: []string{"one two"}},
+		{name: "after the last space", content: "one two three", limit: 10, want: []string{"one two ", "three"}},
+		{name: "mid-word without a space", content: "abcdefghij", limit: 4, want: []string{"abcd", "efgh", "ij"}},
+		{name: "emoji count twice", content: "ab😀cd", limit: 3, want: []string{"ab", "😀c", "d"}},
+		{name: "emoji aren't cut in half", content: "😀😀😀", limit: 3, want: []string{"😀", "😀", "😀"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitAtWordBoundaries(tt.content, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("splitAtWordBoundaries(%q, %d) = %q, want %q", tt.content, tt.limit, got, tt.want)
+			}
+		})
+	}
+	if got := splitAtLineBoundaries("ab\ncd ef\ngh", 7); !slices.Equal(got, []string{"ab\n", "cd ef\n", "gh"}) {
+		t.Errorf("splitAtLineBoundaries = %q, want breaks after newlines", got)
+	}
+}
@@ -0,0 +1,92 @@
+package notionsync
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// maxLinkLength is the longest link URL Notion accepts
+const maxLinkLength = 2000
+
+// escapeReservedText makes the rich text of blocks safe to send, code included. It only
+// touches what Notion would reject or mangle: control characters and invalid UTF-8 are
+// removed, links that aren't absolute URLs or are too long keep their text but lose the
+// link, and empty text runs are dropped.
+func escapeReservedText(ctx context.Context, blocks []notion.Block) []notion.Block {
+	for i, block := range blocks {
+		if richText := blockRichText(block); len(richText) > 0 {
+			block = withRichText(block, escapeRichText(ctx, richText, isCodeBlock(block)))
+		}
+		if table, ok := block.(*notion.TableBlock); ok {
+			for _, child := range table.Children {
+				if row, ok := child.(*notion.TableRowBlock); ok {
+					for j, cell := range row.Cells {
+						row.Cells[j] = escapeRichText(ctx, cell, false)
+					}
+				}
+			}
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, escapeReservedText(ctx, children))
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// escapeRichText applies escapeReservedText to the runs of a single rich text value. Code
+// keeps its tabs and line breaks like any other text, but its runs are never dropped.
+func escapeRichText(ctx context.Context, richText []notion.RichText, isCode bool) []notion.RichText {
+	result := make([]notion.RichText, 0, len(richText))
+	for _, rt := range richText {
+		if rt.Text == nil {
+			result = append(result, rt)
+			continue
+		}
+		text := *rt.Text
+		if cleaned := removeControlCharacters(text.Content); cleaned != text.Content {
+			warnf(ctx, "Removed control characters or invalid UTF-8 from text '%s'\n", truncateText(cleaned, 40))
+			text.Content = cleaned
+		}
+		if text.Link != nil && !isValidLink(text.Link.URL) {
+			warnf(ctx, "Dropped link '%s' Notion would reject, keeping its text '%s'\n", truncateText(text.Link.URL, 80), text.Content)
+			text.Link = nil
+		}
+		if text.Content == "" && !isCode && len(richText) > 1 {
+			continue
+		}
+		rt.Text = &text
+		rt.PlainText = text.Content
+		result = append(result, rt)
+	}
+	return result
+}
+
+// removeControlCharacters strips invalid UTF-8, noncharacters and control characters other
+// than tabs and line breaks
+func removeControlCharacters(content string) string {
+	content = strings.ToValidUTF8(content, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == 0xFFFE || r == 0xFFFF {
+			return -1
+		}
+		return r
+	}, content)
+}
+
+// isValidLink reports whether Notion accepts url as a link: an absolute URL with a scheme,
+// without whitespace and within the length limit
+func isValidLink(link string) bool {
+	if link == "" || len(link) > maxLinkLength || strings.ContainsAny(link, " \t\n") {
+		return false
+	}
+	parsed, err := url.Parse(link)
+	return err == nil && parsed.Scheme != "" && (parsed.Host != "" || parsed.Opaque != "")
+}
@@ -0,0 +1,128 @@
+package notionsync
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestRemoveControlCharacters(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "plain text is kept", content: "plain text", want: "plain text"},
+		{name: "tabs and line breaks are kept", content: "a\tb\nc", want: "a\tb\nc"},
+		{name: "null and escape are removed", content: "a\x00b\x1b[31mc", want: "ab[31mc"},
+		{name: "invalid UTF-8 is removed", content: "caf\xc3 ok\xff", want: "caf ok"},
+		{name: "noncharacters are removed", content: "x\uFFFEy\uFFFF", want: "xy"},
+		{name: "reserved markup is left alone", content: "<aside> {{x}} $$ @[id]", want: "<aside> {{x}} $$ @[id]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeControlCharacters(tt.content); got != tt.want {
+				t.Errorf("removeControlCharacters(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.com/page", true},
+		{"mailto:someone@example.com", true},
+		{"", false},
+		{"docs/page.md", false},
+		{"#anchor", false},
+		{"https://example.com/a page", false},
+		{"https://example.com/" + strings.Repeat("a", maxLinkLength), false},
+	}
+	for _, tt := range tests {
+		if got := isValidLink(tt.link); got != tt.want {
+			t.Errorf("isValidLink(%q) = %v, want %v", truncateText(tt.link, 40), got, tt.want)
+		}
+	}
+}
+
+func TestEscapeReservedTextWarns(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	blocks := []notion.Block{
+		&notion.ParagraphBlock{RichText: []notion.RichText{
+			{Text: &notion.Text{Content: "bell\x07"}},
+			{Text: &notion.Text{Content: "relative", Link: &notion.Link{URL: "docs/page.md"}}},
+			{Text: &notion.Text{Content: ""}},
+		}},
+	}
+	got := escapeReservedText(ctx, blocks)
+	richText := got[0].(*notion.ParagraphBlock).RichText
+	if len(richText) != 2 {
+		t.Fatalf("got %d runs, want the empty run dropped", len(richText))
+	}
+	if richText[0].Text.Content != "bell" || richText[0].PlainText != "bell" {
+		t.Errorf("first run = %q, want the control character removed", richText[0].Text.Content)
+	}
+	if richText[1].Text.Link != nil || richText[1].Text.Content != "relative" {
+		t.Errorf("second run = %+v, want the text without its link", richText[1].Text)
+	}
+	if n := warningCount(ctx); n != 2 {
+		t.Errorf("got %d warnings, want 2", n)
+	}
+}
+
+func TestSyncFileEscapesReservedSequences(t *testing.T) {
+	const markdown = "# Title\n\n" +
+		"Colour \x1b[31mred\x1b[0m and a null \x00 byte.\n\n" +
+		"Broken \xff\xfe bytes and [a link](https://example.com/page).\n\n" +
+		"- item with [a long link](https://example.com/" + "LONG" + ")\n\n" +
+		"| a | b |\n| --- | --- |\n| \x01cell | `code` |\n\n" +
+		"```\ncode\twith\ttabs\n```\n"
+	longLink := strings.Repeat("a", maxLinkLength)
+
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.EscapeReserved = true
+	mdPath := writeMarkdown(t, strings.Replace(markdown, "LONG", longLink, 1))
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	var walk func([]notion.Block)
+	walk = func(blocks []notion.Block) {
+		for _, block := range blocks {
+			for _, rt := range blockRichText(block) {
+				texts = append(texts, rt.Text.Content)
+				if rt.Text.Link != nil && !isValidLink(rt.Text.Link.URL) {
+					t.Errorf("link %q was sent to Notion", truncateText(rt.Text.Link.URL, 40))
+				}
+			}
+			if table, ok := block.(*notion.TableBlock); ok {
+				for _, child := range table.Children {
+					for _, cell := range child.(*notion.TableRowBlock).Cells {
+						for _, rt := range cell {
+							texts = append(texts, rt.Text.Content)
+						}
+					}
+				}
+			}
+			walk(blockChildren(block))
+		}
+	}
+	walk(client.content["page"])
+
+	all := strings.Join(texts, "|")
+	if removeControlCharacters(all) != all {
+		t.Errorf("synced text still has control characters: %q", all)
+	}
+	for _, want := range []string{"red", "a link", "long link", "cell", "code\twith\ttabs"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("synced text %q is missing %q", all, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// GetDatabaseSchema returns the property definitions of a database keyed by property name.
+// Each database is fetched once, later calls are answered from the cache.
+func (c *NotionClient) GetDatabaseSchema(ctx context.Context, databaseID string) (notion.DatabaseProperties, error) {
+	if schema, ok := c.schemas[databaseID]; ok {
+		return schema, nil
+	}
+	db, err := c.NotionClient.FindDatabaseByID(ctx, databaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch database schema: %w", err)
+	}
+	if c.schemas == nil {
+		c.schemas = make(map[string]notion.DatabaseProperties)
+	}
+	c.schemas[databaseID] = db.Properties
+	return db.Properties, nil
+}
+
+// pageSchema returns the schema of the database holding the page, or errNotDatabasePage.
+// Which database a page is in is looked up once per page.
+func (c *NotionClient) pageSchema(ctx context.Context, pageID string) (notion.DatabaseProperties, error) {
+	databaseID, ok := c.pageDatabases[pageID]
+	if !ok {
+		page, err := c.getPageCached(ctx, pageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch page: %w", err)
+		}
+		if page.Parent.Type == notion.ParentTypeDatabase {
+			databaseID = page.Parent.DatabaseID
+		}
+		if c.pageDatabases == nil {
+			c.pageDatabases = make(map[string]string)
+		}
+		c.pageDatabases[pageID] = databaseID
+	}
+	if databaseID == "" {
+		return nil, errNotDatabasePage
+	}
+	return c.GetDatabaseSchema(ctx, databaseID)
+}
+
+// checkPropertyType fails unless the schema has a property called name of the given type,
+// naming the properties there are or the property's actual type
+func checkPropertyType(schema notion.DatabaseProperties, name string, want notion.DatabasePropertyType) error {
+	property, ok := schema[name]
+	if !ok {
+		names := make([]string, 0, len(schema))
+		for known := range schema {
+			names = append(names, "'"+known+"'")
+		}
+		sort.Strings(names)
+		return fmt.Errorf("the database has no property '%s', its properties are %s", name, strings.Join(names, ", "))
+	}
+	if property.Type != want {
+		return fmt.Errorf("property '%s' is of type %s, not %s", name, property.Type, want)
+	}
+	return nil
+}
+
+// titleProperty returns the name of the schema's title property
+func titleProperty(schema notion.DatabaseProperties) (string, error) {
+	for name, property := range schema {
+		if property.Type == notion.DBPropTypeTitle {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("the database has no title property")
+}
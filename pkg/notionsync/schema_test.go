@@ -0,0 +1,85 @@
+package notionsync
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// newSchemaClient returns a client for a page in a database with a title, a rich_text and a
+// number property, and the requests it sends as "METHOD path"
+func newSchemaClient() (*NotionClient, *[]string) {
+	var requests []string
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		body := `{"object":"page","id":"page","parent":{"type":"database_id","database_id":"db"},"properties":{}}`
+		if strings.HasPrefix(req.URL.Path, "/v1/databases/") {
+			body = `{"object":"database","id":"db","title":[],"parent":{"type":"workspace","workspace":true},"properties":{` +
+				`"Name":{"id":"title","type":"title","title":{}},` +
+				`"Hash":{"id":"h","type":"rich_text","rich_text":{}},` +
+				`"Count":{"id":"c","type":"number","number":{"format":"number"}}}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{"Content-Type": {"application/json"}}}
+	})}
+	return c, &requests
+}
+
+func TestSetPropertyChecksSchema(t *testing.T) {
+	tests := []struct {
+		property  string
+		wantErr   string
+		wantWrite bool
+	}{
+		{property: "Hash", wantWrite: true},
+		{property: "Count", wantErr: "property 'Count' is of type number, not rich_text"},
+		{property: "Missing", wantErr: "has no property 'Missing', its properties are 'Count', 'Hash', 'Name'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property, func(t *testing.T) {
+			c, requests := newSchemaClient()
+			err := c.SetProperty(context.Background(), "page", tt.property, "value")
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want it to say %q", err, tt.wantErr)
+			}
+			if wrote := slices.Contains(*requests, "PATCH /v1/pages/page"); wrote != tt.wantWrite {
+				t.Errorf("requests = %q, want a write %v", *requests, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestGetDatabaseSchemaCached(t *testing.T) {
+	c, requests := newSchemaClient()
+	ctx := context.Background()
+	if err := c.SetProperty(ctx, "page", "Hash", "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdatePageTitle(ctx, "page", notion.Heading1Block{RichText: plainRichText("Title")}); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := c.GetDatabaseSchema(ctx, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema["Count"].Type != notion.DBPropTypeNumber {
+		t.Errorf("schema = %+v, want the database's properties", schema)
+	}
+	var fetched int
+	for _, request := range *requests {
+		if request == "GET /v1/databases/db" {
+			fetched++
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("requests = %q, want the database fetched once", *requests)
+	}
+}
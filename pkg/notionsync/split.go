@@ -0,0 +1,177 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// pageSection is a part of the document split off into its own child page
+type pageSection struct {
+	Title  string
+	Icon   string
+	Blocks []notion.Block
+}
+
+// splitByHeading splits blocks at every heading of the given level or higher (h1 is the highest).
+// Blocks before the first such heading are returned as the parent's content, each heading starts
+// a section titled with its text and holding the blocks up to the next split heading.
+func splitByHeading(blocks []notion.Block, level int) ([]notion.Block, []pageSection) {
+	var (
+		parent   []notion.Block
+		sections []pageSection
+	)
+	for _, block := range blocks {
+		if l := headingLevel(block); l > 0 && l <= level {
+			sections = append(sections, pageSection{Title: richTextPlainText(blockRichText(block))})
+			continue
+		}
+		if len(sections) == 0 {
+			parent = append(parent, block)
+			continue
+		}
+		current := &sections[len(sections)-1]
+		current.Blocks = append(current.Blocks, block)
+	}
+	return parent, sections
+}
+
+// ConflictPolicies are the accepted values of --on-conflict
+var ConflictPolicies = []string{"skip", "overwrite", "rename"}
+
+// addSectionPages creates a child page under parentID for every section, then appends
+// a table of contents linking to them to the parent page. When a child page with the
+// same title already exists, onConflict decides what happens: "skip" reuses it as is,
+// "overwrite" replaces its content and "rename" creates a new page with a numbered title.
+// Unless the page was just cleared, the table of contents is left out when the page
+// already has one linking to every child page, so re-running an append doesn't repeat it.
+func addSectionPages(ctx context.Context, notionClient NotionClientInterface, parentID string, sections []pageSection, onConflict string, cleared bool) error {
+	existing, err := notionClient.GetChildPages(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to list child pages: %w", err)
+	}
+	if existing == nil {
+		existing = make(map[string]string)
+	}
+	pageIDs := make([]string, 0, len(sections))
+	for _, section := range sections {
+		childID, err := addSectionPage(ctx, notionClient, parentID, section, existing, onConflict)
+		if err != nil {
+			return fmt.Errorf("failed to add child page '%s': %w", section.Title, err)
+		}
+		pageIDs = append(pageIDs, childID)
+	}
+	if !cleared {
+		content, err := notionClient.GetPageContent(ctx, parentID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page content: %w", err)
+		}
+		if linksAllPages(content, pageIDs) {
+			debugf(ctx, "[DEBUG] Page already has a table of contents for its %d child pages\n", len(pageIDs))
+			return nil
+		}
+	}
+	if _, err := notionClient.AddPageContent(ctx, parentID, sectionContents(sections, pageIDs)); err != nil {
+		return fmt.Errorf("failed to add table of contents: %w", err)
+	}
+	return nil
+}
+
+// addSectionPage writes one section to its child page following the conflict policy and
+// returns the page's ID. Pages it creates are added to existing.
+func addSectionPage(ctx context.Context, notionClient NotionClientInterface, parentID string, section pageSection, existing map[string]string, onConflict string) (string, error) {
+	title := section.Title
+	if childID, ok := existing[title]; ok {
+		switch onConflict {
+		case "skip":
+			fmt.Fprintf(output(ctx), "Child page '%s' already exists, skipping\n", title)
+			return childID, nil
+		case "overwrite":
+			debugf(ctx, "[DEBUG] Overwriting child page '%s' with %d blocks\n", title, len(section.Blocks))
+			if err := notionClient.ClearPageContent(ctx, childID); err != nil {
+				return "", err
+			}
+			if len(section.Blocks) > 0 {
+				if _, err := notionClient.AddPageContent(ctx, childID, section.Blocks); err != nil {
+					return "", err
+				}
+			}
+			return childID, nil
+		default:
+			for n := 2; ; n++ {
+				title = fmt.Sprintf("%s (%d)", section.Title, n)
+				if _, taken := existing[title]; !taken {
+					break
+				}
+			}
+		}
+	}
+	debugf(ctx, "[DEBUG] Creating child page '%s' with %d blocks\n", title, len(section.Blocks))
+	childID, err := notionClient.CreateChildPage(ctx, parentID, title, section.Icon, section.Blocks)
+	if err != nil {
+		return "", err
+	}
+	existing[title] = childID
+	return childID, nil
+}
+
+// sectionContents builds the table of contents: a "Contents" heading and a bulleted link per child page
+func sectionContents(sections []pageSection, pageIDs []string) []notion.Block {
+	blocks := []notion.Block{notion.Heading2Block{RichText: plainRichText("Contents")}}
+	for i, section := range sections {
+		blocks = append(blocks, notion.BulletedListItemBlock{
+			RichText: []notion.RichText{{
+				Type:      notion.RichTextTypeText,
+				Text:      &notion.Text{Content: section.Title, Link: &notion.Link{URL: notionPageURL(pageIDs[i])}},
+				PlainText: section.Title,
+			}},
+		})
+	}
+	return blocks
+}
+
+// linksAllPages reports whether the bulleted list items in blocks link to every page in pageIDs
+func linksAllPages(blocks []notion.Block, pageIDs []string) bool {
+	linked := make(map[string]bool)
+	for _, block := range blocks {
+		if _, ok := block.(*notion.BulletedListItemBlock); !ok {
+			if _, ok := block.(notion.BulletedListItemBlock); !ok {
+				continue
+			}
+		}
+		for _, rt := range blockRichText(block) {
+			if rt.Text != nil && rt.Text.Link != nil {
+				linked[rt.Text.Link.URL] = true
+			}
+		}
+	}
+	for _, id := range pageIDs {
+		if !linked[notionPageURL(id)] {
+			return false
+		}
+	}
+	return true
+}
+
+// clearKeepingChildPages deletes the page's blocks after the first keep, except its child pages:
+// deleting a child_page block archives the page, which --on-conflict would then never see
+func clearKeepingChildPages(ctx context.Context, notionClient NotionClientInterface, pageID string, keep int) error {
+	blocks, err := notionClient.GetPageContent(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if keep > len(blocks) {
+		warnf(ctx, "Page has only %d blocks, preserving all of them instead of the first %d\n", len(blocks), keep)
+		return nil
+	}
+	var oldIDs []string
+	for _, block := range blocks[keep:] {
+		if _, ok := block.(*notion.ChildPageBlock); ok {
+			continue
+		}
+		oldIDs = append(oldIDs, block.ID())
+	}
+	_, err = notionClient.ReplaceSection(ctx, pageID, "", oldIDs, nil)
+	return err
+}
@@ -0,0 +1,137 @@
+package notionsync
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+const splitMarkdown = "# Doc\n\nIntro.\n\n## Alpha\n\nNew alpha.\n\n## Beta\n\nNew beta.\n"
+
+// pageTexts returns the plain text of each block on a page
+func pageTexts(blocks []notion.Block) []string {
+	texts := make([]string, len(blocks))
+	for i, block := range blocks {
+		if page, ok := block.(*notion.ChildPageBlock); ok {
+			texts[i] = "page:" + page.Title
+			continue
+		}
+		texts[i] = richTextPlainText(blockRichText(block))
+	}
+	return texts
+}
+
+func TestSplitOnConflict(t *testing.T) {
+	tests := []struct {
+		onConflict string
+		wantPages  []string
+		wantAlpha  []string
+	}{
+		{"skip", []string{"Alpha", "Beta"}, []string{"Old alpha."}},
+		{"overwrite", []string{"Alpha", "Beta"}, []string{"New alpha."}},
+		{"rename", []string{"Alpha", "Alpha (2)", "Beta"}, []string{"Old alpha."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.onConflict, func(t *testing.T) {
+			client := newFakeNotionClient()
+			client.content["page"] = []notion.Block{withID(notion.ParagraphBlock{RichText: plainRichText("Old intro.")}, "old-intro")}
+			alphaID := client.addChildPage("page", "Alpha", notion.ParagraphBlock{RichText: plainRichText("Old alpha.")})
+
+			opts := testOptions()
+			opts.Replace, opts.SplitLevel, opts.OnConflict = true, 2, tt.onConflict
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, splitMarkdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := slices.Sorted(maps.Keys(client.childPages["page"])); !slices.Equal(got, tt.wantPages) {
+				t.Errorf("child pages = %v, want %v", got, tt.wantPages)
+			}
+			if got := client.childPages["page"]["Alpha"]; got != alphaID {
+				t.Errorf("Alpha is page %q, want the existing %q", got, alphaID)
+			}
+			if got := pageTexts(client.content[alphaID]); !slices.Equal(got, tt.wantAlpha) {
+				t.Errorf("Alpha holds %v, want %v", got, tt.wantAlpha)
+			}
+			parent := pageTexts(client.content["page"])
+			if slices.Contains(parent, "Old intro.") || !slices.Contains(parent, "Intro.") {
+				t.Errorf("parent holds %v, want the old intro replaced", parent)
+			}
+			if n := count(parent, "Contents"); n != 1 {
+				t.Errorf("parent has %d tables of contents, want 1: %v", n, parent)
+			}
+		})
+	}
+}
+
+func TestSplitAppendAddsContentsOnce(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.SplitLevel, opts.OnConflict = 2, "skip"
+	for range 2 {
+		if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, splitMarkdown), "page"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parent := pageTexts(client.content["page"])
+	if n := count(parent, "Contents"); n != 1 {
+		t.Errorf("parent has %d tables of contents, want 1: %v", n, parent)
+	}
+	if n := count(parent, "Intro."); n != 2 {
+		t.Errorf("parent has the intro %d times, want it appended on each run: %v", n, parent)
+	}
+}
+
+// count returns how often s occurs in texts
+func count(texts []string, s string) int {
+	n := 0
+	for _, text := range texts {
+		if text == s {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSplitCreatesLinkedChildPages(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.SplitLevel = 1
+	opts.TitleLevel = 0
+	markdown := "Preamble.\n\n# One\n\nFirst.\n\n# Two\n\nSecond.\n\n## Two point one\n\nNested.\n\n# Three\n\nThird.\n"
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callNames(); count(got, "CreateChildPage") != 3 {
+		t.Errorf("calls = %v, want 3 child pages created", got)
+	}
+	children := client.childPages["page"]
+	if got := slices.Sorted(maps.Keys(children)); !slices.Equal(got, []string{"One", "Three", "Two"}) {
+		t.Fatalf("child pages = %v, want One, Two and Three", got)
+	}
+	if got := pageTexts(client.content[children["Two"]]); !slices.Equal(got, []string{"Second.", "Two point one", "Nested."}) {
+		t.Errorf("Two holds %v, want its section including the subheading", got)
+	}
+
+	var links []string
+	for _, block := range client.content["page"] {
+		if _, ok := block.(*notion.BulletedListItemBlock); !ok {
+			continue
+		}
+		rt := blockRichText(block)[0]
+		links = append(links, rt.PlainText+" "+rt.Text.Link.URL)
+	}
+	want := []string{
+		"One " + notionPageURL(children["One"]),
+		"Two " + notionPageURL(children["Two"]),
+		"Three " + notionPageURL(children["Three"]),
+	}
+	if !slices.Equal(links, want) {
+		t.Errorf("contents links = %q, want %q", links, want)
+	}
+	if parent := pageTexts(client.content["page"]); !slices.Contains(parent, "Preamble.") || !slices.Contains(parent, "Contents") {
+		t.Errorf("parent holds %v, want the preamble and the contents", parent)
+	}
+}
@@ -0,0 +1,95 @@
+package notionsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pageState is what the state file records about a page after syncing it: its last edit,
+// which was made by the integration itself
+type pageState struct {
+	LastEditedTime time.Time `json:"last_edited_time"`
+	LastEditedBy   string    `json:"last_edited_by"`
+}
+
+// PageEdit is the last edit of a Notion page and whether the integration made it
+type PageEdit struct {
+	Time          time.Time
+	By            string
+	ByIntegration bool
+}
+
+// errEditedByHuman is returned by SyncFile when --replace would overwrite someone else's edits
+var errEditedByHuman = errors.New("page edited since the last sync")
+
+// loadSyncState reads the state file, a JSON object mapping page IDs to their state. A missing
+// file is an empty state, as before the first sync.
+func loadSyncState(statePath string) (map[string]pageState, error) {
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return map[string]pageState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading state file: %w", err)
+	}
+	state := map[string]pageState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("Error decoding state file: %w", err)
+	}
+	return state, nil
+}
+
+// recordPageState stores the page's state in the state file, keeping the other pages' entries
+func recordPageState(statePath, pageID string, edit PageEdit) error {
+	state, err := loadSyncState(statePath)
+	if err != nil {
+		return err
+	}
+	state[pageID] = pageState{LastEditedTime: edit.Time, LastEditedBy: edit.By}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath, append(data, '\n'), 0o644)
+}
+
+// checkPageEdit fails if the page was edited by someone other than the integration since the
+// recorded sync. A page without a record has nothing to compare against and passes.
+func checkPageEdit(pageID string, recorded pageState, ok bool, edit PageEdit) error {
+	if !ok || edit.ByIntegration {
+		return nil
+	}
+	if edit.Time.Equal(recorded.LastEditedTime) && edit.By == recorded.LastEditedBy {
+		return nil
+	}
+	return fmt.Errorf("%w: page %s was last edited at %s by user %s, pass --force to replace it anyway",
+		errEditedByHuman, pageID, edit.Time.Format(time.RFC3339), edit.By)
+}
+
+// GetLastEdit returns when and by whom the page was last edited. The integration's own user
+// is looked up once and remembered. Content changes don't go through the page cache, so the
+// page is always fetched afresh.
+func (c *NotionClient) GetLastEdit(ctx context.Context, pageID string) (PageEdit, error) {
+	c.invalidatePage(pageID)
+	page, err := c.getPageCached(ctx, pageID)
+	if err != nil {
+		return PageEdit{}, err
+	}
+	if c.botUserID == "" {
+		me, err := c.NotionClient.FindCurrentUser(ctx)
+		if err != nil {
+			return PageEdit{}, fmt.Errorf("failed to look up the integration's user: %w", err)
+		}
+		c.botUserID = me.ID
+	}
+	edit := PageEdit{Time: page.LastEditedTime}
+	if page.LastEditedBy != nil {
+		edit.By = page.LastEditedBy.ID
+	}
+	edit.ByIntegration = edit.By == c.botUserID
+	return edit, nil
+}
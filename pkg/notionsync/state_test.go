@@ -0,0 +1,128 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// editedClient is a fake client whose pages were last edited by edit
+type editedClient struct {
+	*fakeNotionClient
+	edit PageEdit
+}
+
+func (c *editedClient) GetLastEdit(ctx context.Context, pageID string) (PageEdit, error) {
+	c.record("GetLastEdit %s", pageID)
+	return c.edit, nil
+}
+
+func TestCheckPageEdit(t *testing.T) {
+	synced := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	recorded := pageState{LastEditedTime: synced, LastEditedBy: "bot"}
+	tests := []struct {
+		name     string
+		recorded bool
+		edit     PageEdit
+		wantErr  bool
+	}{
+		{name: "never synced", edit: PageEdit{Time: synced.Add(time.Hour), By: "human"}},
+		{name: "unchanged since the sync", recorded: true, edit: PageEdit{Time: synced, By: "bot"}},
+		{name: "edited by the integration", recorded: true, edit: PageEdit{Time: synced.Add(time.Hour), By: "bot", ByIntegration: true}},
+		{name: "edited by a human", recorded: true, edit: PageEdit{Time: synced.Add(time.Hour), By: "human"}, wantErr: true},
+		{name: "same time, different user", recorded: true, edit: PageEdit{Time: synced, By: "human"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPageEdit("page", recorded, tt.recorded, tt.edit)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, errEditedByHuman)) {
+				t.Errorf("checkPageEdit = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyncFileReplaceEditGuard(t *testing.T) {
+	synced := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	later := synced.Add(time.Hour)
+	tests := []struct {
+		name      string
+		edit      PageEdit
+		force     bool
+		wantErr   bool
+		wantState PageEdit
+	}{
+		{
+			name:      "edited by the integration",
+			edit:      PageEdit{Time: later, By: "bot", ByIntegration: true},
+			wantState: PageEdit{Time: later, By: "bot"},
+		},
+		{
+			name:      "edited by a human",
+			edit:      PageEdit{Time: later, By: "human"},
+			wantErr:   true,
+			wantState: PageEdit{Time: synced, By: "bot"},
+		},
+		{
+			name:      "edited by a human with --force",
+			edit:      PageEdit{Time: later, By: "human"},
+			force:     true,
+			wantState: PageEdit{Time: later, By: "human"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statePath := filepath.Join(t.TempDir(), "state.json")
+			if err := recordPageState(statePath, "page", PageEdit{Time: synced, By: "bot"}); err != nil {
+				t.Fatal(err)
+			}
+			client := &editedClient{newFakeNotionClient(), tt.edit}
+			opts := testOptions()
+			opts.Replace, opts.StateFile, opts.Force = true, statePath, tt.force
+
+			err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\nNew text.\n"), "page")
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, errEditedByHuman)) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cleared := slices.Contains(client.callNames(), "ClearPageContent"); cleared == tt.wantErr {
+				t.Errorf("calls = %v, want the page replaced only when the guard passes", client.callNames())
+			}
+			state, err := loadSyncState(statePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := state["page"]; !got.LastEditedTime.Equal(tt.wantState.Time) || got.LastEditedBy != tt.wantState.By {
+				t.Errorf("state = %+v, want %+v", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestGetLastEditByIntegration(t *testing.T) {
+	for _, editor := range []string{"bot-1", "human-1"} {
+		t.Run(editor, func(t *testing.T) {
+			client := NewNotionClient("token", DefaultNotionVersion)
+			client.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+				body := `{"object":"user","id":"bot-1","type":"bot","bot":{}}`
+				if strings.HasPrefix(req.URL.Path, "/v1/pages/") {
+					body = `{"object":"page","id":"page","last_edited_time":"2024-05-01T11:00:00Z","last_edited_by":{"object":"user","id":"` + editor + `"},"parent":{"type":"workspace","workspace":true},"properties":{}}`
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{"Content-Type": {"application/json"}}}
+			})}
+			edit, err := client.GetLastEdit(context.Background(), "page")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := PageEdit{Time: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC), By: editor, ByIntegration: editor == "bot-1"}
+			if !edit.Time.Equal(want.Time) || edit.By != want.By || edit.ByIntegration != want.ByIntegration {
+				t.Errorf("edit = %+v, want %+v", edit, want)
+			}
+		})
+	}
+}
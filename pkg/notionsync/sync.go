@@ -0,0 +1,665 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// SyncOptions holds the settings applied to every markdown file synced
+type SyncOptions struct {
+	Replace        bool
+	PreserveFirstN int
+	UseHash        bool
+	HashProperty   string
+	HashStorage    string
+	PropertyPrefix string
+	// TimestampProperty names the rich_text property receiving the time of each sync that
+	// changed the page, empty writes none. Like the hash property it gets PropertyPrefix.
+	TimestampProperty string
+	RewriteText       string
+	RewriteMode       string
+	DryRun            bool
+	DryRunDiff        bool
+	DiffOutput        string
+	ValidateOnly      bool
+	Roundtrip         bool
+	PreviewImages     bool
+	RowHeader         bool
+	EscapeReserved    bool
+	Output            string
+	TitleLevel        int
+	TitleOverflow     string
+	LinkIndex         bool
+	BookmarkURLs      bool
+	DateMentions      bool
+	DatePrefix        string
+	Users             map[string]string
+	TaskMetadataMode  string
+	// TasksDatabase adds the task items to this database as pages instead of to the page as
+	// to-dos, their due date and assignees in the TaskDueProperty and TaskAssigneeProperty
+	// properties ("Due" and "Assignee" when empty)
+	TasksDatabase        string
+	TaskDueProperty      string
+	TaskAssigneeProperty string
+	SplitLevel           int
+	OnConflict           string
+	WrapIn               string
+	WrapLabel            string
+	SkipImages           bool
+	Images               ImageOptions
+	VerifyPage           bool
+	Icon                 string
+	Cover                string
+	PageIDFile           string
+	BlockMapOut          string
+	CommentSummary       bool
+	DiffAgainstFile      string
+	FrontmatterProps     bool
+	Footnotes            string
+	StateFile            string
+	HeadingEmoji         string
+	GitDiff              bool
+	// EntryHeadingDate starts appended content with a heading holding the date in EntryDateFormat
+	EntryHeadingDate  bool
+	EntryDateFormat   string
+	SkipExistingEntry bool
+	// UnderHeading appends at the end of the section under this heading instead of the page's bottom
+	UnderHeading string
+	Force        bool
+
+	// StatusOutput receives the status messages printed while syncing, nil prints them to
+	// os.Stdout. Programs that report the result themselves, like the CLI's --output json,
+	// set it to io.Discard.
+	StatusOutput io.Writer
+	// Debug prints debug messages to StatusOutput as well
+	Debug bool
+	// InputWait is how long the markdown file and the mapping files are waited for to appear
+	// and stop changing before they are read
+	InputWait time.Duration
+	// Report records the sync in a run report, nil records nothing
+	Report *Report
+}
+
+// Offline reports whether the options only check the markdown locally, never contacting Notion
+func (opts SyncOptions) Offline() bool {
+	return opts.ValidateOnly || opts.Roundtrip || opts.PreviewImages || (opts.DryRun && !opts.DryRunDiff)
+}
+
+// metadataProperty returns the name of a metadata property this tool reads and writes, such as
+// the content hash, with the PropertyPrefix that keeps it apart from other tools' properties
+func (opts SyncOptions) metadataProperty(name string) string {
+	return opts.PropertyPrefix + name
+}
+
+// operation names how the sync changes the page: "replace" or "append"
+func (opts SyncOptions) operation() string {
+	if opts.Replace {
+		return "replace"
+	}
+	return "append"
+}
+
+// ErrContentUnchanged is returned by SyncFile when the content hash shows nothing changed
+var ErrContentUnchanged = errors.New("no content change detected")
+
+// ErrValidationFailed is returned by SyncFile when --validate-only found warnings or problems
+var ErrValidationFailed = errors.New("validation failed")
+
+// SyncFile converts the markdown file at mdPath and syncs it to the page pageID
+func SyncFile(ctx context.Context, opts SyncOptions, notionClient NotionClientInterface, mdPath, pageID string) error {
+	ctx = NewContext(ctx, opts)
+	mdContent, err := readInputFile(ctx, mdPath)
+	if err != nil {
+		return fmt.Errorf("Error reading markdown file: %w", err)
+	}
+	return syncContent(ctx, opts, notionClient, mdPath, pageID, normalizeLineEndings(mdContent))
+}
+
+// syncContent converts the markdown mdContent read from mdPath and syncs it to the page pageID
+func syncContent(ctx context.Context, opts SyncOptions, notionClient NotionClientInterface, mdPath, pageID string, mdContent []byte) error {
+	// Every document gets its own session, so validation only fails on its own warnings
+	ctx = NewContext(ctx, opts)
+	reportFrom(ctx).beginFile(mdPath, pageID, opts.operation())
+	err := syncDocument(ctx, opts, notionClient, mdPath, pageID, mdContent)
+	reportFrom(ctx).endFile(err)
+	return err
+}
+
+// syncDocument does the work of syncContent
+func syncDocument(ctx context.Context, opts SyncOptions, notionClient NotionClientInterface, mdPath, pageID string, mdContent []byte) error {
+	printAppTitle(ctx, mdPath, opts.operation(), opts.UseHash, opts.Force, opts.RewriteText)
+
+	// The frontmatter can name the target page, --page takes precedence
+	frontmatter, mdContent := parseFrontmatter(mdContent)
+	if pageID == "" {
+		pageID = frontmatter[frontmatterPageKey]
+	}
+	reportFrom(ctx).setPageID(pageID)
+	if pageID == "" && !opts.Offline() {
+		return fmt.Errorf("No target page for %s: pass --page or set %s in the frontmatter", mdPath, frontmatterPageKey)
+	}
+	if opts.Icon == "" {
+		opts.Icon = frontmatterImage(frontmatter[frontmatterIconKey], mdPath)
+	}
+	if opts.Cover == "" {
+		opts.Cover = frontmatterImage(frontmatter[frontmatterCoverKey], mdPath)
+	}
+
+	// Rewrite text if mapping is provided before conversion to notion blocks
+	var err error
+	if opts.RewriteText != "" {
+		if mdContent, err = rewriteContent(ctx, mdContent, mdPath, opts.RewriteText, opts.RewriteMode); err != nil {
+			return err
+		}
+	}
+
+	// A locally stored copy of the last synced markdown detects changes without reading Notion
+	if opts.DiffAgainstFile != "" && !opts.Offline() && !opts.DryRunDiff {
+		previous, err := os.ReadFile(opts.DiffAgainstFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error reading last synced copy '%s': %w", opts.DiffAgainstFile, err)
+		}
+		if err == nil {
+			reportFrom(ctx).hashCheck("file", fmt.Sprintf("%x", sha256.Sum256(previous)), fmt.Sprintf("%x", sha256.Sum256(mdContent)))
+		}
+		if err == nil && bytes.Equal(previous, mdContent) && opts.Force {
+			fmt.Fprintf(output(ctx), "No content change detected since the copy in '%s', syncing anyway (--force).\n", opts.DiffAgainstFile)
+		} else if err == nil && bytes.Equal(previous, mdContent) {
+			fmt.Fprintf(output(ctx), "⚠️ No content change detected since the copy in '%s'. Skipping update.\n", opts.DiffAgainstFile)
+			emitPageID(ctx, opts.PageIDFile, pageID)
+			return ErrContentUnchanged
+		}
+	}
+
+	// Footnotes are resolved in the markdown, the hash still covers the content as written
+	markdown, footnotes := extractFootnotes(string(mdContent), opts.Footnotes)
+
+	// First convert markdown to Notion blocks
+	blocks, err := convertMarkdown(ctx, markdown)
+	if err != nil {
+		return fmt.Errorf("Error converting markdown to Notion blocks: %w", err)
+	}
+
+	if opts.RowHeader {
+		applyRowHeader(blocks)
+	}
+
+	if opts.PreviewImages {
+		missing, err := printImagePreview(ctx, previewImages(ctx, blocks, mdPath, opts.Images), opts.Output)
+		if err != nil {
+			return fmt.Errorf("Error printing image preview: %w", err)
+		}
+		if missing > 0 {
+			return ErrValidationFailed
+		}
+		return nil
+	}
+
+	// Then process the blocks to handle images correctly
+	var imageFailures []failedImageBlock
+	if !opts.SkipImages {
+		blocks, err = ProcessImageBlocks(ctx, blocks, mdPath, notionClient, opts.Images)
+		if err != nil {
+			return fmt.Errorf("failed to process images: %w", err)
+		}
+		imageFailures = failedImages(blocks)
+		defer reportImageFailures(ctx, imageFailures)
+	}
+
+	if opts.BookmarkURLs {
+		blocks = ProcessBookmarkBlocks(blocks)
+	}
+
+	// Debug all block types
+	debugBlocks(ctx, blocks)
+
+	// --- content_hash optimization ---
+	// Compute hash of the input markdown file
+	hashBytes := sha256.Sum256(mdContent)
+	contentHash := fmt.Sprintf("%x", hashBytes[:])
+
+	// Validate blocks before sending to Notion
+	blocks = ValidateContentBlocks(ctx, blocks)
+	blocks = transformRichText(blocks, convertHTMLAnchors)
+	blocks = applyPageMentions(ctx, blocks)
+	if opts.EscapeReserved {
+		blocks = escapeReservedText(ctx, blocks)
+	}
+	blocks = transformRichText(blocks, splitRichText)
+	// Task metadata goes first so its @tokens aren't taken for date or user mentions
+	var tasks []databaseTask
+	if opts.TasksDatabase != "" {
+		blocks, tasks = extractTasks(blocks)
+	}
+	blocks = applyTaskMetadata(blocks, opts.TaskMetadataMode)
+	if opts.DateMentions {
+		blocks = applyDateMentions(ctx, blocks, opts.DatePrefix, time.Now())
+	}
+	if opts.Users != nil {
+		blocks = applyUserMentions(ctx, blocks, opts.Users)
+	}
+	if opts.Roundtrip {
+		printRoundtrip(ctx, string(mdContent), renderMarkdown(blocks))
+		return nil
+	}
+	titleBlock, blocks := FilterTitleBlock(blocks, opts.TitleLevel)
+	if opts.LinkIndex {
+		blocks = appendLinkIndex(ctx, blocks)
+	}
+	if opts.WrapIn != "" {
+		label := opts.WrapLabel
+		if label == "" {
+			label = strings.TrimSuffix(filepath.Base(mdPath), filepath.Ext(mdPath))
+		}
+		blocks = wrapBlocks(ctx, blocks, opts.WrapIn, label)
+	}
+
+	// Dated entries start with a heading holding the day, the same day's entry can be skipped
+	var entry notion.Block
+	if opts.EntryHeadingDate {
+		entry = entryHeading(time.Now(), opts.EntryDateFormat)
+		blocks = append([]notion.Block{entry}, blocks...)
+	}
+
+	if opts.ValidateOnly {
+		if reportValidation(ctx, titleBlock, blocks, opts.TitleOverflow) != 0 {
+			return ErrValidationFailed
+		}
+		return nil
+	}
+
+	if opts.DryRunDiff {
+		live, err := notionClient.GetPageContent(ctx, pageID)
+		if err != nil {
+			return fmt.Errorf("Error fetching Notion page content: %w", err)
+		}
+		if opts.DiffOutput == "unified" {
+			if diff := unifiedSyncDiff(pageID, mdPath, opts.Replace, live, blocks); diff != "" {
+				fmt.Fprint(output(ctx), diff)
+			} else {
+				fmt.Fprintln(output(ctx), "No changes planned.")
+			}
+			return nil
+		}
+		if err := printSyncPlan(ctx, buildSyncPlan(pageID, opts.Replace, live, blocks), opts.Output); err != nil {
+			return fmt.Errorf("Error printing sync plan: %w", err)
+		}
+		return nil
+	}
+
+	if opts.DryRun {
+		return printDryRun(ctx, opts, pageID, titleBlock, blocks)
+	}
+
+	// Checked before anything is written, the integration's own edits would hide a human's
+	if opts.Replace && opts.StateFile != "" && !opts.Force {
+		state, err := loadSyncState(opts.StateFile)
+		if err != nil {
+			return err
+		}
+		edit, err := notionClient.GetLastEdit(ctx, pageID)
+		if err != nil {
+			return fmt.Errorf("Error reading the page's last edit: %w", err)
+		}
+		recorded, ok := state[pageID]
+		if err := checkPageEdit(pageID, recorded, ok, edit); err != nil {
+			return err
+		}
+	}
+
+	if entry != nil && opts.SkipExistingEntry {
+		live, err := notionClient.GetPageContent(ctx, pageID)
+		if err != nil {
+			return fmt.Errorf("Error fetching Notion page content: %w", err)
+		}
+		if hasEntry(live, entry) {
+			fmt.Fprintf(output(ctx), "⚠️ The page already has an entry '%s'. Skipping update.\n", richTextPlainText(blockRichText(entry)))
+			emitPageID(ctx, opts.PageIDFile, pageID)
+			return ErrContentUnchanged
+		}
+	}
+
+	if titleBlock != nil {
+		err := notionClient.UpdatePageTitle(ctx, pageID, titleBlock)
+		if err != nil {
+			fmt.Fprintf(output(ctx), "Error updating page title: %s\n", err)
+		}
+	}
+
+	// Like the properties the icon and cover are set on every sync, also of unchanged content
+	if opts.Icon != "" || opts.Cover != "" {
+		if err := notionClient.SetPageIconAndCover(ctx, pageID, opts.Icon, opts.Cover); err != nil {
+			return fmt.Errorf("Error setting page icon and cover: %w", err)
+		}
+	}
+
+	// Properties are set before the hash check, the hash only covers the content after the frontmatter
+	if opts.FrontmatterProps {
+		properties := maps.Clone(frontmatter)
+		delete(properties, frontmatterPageKey)
+		delete(properties, frontmatterIconKey)
+		delete(properties, frontmatterCoverKey)
+		if len(properties) > 0 {
+			if err := notionClient.SetProperties(ctx, pageID, properties); err != nil {
+				return fmt.Errorf("Error setting page properties from the frontmatter: %w", err)
+			}
+		}
+	}
+
+	// Checks the stored content hash to see whether the content is different than that already published in notion
+	hashStorage := opts.HashStorage
+	if opts.UseHash {
+		var propertyHash string
+		contentHashPropertyName := "Content Hash"
+		if hashStorage == "property" {
+			if opts.HashProperty != "" {
+				contentHashPropertyName = opts.HashProperty
+			}
+			contentHashPropertyName = opts.metadataProperty(contentHashPropertyName)
+			propertyHash, err = notionClient.GetProperty(ctx, pageID, contentHashPropertyName)
+			// Pages outside a database have no properties to keep the hash in
+			if errors.Is(err, errNotDatabasePage) && opts.HashProperty == "" {
+				fmt.Fprintln(output(ctx), "Page is not in a database, keeping the content hash in a code block instead")
+				hashStorage, err = "code", nil
+			}
+			if err != nil {
+				return fmt.Errorf("Error getting '%s' property: %w", contentHashPropertyName, err)
+			}
+		}
+		if hashStorage == "property" {
+			reportFrom(ctx).hashCheck("property", propertyHash, contentHash)
+			fmt.Fprintf(output(ctx), "Page hash (Property Name: '%s'): %s\n", contentHashPropertyName, propertyHash)
+			fmt.Fprintf(output(ctx), "Content hash: %s\n", contentHash)
+			if propertyHash == contentHash && opts.Force {
+				fmt.Fprintln(output(ctx), "No content change detected, syncing anyway (--force).")
+			} else if propertyHash == contentHash {
+				fmt.Fprintln(output(ctx), "⚠️ No content change detected. Skipping update.")
+				emitPageID(ctx, opts.PageIDFile, pageID)
+				return ErrContentUnchanged
+			}
+			if err := notionClient.SetProperty(ctx, pageID, contentHashPropertyName, contentHash); err != nil {
+				fmt.Fprintf(output(ctx), "Warning: failed to set '%s' property: %s\n", contentHashPropertyName, err)
+			}
+		} else {
+			storedHash, err := notionClient.GetStoredHash(ctx, pageID, hashStorage)
+			if err != nil {
+				return fmt.Errorf("Error reading %s hash block: %w", hashStorage, err)
+			}
+			reportFrom(ctx).hashCheck(hashStorage, storedHash, contentHash)
+			fmt.Fprintf(output(ctx), "Page hash (%s block): %s\n", hashStorage, storedHash)
+			fmt.Fprintf(output(ctx), "Content hash: %s\n", contentHash)
+			if storedHash == contentHash && opts.Force {
+				fmt.Fprintln(output(ctx), "No content change detected, syncing anyway (--force).")
+			} else if storedHash == contentHash {
+				fmt.Fprintln(output(ctx), "⚠️ No content change detected. Skipping update.")
+				emitPageID(ctx, opts.PageIDFile, pageID)
+				return ErrContentUnchanged
+			}
+		}
+	}
+
+	blocks, sections := splitSections(blocks, opts)
+
+	// --git-diff only replaces the sections the latest commit changed, anything it can't match
+	// up with the page falls back to replacing the whole page
+	syncedSections := false
+	if opts.GitDiff {
+		updates, err := gitDiffSections(ctx, notionClient, mdPath, pageID, titleBlock, blocks)
+		if err != nil {
+			fmt.Fprintf(output(ctx), "⚠️ Replacing the whole page: %s\n", err)
+		}
+		for _, update := range updates {
+			fmt.Fprintf(output(ctx), "Replacing section '%s' (%d blocks with %d)\n", update.Heading, len(update.OldIDs), len(update.Blocks))
+			if _, err := notionClient.ReplaceSection(ctx, pageID, update.HeadingID, update.OldIDs, update.Blocks); err != nil {
+				return fmt.Errorf("Error replacing section '%s': %w", update.Heading, err)
+			}
+			syncedSections = true
+		}
+	}
+
+	// If we are replacing all the content with new content, we need to clear all the existing content first
+	replace := opts.Replace && !syncedSections
+	if replace && len(sections) > 0 {
+		if err := clearKeepingChildPages(ctx, notionClient, pageID, opts.PreserveFirstN); err != nil {
+			return fmt.Errorf("Error clearing Notion page: %w", err)
+		}
+	} else if replace && opts.PreserveFirstN > 0 {
+		if err := notionClient.ClearPageContentAfter(ctx, pageID, opts.PreserveFirstN); err != nil {
+			return fmt.Errorf("Error clearing Notion page: %w", err)
+		}
+	} else if replace {
+		if err := notionClient.ClearPageContent(ctx, pageID); err != nil {
+			return fmt.Errorf("Error clearing Notion page: %w", err)
+		}
+	}
+
+	if !syncedSections && (len(blocks) > 0 || len(sections) == 0) {
+		var blockIDs []string
+		if opts.UnderHeading != "" {
+			blockIDs, err = appendUnderHeading(ctx, notionClient, pageID, opts.UnderHeading, blocks)
+		} else {
+			blockIDs, err = notionClient.AddPageContent(ctx, pageID, blocks)
+		}
+		if err != nil {
+			return fmt.Errorf("Error updating Notion page: %w", err)
+		}
+		if opts.BlockMapOut != "" {
+			if err := writeBlockMap(opts.BlockMapOut, buildBlockMap(string(mdContent), blocks, blockIDs)); err != nil {
+				fmt.Fprintf(output(ctx), "Warning: failed to write block map '%s': %s\n", opts.BlockMapOut, err)
+			}
+		}
+		if opts.Footnotes == "comments" {
+			for _, comment := range footnoteComments(ctx, blocks, blockIDs, footnotes) {
+				if err := notionClient.AddBlockComment(ctx, comment.BlockID, comment.RichText); err != nil {
+					fmt.Fprintf(output(ctx), "Warning: failed to post footnote comment on block %s: %s\n", comment.BlockID, err)
+				}
+			}
+		}
+	}
+
+	if len(sections) > 0 {
+		if err := addSectionPages(ctx, notionClient, pageID, sections, opts.OnConflict, replace); err != nil {
+			return fmt.Errorf("Error creating section pages: %w", err)
+		}
+	}
+
+	if len(tasks) > 0 {
+		if err := createTasks(ctx, notionClient, opts, tasks); err != nil {
+			return fmt.Errorf("Error adding tasks: %w", err)
+		}
+	}
+
+	// Block stored hashes are written last so the metadata block trails the content
+	if opts.UseHash && hashStorage != "property" {
+		if err := notionClient.SetStoredHash(ctx, pageID, hashStorage, contentHash); err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to store %s hash block: %s\n", hashStorage, err)
+		}
+	}
+
+	// Only reached when the sync succeeded, every failure above returns
+	if opts.TimestampProperty != "" {
+		name := opts.metadataProperty(opts.TimestampProperty)
+		if err := notionClient.SetProperty(ctx, pageID, name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to set '%s' property: %s\n", name, err)
+		}
+	}
+
+	if opts.VerifyPage {
+		if err := notionClient.VerifyPage(ctx, pageID); err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to verify page: %s\n", err)
+		} else {
+			fmt.Fprintln(output(ctx), "Page marked as verified.")
+		}
+	}
+
+	if opts.CommentSummary {
+		summary := syncSummary(blocks, sections, opts.Replace, time.Now())
+		if err := notionClient.AddComment(ctx, pageID, summary); err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to post summary comment: %s\n", err)
+		} else {
+			debugf(ctx, "[DEBUG] Posted summary comment: %s\n", summary)
+		}
+	}
+
+	if opts.StateFile != "" {
+		edit, err := notionClient.GetLastEdit(ctx, pageID)
+		if err == nil {
+			err = recordPageState(opts.StateFile, pageID, edit)
+		}
+		if err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to record the page state in '%s': %s\n", opts.StateFile, err)
+		}
+	}
+
+	if opts.DiffAgainstFile != "" {
+		if err := os.WriteFile(opts.DiffAgainstFile, mdContent, 0o644); err != nil {
+			fmt.Fprintf(output(ctx), "Warning: failed to store last synced copy '%s': %s\n", opts.DiffAgainstFile, err)
+		}
+	}
+
+	emitPageID(ctx, opts.PageIDFile, pageID)
+	fmt.Fprintln(output(ctx), "✅ Page updated successfully.")
+	return nil
+}
+
+// printDryRun prints what a sync would send to Notion: the operation, the title and the exact
+// body of every request appending the blocks, indented for reading
+func printDryRun(ctx context.Context, opts SyncOptions, pageID string, titleBlock notion.Block, blocks []notion.Block) error {
+	if pageID == "" {
+		pageID = "<page-id>"
+	}
+	fmt.Fprintln(output(ctx), "[DRY RUN] No changes made to Notion.")
+	switch {
+	case opts.Replace && opts.PreserveFirstN > 0:
+		fmt.Fprintf(output(ctx), "Operation: replace the content of page %s after its first %d blocks\n", pageID, opts.PreserveFirstN)
+	case opts.Replace:
+		fmt.Fprintf(output(ctx), "Operation: replace the content of page %s\n", pageID)
+	case opts.UnderHeading != "":
+		fmt.Fprintf(output(ctx), "Operation: append to page %s under heading '%s' (the bottom of the page if it has none)\n", pageID, opts.UnderHeading)
+	default:
+		fmt.Fprintf(output(ctx), "Operation: append to page %s\n", pageID)
+	}
+	if titleBlock != nil {
+		fmt.Fprintf(output(ctx), "Title: %s\n", richTextPlainText(blockRichText(titleBlock)))
+	}
+	if opts.Icon != "" {
+		fmt.Fprintf(output(ctx), "Icon: %s\n", opts.Icon)
+	}
+	if opts.Cover != "" {
+		fmt.Fprintf(output(ctx), "Cover: %s\n", opts.Cover)
+	}
+
+	blocks, sections := splitSections(blocks, opts)
+	if len(blocks) > 0 || len(sections) == 0 {
+		chunks := chunkBlocks(blocks)
+		for i, chunk := range chunks {
+			deep := detachDeepChildren(chunk, nil)
+			body, err := appendChildrenBody(chunk, "")
+			reattachDeepChildren(chunk, deep)
+			if err != nil {
+				return fmt.Errorf("Error building request body: %w", err)
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, body, "", "  "); err != nil {
+				return fmt.Errorf("Error building request body: %w", err)
+			}
+			fmt.Fprintf(output(ctx), "\nRequest %d/%d: PATCH %s\n%s\n", i+1, len(chunks), appendChildrenURL(pageID), indented.String())
+			if len(deep) > 0 {
+				fmt.Fprintf(output(ctx), "Followed by requests appending the children of %d blocks nested deeper than %d levels to those blocks\n", len(deep), maxNestingDepth)
+			}
+		}
+	}
+	for _, section := range sections {
+		icon := ""
+		if section.Icon != "" {
+			icon = " (icon " + section.Icon + ")"
+		}
+		fmt.Fprintf(output(ctx), "\nChild page '%s'%s with %d blocks\n", section.Title, icon, len(section.Blocks))
+	}
+	return nil
+}
+
+// syncSummary describes a finished sync for the summary comment, e.g.
+// "Synced by notionmd-cli at 2024-01-15T10:00:00Z: replaced content with 12 blocks, 2 images uploaded"
+func syncSummary(blocks []notion.Block, sections []pageSection, replace bool, now time.Time) string {
+	added := len(blocks)
+	for _, section := range sections {
+		added += len(section.Blocks)
+	}
+	uploads := countUploadedImages(blocks)
+	for _, section := range sections {
+		uploads += countUploadedImages(section.Blocks)
+	}
+	action := "added"
+	if replace {
+		action = "replaced content with"
+	}
+	summary := fmt.Sprintf("Synced by notionmd-cli at %s: %s %d blocks, %d images uploaded", now.UTC().Format(time.RFC3339), action, added, uploads)
+	if len(sections) > 0 {
+		summary += fmt.Sprintf(", %d child pages", len(sections))
+	}
+	return summary
+}
+
+// countUploadedImages counts the image blocks referencing an uploaded file, including nested ones
+func countUploadedImages(blocks []notion.Block) int {
+	count := 0
+	for _, block := range blocks {
+		if sized, ok := block.(sizedImage); ok {
+			block = sized.Block
+		}
+		if _, ok := block.(ImageBlock); ok {
+			count++
+		}
+		count += countUploadedImages(blockChildren(block))
+	}
+	return count
+}
+
+// reportImageFailures lists the images that were replaced by links, once the sync is over
+func reportImageFailures(ctx context.Context, failures []failedImageBlock) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(output(ctx), "⚠️  %d images could not be processed and were replaced by links:\n", len(failures))
+	for _, failure := range failures {
+		fmt.Fprintf(output(ctx), "  - %s: %s\n", failure.Path, failure.Err)
+	}
+}
+
+// emitPageID writes the page ID and URL to path as key=value lines, if a path was given
+func emitPageID(ctx context.Context, path, pageID string) {
+	if path == "" {
+		return
+	}
+	content := fmt.Sprintf("page_id=%s\nurl=%s\n", pageID, notionPageURL(pageID))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		fmt.Fprintf(output(ctx), "Warning: failed to write page ID file '%s': %s\n", path, err)
+	}
+}
+
+// printTitle prints a detailed operation title based on flags and arguments
+func printAppTitle(ctx context.Context, mdPath, operation string, useHash, force bool, rewriteText string) {
+	details := []string{"NotionMD Cli: Processing file '" + mdPath + "' using " + operation}
+	if useHash && force {
+		details = append(details, "content hash check enabled but forced to sync")
+	} else if useHash {
+		details = append(details, "content hash check enabled")
+	}
+	if rewriteText != "" {
+		details = append(details, "rewrite mapping: '"+rewriteText+"'")
+	}
+	fmt.Fprintln(output(ctx), "\n===== "+strings.Join(details, ", ")+" =====\n")
+}
@@ -0,0 +1,388 @@
+package notionsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// writeMarkdown writes content to a markdown file in a temporary directory and returns its path
+func writeMarkdown(t *testing.T, content string) string {
+	t.Helper()
+	mdPath := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(mdPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return mdPath
+}
+
+// testOptions returns the options the CLI syncs with by default, printing nothing
+func testOptions() SyncOptions {
+	return SyncOptions{TitleLevel: 1, HashStorage: "property", StatusOutput: io.Discard}
+}
+
+func TestSyncFileCalls(t *testing.T) {
+	const markdown = "# Title\n\nSome text.\n"
+	tests := []struct {
+		name      string
+		opts      func(*SyncOptions)
+		prepare   func(*fakeNotionClient)
+		wantErr   error
+		wantCalls []string
+		wantTitle string
+	}{
+		{
+			name:      "append adds without clearing",
+			wantCalls: []string{"UpdatePageTitle", "AddPageContent"},
+			wantTitle: "Title",
+		},
+		{
+			name:      "replace clears before adding",
+			opts:      func(o *SyncOptions) { o.Replace = true },
+			wantCalls: []string{"UpdatePageTitle", "ClearPageContent", "AddPageContent"},
+			wantTitle: "Title",
+		},
+		{
+			name:      "preserve first keeps the leading blocks",
+			opts:      func(o *SyncOptions) { o.Replace, o.PreserveFirstN = true, 2 },
+			wantCalls: []string{"UpdatePageTitle", "ClearPageContentAfter", "AddPageContent"},
+			wantTitle: "Title",
+		},
+		{
+			name:      "use hash stores the hash after a change",
+			opts:      func(o *SyncOptions) { o.UseHash = true },
+			wantCalls: []string{"UpdatePageTitle", "GetProperty", "SetProperty", "AddPageContent"},
+			wantTitle: "Title",
+		},
+		{
+			name: "use hash skips on match",
+			opts: func(o *SyncOptions) { o.UseHash, o.Replace = true, true },
+			prepare: func(c *fakeNotionClient) {
+				c.properties["page"] = map[string]string{"Content Hash": contentHashOf(t, markdown)}
+			},
+			wantErr:   ErrContentUnchanged,
+			wantCalls: []string{"UpdatePageTitle", "GetProperty"},
+			wantTitle: "Title",
+		},
+		{
+			name: "force syncs despite a matching hash",
+			opts: func(o *SyncOptions) { o.UseHash, o.Force = true, true },
+			prepare: func(c *fakeNotionClient) {
+				c.properties["page"] = map[string]string{"Content Hash": contentHashOf(t, markdown)}
+			},
+			wantCalls: []string{"UpdatePageTitle", "GetProperty", "SetProperty", "AddPageContent"},
+			wantTitle: "Title",
+		},
+		{
+			name:      "title level 0 keeps the heading as content",
+			opts:      func(o *SyncOptions) { o.TitleLevel = 0 },
+			wantCalls: []string{"AddPageContent"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			if tt.prepare != nil {
+				tt.prepare(client)
+			}
+			opts := testOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := client.callNames(); !slices.Equal(got, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+			if got := client.titles["page"]; got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+// contentHashOf returns the content hash a sync of markdown stores, by syncing it once
+func contentHashOf(t *testing.T, markdown string) string {
+	t.Helper()
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.UseHash = true
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+	return client.properties["page"]["Content Hash"]
+}
+
+func TestSyncFileReplaceReplacesContent(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.Replace = true
+	for _, markdown := range []string{"# Title\n\nfirst\n\nsecond\n", "# Title\n\nthird\n"} {
+		if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := richTextPlainText(blockRichText(client.content["page"][0])); len(client.content["page"]) != 1 || got != "third" {
+		t.Errorf("page holds %d blocks starting with %q, want only the third paragraph", len(client.content["page"]), got)
+	}
+}
+
+func TestSyncFilePropertyPrefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         func(*SyncOptions)
+		wantProperty []string
+	}{
+		{
+			name:         "default names",
+			opts:         func(o *SyncOptions) {},
+			wantProperty: []string{"GetProperty page Content Hash", "SetProperty page Content Hash", "SetProperty page Last Synced"},
+		},
+		{
+			name:         "prefixed names",
+			opts:         func(o *SyncOptions) { o.PropertyPrefix = "notionmd_" },
+			wantProperty: []string{"GetProperty page notionmd_Content Hash", "SetProperty page notionmd_Content Hash", "SetProperty page notionmd_Last Synced"},
+		},
+		{
+			name:         "prefixed hash property name",
+			opts:         func(o *SyncOptions) { o.PropertyPrefix, o.HashProperty = "notionmd_", "Hash" },
+			wantProperty: []string{"GetProperty page notionmd_Hash", "SetProperty page notionmd_Hash", "SetProperty page notionmd_Last Synced"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			opts := testOptions()
+			opts.UseHash, opts.TimestampProperty = true, "Last Synced"
+			tt.opts(&opts)
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\ntext\n"), "page"); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, call := range client.calls {
+				if strings.HasPrefix(call, "GetProperty ") || strings.HasPrefix(call, "SetProperty ") {
+					got = append(got, call)
+				}
+			}
+			if !slices.Equal(got, tt.wantProperty) {
+				t.Errorf("property calls = %q, want %q", got, tt.wantProperty)
+			}
+		})
+	}
+}
+
+func TestSyncFileTimestampSkippedWhenUnchanged(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.UseHash, opts.TimestampProperty, opts.PropertyPrefix = true, "Last Synced", "x_"
+	mdPath := writeMarkdown(t, "# Title\n\ntext\n")
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	stamp := client.properties["page"]["x_Last Synced"]
+	if _, err := time.Parse(time.RFC3339, stamp); err != nil {
+		t.Fatalf("timestamp %q isn't RFC 3339: %s", stamp, err)
+	}
+	client.properties["page"]["x_Last Synced"] = "earlier"
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); !errors.Is(err, ErrContentUnchanged) {
+		t.Fatalf("error = %v, want ErrContentUnchanged", err)
+	}
+	if got := client.properties["page"]["x_Last Synced"]; got != "earlier" {
+		t.Errorf("timestamp = %q after an unchanged sync, want it left alone", got)
+	}
+}
+
+func TestSyncFileEmitsPageIDFile(t *testing.T) {
+	const pageID = "01234567-89ab-cdef-0123-456789abcdef"
+	const want = "page_id=" + pageID + "\nurl=https://www.notion.so/0123456789abcdef0123456789abcdef\n"
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.UseHash = true
+	opts.PageIDFile = filepath.Join(t.TempDir(), "page-id")
+	mdPath := writeMarkdown(t, "# Title\n\ntext\n")
+	if err := SyncFile(context.Background(), opts, client, mdPath, pageID); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(opts.PageIDFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("page ID file = %q, want %q", data, want)
+	}
+
+	// An unchanged page is still the page later steps should use
+	if err := os.Remove(opts.PageIDFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncFile(context.Background(), opts, client, mdPath, pageID); !errors.Is(err, ErrContentUnchanged) {
+		t.Fatalf("error = %v, want ErrContentUnchanged", err)
+	}
+	if data, err := os.ReadFile(opts.PageIDFile); err != nil || string(data) != want {
+		t.Errorf("page ID file after an unchanged sync = %q, %v, want %q", data, err, want)
+	}
+}
+
+// failingAddClient is a fakeNotionClient whose AddPageContent calls fail
+type failingAddClient struct {
+	*fakeNotionClient
+}
+
+func (c *failingAddClient) AddPageContent(ctx context.Context, pageID string, blocks []notion.Block) ([]string, error) {
+	c.record("AddPageContent %s %d", pageID, len(blocks))
+	return nil, errors.New("validation_error")
+}
+
+func TestSyncFileVerifyPage(t *testing.T) {
+	opts := testOptions()
+	opts.VerifyPage = true
+	mdPath := writeMarkdown(t, "# Title\n\ntext\n")
+
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callNames(); !slices.Equal(got, []string{"UpdatePageTitle", "AddPageContent", "VerifyPage"}) {
+		t.Errorf("calls = %v, want the page verified after its content is added", got)
+	}
+
+	failing := &failingAddClient{newFakeNotionClient()}
+	if err := SyncFile(context.Background(), opts, failing, mdPath, "page"); err == nil {
+		t.Fatal("sync succeeded, want the failed add reported")
+	}
+	if got := failing.callNames(); slices.Contains(got, "VerifyPage") {
+		t.Errorf("calls = %v, want no verification after a failed sync", got)
+	}
+}
+
+func TestSyncSummary(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	blocks := []notion.Block{
+		notion.ParagraphBlock{RichText: plainRichText("text")},
+		createImageBlockWithFileUpload("upload-1", nil),
+		notion.ToggleBlock{Children: []notion.Block{createImageBlockWithFileUpload("upload-2", nil)}},
+	}
+	tests := []struct {
+		name     string
+		replace  bool
+		sections []pageSection
+		want     string
+	}{
+		{"append", false, nil, "Synced by notionmd-cli at 2024-01-15T09:00:00Z: added 3 blocks, 2 images uploaded"},
+		{"replace", true, nil, "Synced by notionmd-cli at 2024-01-15T09:00:00Z: replaced content with 3 blocks, 2 images uploaded"},
+		{"split", false, []pageSection{{Title: "A", Blocks: blocks[:2]}}, "Synced by notionmd-cli at 2024-01-15T09:00:00Z: added 5 blocks, 3 images uploaded, 1 child pages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncSummary(blocks, tt.sections, tt.replace, now); got != tt.want {
+				t.Errorf("summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncFileCommentSummary(t *testing.T) {
+	opts := testOptions()
+	opts.CommentSummary = true
+	mdPath := writeMarkdown(t, "# Title\n\none\n\ntwo\n")
+
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatal(err)
+	}
+	comments := client.comments["page"]
+	if len(comments) != 1 || !strings.HasPrefix(comments[0], "Synced by notionmd-cli at ") || !strings.HasSuffix(comments[0], ": added 2 blocks, 0 images uploaded") {
+		t.Errorf("comments = %q, want the sync summary", comments)
+	}
+
+	failing := &failingAddClient{newFakeNotionClient()}
+	if err := SyncFile(context.Background(), opts, failing, mdPath, "page"); err == nil {
+		t.Fatal("sync succeeded, want the failed add reported")
+	}
+	if got := failing.comments["page"]; len(got) != 0 {
+		t.Errorf("comments = %q after a failed sync, want none", got)
+	}
+}
+
+func TestSyncFilePreserveFirstN(t *testing.T) {
+	client := newFakeNotionClient()
+	for _, text := range []string{"Header one", "Header two", "Old content", "More old content"} {
+		client.content["page"] = append(client.content["page"], withID(notion.ParagraphBlock{RichText: plainRichText(text)}, client.newID()))
+	}
+	opts := testOptions()
+	opts.Replace, opts.PreserveFirstN = true, 2
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\nNew content\n"), "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pageTexts(client.content["page"]), []string{"Header one", "Header two", "New content"}; !slices.Equal(got, want) {
+		t.Errorf("page = %q, want %q", got, want)
+	}
+}
+
+func TestSyncFileDiffAgainstFile(t *testing.T) {
+	copyPath := filepath.Join(t.TempDir(), "last.md")
+	opts := testOptions()
+	opts.DiffAgainstFile = copyPath
+	mdPath := writeMarkdown(t, "# Title\n\nFirst version.\n")
+
+	client := newFakeNotionClient()
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if _, err := os.Stat(copyPath); err != nil {
+		t.Fatalf("the synced copy wasn't stored: %v", err)
+	}
+
+	// Unchanged content is skipped without any Notion call, reads included
+	client = newFakeNotionClient()
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); !errors.Is(err, ErrContentUnchanged) {
+		t.Fatalf("unchanged sync: err = %v, want ErrContentUnchanged", err)
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("unchanged sync made calls %v, want none", client.calls)
+	}
+
+	// --force syncs anyway
+	opts.Force = true
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatalf("forced sync: %v", err)
+	}
+	if !slices.Contains(client.callNames(), "AddPageContent") {
+		t.Errorf("forced sync calls = %v, want the content added", client.callNames())
+	}
+	opts.Force = false
+
+	// A failed sync of changed content keeps the old copy, so the next run retries
+	if err := os.WriteFile(mdPath, []byte("# Title\n\nSecond version.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := os.ReadFile(copyPath)
+	if err := SyncFile(context.Background(), opts, &failingAddClient{newFakeNotionClient()}, mdPath, "page"); err == nil {
+		t.Fatal("sync with a failing client succeeded")
+	}
+	if after, _ := os.ReadFile(copyPath); !bytes.Equal(after, stored) {
+		t.Errorf("a failed sync updated the stored copy to %q", after)
+	}
+
+	client = newFakeNotionClient()
+	if err := SyncFile(context.Background(), opts, client, mdPath, "page"); err != nil {
+		t.Fatalf("changed sync: %v", err)
+	}
+	if got := ownText(client.content["page"][0]); got != "Second version." {
+		t.Errorf("content = %q, want the second version", got)
+	}
+	if after, _ := os.ReadFile(copyPath); !strings.Contains(string(after), "Second version.") {
+		t.Errorf("stored copy = %q, want the second version", after)
+	}
+}
@@ -0,0 +1,98 @@
+package notionsync
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find a GFM table delimiter row: | --- | :---: | ---: |
+var tableDelimiterRegex = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+
+// isTableStart reports whether lines[i] is the header row of a GFM table, which needs a
+// delimiter row with the same number of cells right after it
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || strings.HasPrefix(lines[i], "    ") || !strings.Contains(lines[i], "|") {
+		return false
+	}
+	if !strings.Contains(lines[i+1], "|") || !tableDelimiterRegex.MatchString(lines[i+1]) {
+		return false
+	}
+	return len(splitTableRow(lines[i])) == len(splitTableRow(lines[i+1]))
+}
+
+// convertTable converts the GFM table starting at lines[start] into a table block whose first
+// row is the column header, and returns the index of the line after the table
+func convertTable(lines []string, start int) (*notion.TableBlock, int) {
+	header := splitTableRow(lines[start])
+	table := &notion.TableBlock{
+		TableWidth:      len(header),
+		HasColumnHeader: true,
+	}
+	table.Children = append(table.Children, tableRow(header, len(header)))
+
+	i := start + 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		table.Children = append(table.Children, tableRow(splitTableRow(lines[i]), len(header)))
+	}
+	return table, i
+}
+
+// tableRow builds a table row of exactly width cells, padding or cutting off cells as GFM does
+func tableRow(cells []string, width int) *notion.TableRowBlock {
+	row := &notion.TableRowBlock{Cells: make([][]notion.RichText, width)}
+	for i := range row.Cells {
+		row.Cells[i] = []notion.RichText{}
+		if i < len(cells) {
+			row.Cells[i] = inlineRichText(cells[i])
+		}
+	}
+	return row
+}
+
+// splitTableRow splits a table row into its trimmed cells. Escaped pipes (\|) and pipes inside
+// code spans don't separate cells.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = strings.TrimSuffix(line, "|")
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+			continue
+		case line[i] == '`':
+			inCode = !inCode
+		case line[i] == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(line[i])
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// inlineRichText converts the inline markdown of a single line, such as a table cell, into rich text
+func inlineRichText(text string) []notion.RichText {
+	return parseInline(text)
+}
+
+// applyRowHeader marks the first column of every table as a row header
+func applyRowHeader(blocks []notion.Block) {
+	for _, block := range blocks {
+		if table, ok := block.(*notion.TableBlock); ok {
+			table.HasRowHeader = true
+			continue
+		}
+		applyRowHeader(blockChildren(block))
+	}
+}
@@ -0,0 +1,48 @@
+package notionsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestSyncFileTableHeaders(t *testing.T) {
+	const markdown = "# Title\n\n| Name | Value |\n| --- | ---: |\n| a | 1 |\n| b \\| c | `x|y` |\n"
+	tests := []struct {
+		name          string
+		rowHeader     bool
+		wantRowHeader bool
+	}{
+		{"column header only", false, false},
+		{"row header too", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNotionClient()
+			opts := testOptions()
+			opts.RowHeader = tt.rowHeader
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, markdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			content := client.content["page"]
+			if len(content) != 1 {
+				t.Fatalf("content = %v, want a table", blockTypes(content))
+			}
+			table, ok := content[0].(*notion.TableBlock)
+			if !ok {
+				t.Fatalf("content = %v, want a table", blockTypes(content))
+			}
+			if !table.HasColumnHeader || table.HasRowHeader != tt.wantRowHeader {
+				t.Errorf("column header %v, row header %v, want true, %v", table.HasColumnHeader, table.HasRowHeader, tt.wantRowHeader)
+			}
+			if table.TableWidth != 2 || len(table.Children) != 3 {
+				t.Fatalf("table is %d wide with %d rows, want 2 wide with 3", table.TableWidth, len(table.Children))
+			}
+			row := table.Children[2].(*notion.TableRowBlock)
+			if got := []string{richTextPlainText(row.Cells[0]), richTextPlainText(row.Cells[1])}; got[0] != "b | c" || got[1] != "x|y" {
+				t.Errorf("last row = %q, want the escaped and code pipes kept in their cells", got)
+			}
+		})
+	}
+}
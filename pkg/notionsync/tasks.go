@@ -0,0 +1,205 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find task metadata: @due(2024-02-01) or @assignee(bob)
+var taskMetadataRegex = regexp.MustCompile(`[ \t]*@(due|assignee)\(([^)]*)\)`)
+
+// Regular expression to find the checkbox starting a task list item: [ ] or [x]
+var taskCheckboxRegex = regexp.MustCompile(`^\[[ xX]\]\s`)
+
+// TaskMetadataModes are the accepted values of --task-metadata
+var TaskMetadataModes = []string{"keep", "compact", "drop"}
+
+// taskMetadata is the metadata parsed out of a task list item
+type taskMetadata struct {
+	Due       string
+	Assignees []string
+}
+
+// String formats the metadata compactly, e.g. "(due 2024-02-01, @bob)"
+func (m taskMetadata) String() string {
+	var parts []string
+	if m.Due != "" {
+		parts = append(parts, "due "+m.Due)
+	}
+	for _, assignee := range m.Assignees {
+		parts = append(parts, "@"+assignee)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// isTaskItem reports whether block is a to-do or a list item starting with a checkbox
+func isTaskItem(block notion.Block) bool {
+	switch block.(type) {
+	case notion.ToDoBlock, *notion.ToDoBlock:
+		return true
+	case notion.BulletedListItemBlock, *notion.BulletedListItemBlock:
+		return taskCheckboxRegex.MatchString(richTextPlainText(blockRichText(block)))
+	}
+	return false
+}
+
+// toDoFromListItem converts a bulleted list item whose text starts with a checkbox into a to-do,
+// checked for [x] or [X], with the checkbox removed from its text. ok is false if the item
+// doesn't start with a checkbox.
+func toDoFromListItem(item notion.BulletedListItemBlock) (todo notion.ToDoBlock, ok bool) {
+	if len(item.RichText) == 0 || !isPlainTextRun(item.RichText[0]) {
+		return todo, false
+	}
+	first := item.RichText[0]
+	checkbox := taskCheckboxRegex.FindString(first.Text.Content)
+	if checkbox == "" {
+		return todo, false
+	}
+	checked := checkbox[1] != ' '
+	richText := item.RichText[1:]
+	if rest := first.Text.Content[len(checkbox):]; rest != "" {
+		richText = append([]notion.RichText{textRun(rest, first.Annotations)}, richText...)
+	}
+	return notion.ToDoBlock{
+		RichText: richText,
+		Children: item.Children,
+		Checked:  &checked,
+		Color:    item.Color,
+	}, true
+}
+
+// applyTaskMetadata strips @due(...) and @assignee(...) metadata from task items. In "compact"
+// mode the metadata is appended to the task text in short form, in "drop" mode it is removed.
+func applyTaskMetadata(blocks []notion.Block, mode string) []notion.Block {
+	if mode == "keep" {
+		return blocks
+	}
+	for i, block := range blocks {
+		if isTaskItem(block) {
+			richText, meta := extractTaskMetadata(blockRichText(block))
+			if compact := meta.String(); mode == "compact" && compact != "" {
+				richText = append(richText, textRun(" "+compact, nil))
+			}
+			block = withRichText(block, richText)
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = withChildren(block, applyTaskMetadata(children, mode))
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// extractTaskMetadata removes the metadata tokens from plain text runs and returns what they held
+func extractTaskMetadata(richText []notion.RichText) ([]notion.RichText, taskMetadata) {
+	var (
+		meta   taskMetadata
+		result []notion.RichText
+	)
+	for _, rt := range richText {
+		if !isPlainTextRun(rt) || !taskMetadataRegex.MatchString(rt.Text.Content) {
+			result = append(result, rt)
+			continue
+		}
+		content := taskMetadataRegex.ReplaceAllStringFunc(rt.Text.Content, func(token string) string {
+			match := taskMetadataRegex.FindStringSubmatch(token)
+			value := strings.TrimSpace(match[2])
+			switch match[1] {
+			case "due":
+				meta.Due = value
+			case "assignee":
+				meta.Assignees = append(meta.Assignees, strings.TrimPrefix(value, "@"))
+			}
+			return ""
+		})
+		if content != "" {
+			result = append(result, textRun(content, rt.Annotations))
+		}
+	}
+	return result, meta
+}
+
+// databaseTask is a task item taken out of the document to become a page in a tasks database
+type databaseTask struct {
+	Title    []notion.RichText
+	Meta     taskMetadata
+	Children []notion.Block
+}
+
+// extractTasks removes the task items from blocks, nested ones included, returning them with
+// their metadata parsed out of their text. Blocks nested in a task stay with it.
+func extractTasks(blocks []notion.Block) ([]notion.Block, []databaseTask) {
+	var (
+		kept  []notion.Block
+		tasks []databaseTask
+	)
+	for _, block := range blocks {
+		if isTaskItem(block) {
+			if item, ok := block.(notion.BulletedListItemBlock); ok {
+				block, _ = toDoFromListItem(item)
+			} else if item, ok := block.(*notion.BulletedListItemBlock); ok {
+				block, _ = toDoFromListItem(*item)
+			}
+			title, meta := extractTaskMetadata(blockRichText(block))
+			tasks = append(tasks, databaseTask{Title: title, Meta: meta, Children: blockChildren(block)})
+			continue
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			children, nested := extractTasks(children)
+			block = withChildren(block, children)
+			tasks = append(tasks, nested...)
+		}
+		kept = append(kept, block)
+	}
+	return kept, tasks
+}
+
+// taskProperties returns the text form of the database properties holding a task's metadata:
+// the due date in dueProperty and the assignees, looked up in users, in assigneeProperty.
+// Assignees users has no ID for are left out with a warning.
+func taskProperties(ctx context.Context, meta taskMetadata, users map[string]string, dueProperty, assigneeProperty string) map[string]string {
+	values := make(map[string]string)
+	if meta.Due != "" {
+		values[dueProperty] = meta.Due
+	}
+	var ids []string
+	for _, assignee := range meta.Assignees {
+		id, ok := users[assignee]
+		if !ok {
+			warnf(ctx, "No user ID for assignee '@%s', add it to --user-map\n", assignee)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		values[assigneeProperty] = strings.Join(ids, ", ")
+	}
+	return values
+}
+
+// createTasks adds every task as a page in the database, its nested blocks as the page content
+func createTasks(ctx context.Context, notionClient NotionClientInterface, opts SyncOptions, tasks []databaseTask) error {
+	dueProperty, assigneeProperty := opts.TaskDueProperty, opts.TaskAssigneeProperty
+	if dueProperty == "" {
+		dueProperty = "Due"
+	}
+	if assigneeProperty == "" {
+		assigneeProperty = "Assignee"
+	}
+	for _, task := range tasks {
+		title := richTextPlainText(task.Title)
+		values := taskProperties(ctx, task.Meta, opts.Users, dueProperty, assigneeProperty)
+		if _, err := notionClient.CreateDatabasePage(ctx, opts.TasksDatabase, task.Title, values, task.Children); err != nil {
+			return fmt.Errorf("failed to add task '%s': %w", title, err)
+		}
+		fmt.Fprintf(output(ctx), "Added task '%s' to the tasks database\n", title)
+	}
+	return nil
+}
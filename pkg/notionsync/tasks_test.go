@@ -0,0 +1,151 @@
+package notionsync
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+const taskMarkdown = "# Tasks\n\n- [ ] Ship it @due(2024-02-01) @assignee(bob)\n- [x] Plan\n\nNotes.\n"
+
+func TestTaskMetadataAsToDos(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"keep", "Ship it @due(2024-02-01) @assignee(bob)"},
+		{"compact", "Ship it (due 2024-02-01, @bob)"},
+		{"drop", "Ship it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			client := newFakeNotionClient()
+			opts := testOptions()
+			opts.TaskMetadataMode = tt.mode
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, taskMarkdown), "page"); err != nil {
+				t.Fatal(err)
+			}
+			content := client.content["page"]
+			if got := blockTypes(content); !slices.Equal(got, []string{"notion.ToDoBlock", "notion.ToDoBlock", "notion.ParagraphBlock"}) {
+				t.Fatalf("blocks = %v, want two to-dos and a paragraph", got)
+			}
+			if got := richTextPlainText(blockRichText(content[0])); got != tt.want {
+				t.Errorf("task text = %q, want %q", got, tt.want)
+			}
+			if len(client.databases) != 0 {
+				t.Errorf("tasks added to databases %v, want none", slices.Collect(maps.Keys(client.databases)))
+			}
+		})
+	}
+}
+
+func TestTaskMetadataToDatabase(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.TasksDatabase = "tasks-db"
+	opts.Users = map[string]string{"bob": "user-bob"}
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, taskMarkdown), "page"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pageTexts(client.content["page"]); !slices.Equal(got, []string{"Notes."}) {
+		t.Errorf("page holds %v, want only the notes", got)
+	}
+	tasks := client.databases["tasks-db"]
+	if len(tasks) != 2 {
+		t.Fatalf("database holds %d tasks, want 2", len(tasks))
+	}
+	if got := client.titles[tasks[0]]; got != "Ship it" {
+		t.Errorf("task title = %q, want the text without its metadata", got)
+	}
+	want := map[string]string{"Due": "2024-02-01", "Assignee": "user-bob"}
+	if got := client.properties[tasks[0]]; !maps.Equal(got, want) {
+		t.Errorf("task properties = %v, want %v", got, want)
+	}
+	if got := client.properties[tasks[1]]; len(got) != 0 {
+		t.Errorf("task without metadata has properties %v", got)
+	}
+}
+
+func TestTaskPropertiesSkipsUnknownAssignees(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	meta := taskMetadata{Due: "2024-02-01", Assignees: []string{"bob", "eve", "ann"}}
+	users := map[string]string{"bob": "user-bob", "ann": "user-ann"}
+	got := taskProperties(ctx, meta, users, "When", "Who")
+	want := map[string]string{"When": "2024-02-01", "Who": "user-bob, user-ann"}
+	if !maps.Equal(got, want) {
+		t.Errorf("properties = %v, want %v", got, want)
+	}
+	if warningCount(ctx) != 1 {
+		t.Errorf("%d warnings, want 1 for the unknown assignee", warningCount(ctx))
+	}
+}
+
+func TestPropertyValuePeople(t *testing.T) {
+	property, err := propertyValue(notion.DBPropTypePeople, "user-1, user-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, user := range property.People {
+		ids = append(ids, user.ID)
+	}
+	if !slices.Equal(ids, []string{"user-1", "user-2"}) {
+		t.Errorf("people = %v, want user-1 and user-2", ids)
+	}
+}
+
+// taskOutline describes blocks one per line, indented by depth: "[x] text" for checked to-dos,
+// "[ ] text" for unchecked ones and "- text" for list items
+func taskOutline(blocks []notion.Block, depth int) []string {
+	var lines []string
+	for _, block := range blocks {
+		marker := "- "
+		if todo, ok := block.(notion.ToDoBlock); ok {
+			marker = "[ ] "
+			if todo.Checked != nil && *todo.Checked {
+				marker = "[x] "
+			}
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+marker+ownText(block))
+		lines = append(lines, taskOutline(blockChildren(block), depth+1)...)
+	}
+	return lines
+}
+
+func TestValidateContentBlocksTaskLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "checked and unchecked",
+			markdown: "- [ ] Open\n- [x] Done\n- [X] Also done\n- Plain [ ] item\n",
+			want:     []string{"[ ] Open", "[x] Done", "[x] Also done", "- Plain [ ] item"},
+		},
+		{
+			name:     "tasks nested under a task",
+			markdown: "- [x] Release\n    - [x] Tag\n    - [ ] Announce\n        - [ ] Blog post\n",
+			want:     []string{"[x] Release", "  [x] Tag", "  [ ] Announce", "    [ ] Blog post"},
+		},
+		{
+			name:     "mixed nesting",
+			markdown: "- Groceries\n    - [ ] Milk\n    - Bread\n- [ ] Chores\n    - Laundry\n    - [x] Dishes\n",
+			want:     []string{"- Groceries", "  [ ] Milk", "  - Bread", "[ ] Chores", "  - Laundry", "  [x] Dishes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			got := taskOutline(ValidateContentBlocks(ctx, convert(t, tt.markdown)), 0)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("blocks =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
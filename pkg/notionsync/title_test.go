@@ -0,0 +1,106 @@
+package notionsync
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestFitTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		wantLen   int
+		truncated bool
+	}{
+		{"short", "Release notes", 13, false},
+		{"at the limit", strings.Repeat("a", maxTitleLength), maxTitleLength, false},
+		{"over the limit", strings.Repeat("a", maxTitleLength+1), maxTitleLength, true},
+		{"emoji at the limit", strings.Repeat("🚀", maxTitleLength/2), maxTitleLength, false},
+		{"emoji over the limit", strings.Repeat("🚀", maxTitleLength/2+1), maxTitleLength - 1, true},
+		{"emoji across the cut", strings.Repeat("a", maxTitleLength-2) + "🚀b", maxTitleLength - 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			got, err := fitTitle(ctx, tt.title, "truncate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := textLength(got); n != tt.wantLen {
+				t.Errorf("title is %d UTF-16 units, want %d", n, tt.wantLen)
+			}
+			if strings.HasSuffix(got, "…") != tt.truncated {
+				t.Errorf("truncated = %v, want %v", !tt.truncated, tt.truncated)
+			}
+			if tt.truncated != (warningCount(ctx) == 1) {
+				t.Errorf("%d warnings, want one only when truncating", warningCount(ctx))
+			}
+			if !strings.HasPrefix(tt.title, strings.TrimSuffix(got, "…")) {
+				t.Error("truncated title isn't a prefix of the title")
+			}
+		})
+	}
+}
+
+func TestFitTitleErrorMode(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	if _, err := fitTitle(ctx, strings.Repeat("🚀", maxTitleLength/2+1), "error"); err == nil {
+		t.Error("expected an error for a title over the limit in UTF-16 units")
+	}
+	if _, err := fitTitle(ctx, strings.Repeat("🚀", maxTitleLength/2), "error"); err != nil {
+		t.Errorf("title at the limit failed: %s", err)
+	}
+}
+
+func TestFilterTitleBlock(t *testing.T) {
+	tests := []struct {
+		name       string
+		markdown   string
+		titleLevel int
+		wantTitle  string
+	}{
+		{"h1 with h1 titles", "# Guide\n\ntext\n", 1, "Guide"},
+		{"h2 with h1 titles", "## Guide\n\ntext\n", 1, ""},
+		{"h1 with h2 titles", "# Guide\n\ntext\n", 2, "Guide"},
+		{"h2 with h2 titles", "## Guide\n\ntext\n", 2, "Guide"},
+		{"h3 with h2 titles", "### Guide\n\ntext\n", 2, ""},
+		{"h1 with titles off", "# Guide\n\ntext\n", 0, ""},
+		{"paragraph first", "text\n\n# Guide\n", 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := convert(t, tt.markdown)
+			title, rest := FilterTitleBlock(blocks, tt.titleLevel)
+			if tt.wantTitle == "" {
+				if title != nil || len(rest) != len(blocks) {
+					t.Errorf("took %v as the title, want none", blockTypes([]notion.Block{title}))
+				}
+				return
+			}
+			if title == nil || ownText(title) != tt.wantTitle {
+				t.Fatalf("title = %v, want %q", title, tt.wantTitle)
+			}
+			if len(rest) != len(blocks)-1 {
+				t.Errorf("%d blocks left, want %d", len(rest), len(blocks)-1)
+			}
+		})
+	}
+}
+
+func TestSyncFileTitleLevel(t *testing.T) {
+	client := newFakeNotionClient()
+	opts := testOptions()
+	opts.TitleLevel = 2
+	if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "## Guide\n\ntext\n"), "page"); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.titles["page"]; got != "Guide" {
+		t.Errorf("title = %q, want Guide", got)
+	}
+	if got := blockTypes(client.content["page"]); len(got) != 1 || got[0] != "notion.ParagraphBlock" {
+		t.Errorf("content = %v, want only the paragraph", got)
+	}
+}
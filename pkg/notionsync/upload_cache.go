@@ -0,0 +1,197 @@
+package notionsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// UploadCache remembers the file upload each file's content was last uploaded as, keyed by
+// the content's SHA-256, so unchanged images aren't uploaded again on every sync
+type UploadCache struct {
+	Path    string
+	mu      sync.Mutex
+	entries map[string]uploadCacheEntry
+	// reused maps the file uploads handed out from the cache during this run to their file
+	reused map[string]string
+}
+
+// uploadCacheEntry is the cache record for one file content
+type uploadCacheEntry struct {
+	FileUploadID string    `json:"file_upload_id"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+// DefaultUploadCachePath returns where the upload cache lives unless --no-upload-cache is
+// given: uploads.json in the user's cache directory
+func DefaultUploadCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notionmd-cli", "uploads.json"), nil
+}
+
+// LoadUploadCache reads the upload cache at path. A missing or unreadable file is an empty cache.
+func LoadUploadCache(ctx context.Context, path string) *UploadCache {
+	cache := &UploadCache{Path: path, entries: make(map[string]uploadCacheEntry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		debugf(ctx, "[DEBUG] Ignoring unreadable upload cache: %s\n", err)
+	}
+	return cache
+}
+
+// lookup returns the cache record of the content with the given hash
+func (c *UploadCache) lookup(hash string) (uploadCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[hash]
+	return entry, ok
+}
+
+// store records the file upload of the content with the given hash and writes the cache
+func (c *UploadCache) store(hash, fileUploadID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[hash] = uploadCacheEntry{FileUploadID: fileUploadID, UploadedAt: time.Now()}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, append(data, '\n'), 0o644)
+}
+
+// reuse records that the cached file upload id is used for the file at filePath
+func (c *UploadCache) reuse(id, filePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reused == nil {
+		c.reused = make(map[string]string)
+	}
+	c.reused[id] = filePath
+}
+
+// forget drops the file upload id handed out from the cache, returning the file it held.
+// ok is false if id didn't come from the cache.
+func (c *UploadCache) forget(id string) (filePath string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if filePath, ok = c.reused[id]; !ok {
+		return "", false
+	}
+	delete(c.reused, id)
+	for hash, entry := range c.entries {
+		if entry.FileUploadID == id {
+			delete(c.entries, hash)
+		}
+	}
+	return filePath, true
+}
+
+// fileSHA256 returns the hex SHA-256 of the file's content
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// cachedFileUpload returns the file upload the content of filePath was last uploaded as, if
+// Notion still has it ready to attach. File uploads expire, an expired or unknown one is
+// dropped from consideration so the file is uploaded again. hash is the content's SHA-256.
+func (c *NotionClient) cachedFileUpload(ctx context.Context, filePath string) (id, hash string) {
+	hash, err := fileSHA256(filePath)
+	if err != nil {
+		debugf(ctx, "[DEBUG] Not using the upload cache for %s: %s\n", filePath, err)
+		return "", ""
+	}
+	entry, ok := c.UploadCache.lookup(hash)
+	if !ok {
+		return "", hash
+	}
+	resp, err := c.NotionHTTP.Get(ctx, "https://api.notion.com/v1/file_uploads/"+entry.FileUploadID)
+	if err != nil {
+		debugf(ctx, "[DEBUG] Failed to check cached file upload %s: %s\n", entry.FileUploadID, err)
+		return "", hash
+	}
+	defer resp.Body.Close()
+	var upload struct {
+		Status string `json:"status"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&upload) != nil || upload.Status != "uploaded" {
+		debugf(ctx, "[DEBUG] Cached file upload %s of %s can't be reused (HTTP %d, status '%s'), uploading again\n",
+			entry.FileUploadID, filePath, resp.StatusCode, upload.Status)
+		return "", hash
+	}
+	c.UploadCache.reuse(entry.FileUploadID, filePath)
+	return entry.FileUploadID, hash
+}
+
+// Regular expression to find Notion's rejection of a file upload an appended block refers to
+func isFileUploadRejected(body string) bool {
+	return strings.Contains(body, "validation_error") && strings.Contains(body, "file_upload")
+}
+
+// reuploadCachedImages uploads the files of the images among blocks and their children whose
+// file upload came from the cache again, dropping the cached ones, and returns the blocks
+// referring to the new uploads. ok is false if no image used a cached file upload.
+func (c *NotionClient) reuploadCachedImages(ctx context.Context, blocks []notion.Block, reuploaded map[string]string) (result []notion.Block, ok bool, err error) {
+	result = make([]notion.Block, len(blocks))
+	for i, block := range blocks {
+		switch b := block.(type) {
+		case ImageBlock:
+			id, found := reuploaded[b.FileUpload.ID]
+			if !found {
+				filePath, cached := c.UploadCache.forget(b.FileUpload.ID)
+				if !cached {
+					break
+				}
+				if id, err = c.UploadFile(ctx, filePath); err != nil {
+					return nil, false, err
+				}
+				reuploaded[b.FileUpload.ID] = id
+			}
+			b.FileUpload.ID = id
+			block, ok = b, true
+		case sizedImage:
+			sized, sizedOK, err := c.reuploadCachedImages(ctx, []notion.Block{b.Block, b.Fallback}, reuploaded)
+			if err != nil {
+				return nil, false, err
+			}
+			b.Block, b.Fallback = sized[0], sized[1]
+			block, ok = b, ok || sizedOK
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			children, childrenOK, err := c.reuploadCachedImages(ctx, children, reuploaded)
+			if err != nil {
+				return nil, false, err
+			}
+			block, ok = withChildren(block, children), ok || childrenOK
+		}
+		result[i] = block
+	}
+	return result, ok, nil
+}
@@ -0,0 +1,241 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// uploadServer is a fake Notion API for file uploads. New uploads get the IDs new-1, new-2...
+// statuses holds the status of earlier uploads, those missing from it are answered with 404.
+// Appending blocks that refer to an upload in rejected fails with a validation error.
+type uploadServer struct {
+	statuses map[string]string
+	rejected map[string]bool
+	requests []string
+	appended []string
+	created  int
+}
+
+func (s *uploadServer) client() *NotionClient {
+	c := NewNotionClient("token", DefaultNotionVersion)
+	c.NotionHTTP.Client = &http.Client{Transport: roundTripFunc(s.roundTrip)}
+	return c
+}
+
+func (s *uploadServer) roundTrip(req *http.Request) *http.Response {
+	s.requests = append(s.requests, req.Method+" "+req.URL.Path)
+	status, body := http.StatusOK, "{}"
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/v1/file_uploads":
+		s.created++
+		id := fmt.Sprintf("new-%d", s.created)
+		s.statuses[id] = "uploaded"
+		body = fmt.Sprintf(`{"id": %q, "upload_url": "https://api.notion.com/v1/file_uploads/%s/send"}`, id, id)
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/v1/file_uploads/"):
+		if uploadStatus, ok := s.statuses[strings.TrimPrefix(req.URL.Path, "/v1/file_uploads/")]; ok {
+			body = fmt.Sprintf(`{"status": %q}`, uploadStatus)
+		} else {
+			status, body = http.StatusNotFound, `{"code": "object_not_found"}`
+		}
+	case req.Method == http.MethodPatch:
+		data, _ := io.ReadAll(req.Body)
+		s.appended = append(s.appended, string(data))
+		for id := range s.rejected {
+			if strings.Contains(string(data), `"`+id+`"`) {
+				status, body = http.StatusBadRequest, `{"code": "validation_error", "message": "Could not find file_upload with ID: `+id+`."}`
+			}
+		}
+		if status == http.StatusOK {
+			body = `{"results": [{"id": "block-1"}]}`
+		}
+	}
+	return &http.Response{StatusCode: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// uploadCreated reports whether a new file upload was created
+func (s *uploadServer) uploadCreated() bool {
+	return slices.Contains(s.requests, "POST /v1/file_uploads")
+}
+
+// cachedImage writes an image and a cache at cachePath remembering it as the file upload id
+func cachedImage(t *testing.T, ctx context.Context, cachePath, id string) (imagePath string, hash string) {
+	t.Helper()
+	imagePath = filepath.Join(t.TempDir(), "chart.png")
+	writeImage(t, imagePath, "chart.png")
+	hash, err := fileSHA256(imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "" {
+		if err := LoadUploadCache(ctx, cachePath).store(hash, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return imagePath, hash
+}
+
+func TestUploadFileCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		wantID      string
+		wantCreated bool
+	}{
+		{name: "cached upload still there", status: "uploaded", wantID: "cached-1"},
+		{name: "cached upload not uploaded", status: "expired", wantID: "new-1", wantCreated: true},
+		{name: "cached upload unknown", wantID: "new-1", wantCreated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), testOptions())
+			cachePath := filepath.Join(t.TempDir(), "uploads.json")
+			imagePath, hash := cachedImage(t, ctx, cachePath, "cached-1")
+			server := &uploadServer{statuses: map[string]string{}}
+			if tt.status != "" {
+				server.statuses["cached-1"] = tt.status
+			}
+			c := server.client()
+			c.UploadCache = LoadUploadCache(ctx, cachePath)
+
+			id, err := c.UploadFile(ctx, imagePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID {
+				t.Errorf("file upload = %q, want %q", id, tt.wantID)
+			}
+			if !slices.Contains(server.requests, "GET /v1/file_uploads/cached-1") {
+				t.Errorf("requests = %q, want the cached upload checked", server.requests)
+			}
+			if server.uploadCreated() != tt.wantCreated {
+				t.Errorf("requests = %q, want an upload created %v", server.requests, tt.wantCreated)
+			}
+			if entry, _ := LoadUploadCache(ctx, cachePath).lookup(hash); entry.FileUploadID != tt.wantID {
+				t.Errorf("cache holds %q, want %q", entry.FileUploadID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestUploadFileWithoutCache(t *testing.T) {
+	// --no-upload-cache leaves the client without a cache
+	ctx := NewContext(context.Background(), testOptions())
+	cachePath := filepath.Join(t.TempDir(), "uploads.json")
+	imagePath, hash := cachedImage(t, ctx, cachePath, "cached-1")
+	server := &uploadServer{statuses: map[string]string{"cached-1": "uploaded"}}
+	c := server.client()
+
+	id, err := c.UploadFile(ctx, imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "new-1" || slices.Contains(server.requests, "GET /v1/file_uploads/cached-1") {
+		t.Errorf("file upload = %q after %q, want a new upload without looking at the cache", id, server.requests)
+	}
+	if entry, _ := LoadUploadCache(ctx, cachePath).lookup(hash); entry.FileUploadID != "cached-1" {
+		t.Errorf("cache holds %q, want it left alone", entry.FileUploadID)
+	}
+}
+
+func TestUploadFileCorruptCache(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	cachePath := filepath.Join(t.TempDir(), "uploads.json")
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	imagePath, hash := cachedImage(t, ctx, cachePath, "")
+	server := &uploadServer{statuses: map[string]string{}}
+	c := server.client()
+	c.UploadCache = LoadUploadCache(ctx, cachePath)
+
+	id, err := c.UploadFile(ctx, imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "new-1" {
+		t.Errorf("file upload = %q, want a new upload", id)
+	}
+	if entry, _ := LoadUploadCache(ctx, cachePath).lookup(hash); entry.FileUploadID != "new-1" {
+		t.Errorf("cache holds %q, want the corrupt file replaced", entry.FileUploadID)
+	}
+	if n := warningCount(ctx); n != 0 {
+		t.Errorf("got %d warnings, want the corrupt cache ignored quietly", n)
+	}
+}
+
+func TestUploadFileCacheWriteFailure(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	// The cache directory can't be created where a file is in the way
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	imagePath, _ := cachedImage(t, ctx, "", "")
+	server := &uploadServer{statuses: map[string]string{}}
+	c := server.client()
+	c.UploadCache = LoadUploadCache(ctx, filepath.Join(blocker, "uploads.json"))
+
+	if id, err := c.UploadFile(ctx, imagePath); err != nil || id != "new-1" {
+		t.Fatalf("UploadFile = %q, %v, want the upload to succeed anyway", id, err)
+	}
+	if n := warningCount(ctx); n != 1 {
+		t.Errorf("got %d warnings, want 1 for the cache write", n)
+	}
+}
+
+func TestAddPageContentReuploadsRejectedCachedImage(t *testing.T) {
+	ctx := NewContext(context.Background(), testOptions())
+	cachePath := filepath.Join(t.TempDir(), "uploads.json")
+	imagePath, hash := cachedImage(t, ctx, cachePath, "cached-1")
+	// The cached upload passes its check, but Notion won't attach it
+	server := &uploadServer{statuses: map[string]string{"cached-1": "uploaded"}, rejected: map[string]bool{"cached-1": true}}
+	c := server.client()
+	c.UploadCache = LoadUploadCache(ctx, cachePath)
+
+	id, err := c.UploadFile(ctx, imagePath)
+	if err != nil || id != "cached-1" {
+		t.Fatalf("UploadFile = %q, %v, want the cached upload", id, err)
+	}
+	image := createImageBlockWithFileUpload(id, nil)
+	blocks := []notion.Block{
+		image,
+		&notion.ToggleBlock{RichText: plainRichText("More"), Children: []notion.Block{newSizedImage(image, image, 100, 0)}},
+	}
+	if _, err := c.AddPageContent(ctx, "page", blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.appended) != 2 {
+		t.Fatalf("sent %d appends, want the rejected one and its retry", len(server.appended))
+	}
+	if retry := server.appended[1]; strings.Contains(retry, "cached-1") || strings.Count(retry, `"new-1"`) != 2 {
+		t.Errorf("retried append = %s, want every image on the one new upload", retry)
+	}
+	if server.created != 1 {
+		t.Errorf("created %d uploads, want the file uploaded once more", server.created)
+	}
+	if entry, _ := LoadUploadCache(ctx, cachePath).lookup(hash); entry.FileUploadID != "new-1" {
+		t.Errorf("cache holds %q, want the new upload", entry.FileUploadID)
+	}
+	if n := warningCount(ctx); n != 1 {
+		t.Errorf("got %d warnings, want 1 for the rejected upload", n)
+	}
+
+	// A new upload that is rejected as well isn't uploaded over and over
+	server.rejected["new-1"] = true
+	if _, err := c.AddPageContent(ctx, "page", []notion.Block{createImageBlockWithFileUpload("new-1", nil)}); err == nil {
+		t.Error("rejected new upload succeeded, want Notion's error")
+	}
+	if server.created != 1 {
+		t.Errorf("created %d uploads, want no more for an upload that didn't come from the cache", server.created)
+	}
+}
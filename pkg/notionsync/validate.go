@@ -0,0 +1,47 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// reportValidation prints a concise validation report and returns the exit code:
+// non-zero when any warning fired or any block would be rejected by Notion
+func reportValidation(ctx context.Context, titleBlock notion.Block, blocks []notion.Block, titleOverflow string) int {
+	problems := findBlockProblems(blocks, "block")
+	if richText := blockRichText(titleBlock); titleBlock != nil && len(richText) > 0 {
+		if _, err := fitTitle(ctx, richText[0].PlainText, titleOverflow); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	warnings := warningCount(ctx)
+	fmt.Fprintf(output(ctx), "\nValidation report: %d blocks, %d warnings, %d problems\n", len(blocks), warnings, len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(output(ctx), "  ✗ %s\n", problem)
+	}
+	if warnings > 0 || len(problems) > 0 {
+		fmt.Fprintln(output(ctx), "❌ Validation failed.")
+		return 1
+	}
+	fmt.Fprintln(output(ctx), "✅ Validation passed.")
+	return 0
+}
+
+// findBlockProblems returns a description of every block Notion would reject
+func findBlockProblems(blocks []notion.Block, path string) []string {
+	var problems []string
+	for i, block := range blocks {
+		location := fmt.Sprintf("%s %d", path, i)
+		for _, rt := range blockRichText(block) {
+			if rt.Text != nil && textLength(rt.Text.Content) > maxRichTextLength {
+				problems = append(problems, fmt.Sprintf("%s (%T): rich text of %d characters exceeds %d", location, block, textLength(rt.Text.Content), maxRichTextLength))
+			}
+		}
+		// Children nested deeper than one request allows are sent in follow-up requests
+		problems = append(problems, findBlockProblems(blockChildren(block), location+" > child")...)
+	}
+	return problems
+}
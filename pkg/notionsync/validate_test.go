@@ -0,0 +1,61 @@
+package notionsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+)
+
+func TestValidateOnlyExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		wantCode int
+	}{
+		{"clean", "# Title\n\nSome text.\n", 0},
+		{"plain fence", "# Title\n\n```\nno language\n```\n", 0},
+		{"tilde fence", "# Title\n\n~~~\nno language\n~~~\n", 0},
+		{"known language", "# Title\n\n```go\nfunc main() {}\n```\n", 0},
+		{"unknown language warns", "# Title\n\n```notalanguage\ncode\n```\n", 1},
+		{"unused attribute warns", "# Title\n\n## Notes {.notacolor}\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdPath := filepath.Join(t.TempDir(), "doc.md")
+			if err := os.WriteFile(mdPath, []byte(tt.markdown), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			opts := SyncOptions{ValidateOnly: true, TitleLevel: 1, StatusOutput: io.Discard}
+			err := SyncFile(context.Background(), opts, &OfflineNotionClient{}, mdPath, "page")
+
+			code := 0
+			if errors.Is(err, ErrValidationFailed) {
+				code = 1
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPlainFenceIsPlainText(t *testing.T) {
+	blocks, err := convertMarkdown(context.Background(), "```\nplain\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderCodeLanguage(blocks[0]); got != "" {
+		t.Errorf("language = %q, want plain text", got)
+	}
+	code, ok := blocks[0].(*notion.CodeBlock)
+	if !ok || code.Language == nil || *code.Language != "plain text" {
+		t.Errorf("block = %#v, want a plain text code block", blocks[0])
+	}
+}
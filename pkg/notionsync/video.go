@@ -0,0 +1,44 @@
+package notionsync
+
+import (
+	"regexp"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Regular expression to find YouTube thumbnail URLs: https://img.youtube.com/vi/<id>/hqdefault.jpg
+var youTubeThumbnailRegex = regexp.MustCompile(`^https?://(?:img\.youtube\.com|i\d?\.ytimg\.com)/vi(?:_webp)?/([A-Za-z0-9_-]{11})/`)
+
+// Regular expression to find YouTube video URLs: https://www.youtube.com/watch?v=<id> or https://youtu.be/<id>
+var youTubeVideoRegex = regexp.MustCompile(`^https?://(?:(?:www\.|m\.)?youtube\.com/(?:watch\?(?:[^#]*&)?v=|embed/|shorts/)|youtu\.be/)([A-Za-z0-9_-]{11})`)
+
+// Regular expression to find Vimeo video URLs and vumbnail.com thumbnails, which carry the video ID
+var vimeoVideoRegex = regexp.MustCompile(`^https?://(?:(?:www\.|player\.)?vimeo\.com/(?:video/)?|vumbnail\.com/)(\d+)`)
+
+// videoEmbedURL returns the video to embed for an image URL pointing at a YouTube or Vimeo video
+// or one of its thumbnails. Thumbnails that don't identify their video (such as Vimeo's CDN
+// images) aren't recognized, so they stay images.
+func videoEmbedURL(url string) (string, bool) {
+	if match := youTubeThumbnailRegex.FindStringSubmatch(url); match != nil {
+		return "https://www.youtube.com/watch?v=" + match[1], true
+	}
+	if match := youTubeVideoRegex.FindStringSubmatch(url); match != nil {
+		return "https://www.youtube.com/watch?v=" + match[1], true
+	}
+	if match := vimeoVideoRegex.FindStringSubmatch(url); match != nil {
+		return "https://vimeo.com/" + match[1], true
+	}
+	return "", false
+}
+
+// createVideoBlock creates a Notion video block embedding the video at url
+func createVideoBlock(url string, altText string) notion.Block {
+	videoBlock := &notion.VideoBlock{
+		Type:     notion.FileTypeExternal,
+		External: &notion.FileExternal{URL: url},
+	}
+	if altText != "" {
+		videoBlock.Caption = plainRichText(altText)
+	}
+	return videoBlock
+}
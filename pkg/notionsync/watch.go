@@ -0,0 +1,100 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchFile calls sync each time the markdown file at mdPath or a local image it references
+// changes, until ctx is done. Changes arriving within debounce of each other, such as an
+// editor writing a temporary file and renaming it over the original, trigger a single sync.
+// The referenced images are looked up again after each sync.
+func WatchFile(ctx context.Context, mdPath string, opts ImageOptions, debounce time.Duration, sync func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Error starting file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// The directories are watched rather than the files, editors that save by renaming a new
+	// file over the old one would otherwise end the watch
+	var files map[string]bool
+	dirs := make(map[string]bool)
+	update := func() {
+		files = make(map[string]bool)
+		for _, path := range watchedFiles(ctx, mdPath, opts) {
+			files[path] = true
+			dir := filepath.Dir(path)
+			if dirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				warnf(ctx, "Not watching %s: %s\n", dir, err)
+				continue
+			}
+			dirs[dir] = true
+		}
+	}
+	update()
+
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if files[event.Name] && event.Op&^fsnotify.Chmod != 0 {
+				debugf(ctx, "[DEBUG] %s\n", event)
+				fire = time.After(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			warnf(ctx, "File watcher error: %s\n", err)
+		case <-fire:
+			fire = nil
+			sync()
+			update()
+		}
+	}
+}
+
+// watchedFiles returns the absolute paths of the markdown file and of the local images it
+// references, resolved the way processImageInParagraph does
+func watchedFiles(ctx context.Context, mdPath string, opts ImageOptions) []string {
+	paths := []string{mdPath}
+	if content, err := os.ReadFile(mdPath); err == nil {
+		for _, ref := range FindImageReferences(string(content)) {
+			if isDataURI(ref.Path) {
+				continue
+			}
+			path := ref.Path
+			if len(opts.PathRewrites) > 0 {
+				path = rewriteImagePath(ctx, path, opts.PathRewrites)
+			}
+			if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(mdPath), path)
+			}
+			paths = append(paths, path)
+		}
+	}
+	for i, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			paths[i] = abs
+		}
+	}
+	return paths
+}
@@ -0,0 +1,39 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// WrapModes are the accepted values of --wrap-in
+var WrapModes = []string{"toggle", "callout"}
+
+// wrapBlocks makes blocks the children of a single toggle or callout labelled label. Notion
+// accepts at most 100 children per block in a request, so longer content is spread over
+// several wrappers labelled "label (1/3)" and so on.
+func wrapBlocks(ctx context.Context, blocks []notion.Block, mode, label string) []notion.Block {
+	if len(blocks) == 0 {
+		return blocks
+	}
+	var chunks [][]notion.Block
+	for start := 0; start < len(blocks); start += maxBlocksPerRequest {
+		end := min(start+maxBlocksPerRequest, len(blocks))
+		chunks = append(chunks, blocks[start:end])
+	}
+	wrapped := make([]notion.Block, 0, len(chunks))
+	for i, children := range chunks {
+		text := label
+		if len(chunks) > 1 {
+			text = fmt.Sprintf("%s (%d/%d)", label, i+1, len(chunks))
+		}
+		if mode == "callout" {
+			wrapped = append(wrapped, notion.CalloutBlock{RichText: plainRichText(text), Children: children})
+		} else {
+			wrapped = append(wrapped, notion.ToggleBlock{RichText: plainRichText(text), Children: children})
+		}
+	}
+	debugf(ctx, "[DEBUG] Wrapped %d blocks in %d %s block(s)\n", len(blocks), len(wrapped), mode)
+	return wrapped
+}
@@ -0,0 +1,60 @@
+package notionsync
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSyncFileWrapIn(t *testing.T) {
+	tests := []struct {
+		mode     string
+		label    string
+		wantType string
+		wantText string
+	}{
+		{"toggle", "Release notes", "notion.ToggleBlock", "Release notes"},
+		{"callout", "Imported", "notion.CalloutBlock", "Imported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			client := newFakeNotionClient()
+			opts := testOptions()
+			opts.WrapIn, opts.WrapLabel = tt.mode, tt.label
+			if err := SyncFile(context.Background(), opts, client, writeMarkdown(t, "# Title\n\nFirst.\n\n- item\n\nLast.\n"), "page"); err != nil {
+				t.Fatal(err)
+			}
+			content := client.content["page"]
+			if got := blockTypes(content); !slices.Equal(got, []string{tt.wantType}) {
+				t.Fatalf("content = %v, want a single %s", got, tt.wantType)
+			}
+			if got := ownText(content[0]); got != tt.wantText {
+				t.Errorf("label = %q, want %q", got, tt.wantText)
+			}
+			children := blockChildren(content[0])
+			if got := blockTypes(children); !slices.Equal(got, []string{"notion.ParagraphBlock", "notion.BulletedListItemBlock", "notion.ParagraphBlock"}) {
+				t.Errorf("children = %v, want the converted content", got)
+			}
+		})
+	}
+}
+
+func TestWrapBlocksChunksChildren(t *testing.T) {
+	var markdown strings.Builder
+	for i := range maxBlocksPerRequest + 1 {
+		fmt.Fprintf(&markdown, "Paragraph %d.\n\n", i)
+	}
+	ctx := NewContext(context.Background(), testOptions())
+	wrapped := wrapBlocks(ctx, convert(t, markdown.String()), "toggle", "Doc")
+	if len(wrapped) != 2 {
+		t.Fatalf("got %d wrappers, want 2", len(wrapped))
+	}
+	if got := []string{ownText(wrapped[0]), ownText(wrapped[1])}; !slices.Equal(got, []string{"Doc (1/2)", "Doc (2/2)"}) {
+		t.Errorf("labels = %q, want numbered labels", got)
+	}
+	if n0, n1 := len(blockChildren(wrapped[0])), len(blockChildren(wrapped[1])); n0 != maxBlocksPerRequest || n1 != 1 {
+		t.Errorf("wrappers hold %d and %d blocks, want %d and 1", n0, n1, maxBlocksPerRequest)
+	}
+}